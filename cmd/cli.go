@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/urfave/cli/v2"
 
 	"github.com/penguin-statistics/soracli/internal/appentry"
+	"github.com/penguin-statistics/soracli/internal/completion"
 )
 
 func Render(c *cli.Context) error {
@@ -14,3 +17,248 @@ func Render(c *cli.Context) error {
 
 	return app.RenderGameData(c)
 }
+
+func MockServer(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.ServeMockServer(c)
+}
+
+func ListNotices(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.ListNotices(c)
+}
+
+func PreviewNotice(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.PreviewNotice(c)
+}
+
+func CreateNotice(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.CreateNotice(c)
+}
+
+func UpdateNotice(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.UpdateNotice(c)
+}
+
+func ExpireNotice(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.ExpireNotice(c)
+}
+
+func AddItem(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.AddItem(c)
+}
+
+func SyncItems(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.SyncItems(c)
+}
+
+func ListLocalCache(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.ListLocalCache(c)
+}
+
+func ClearLocalCache(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.ClearLocalCache(c)
+}
+
+func Export(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.Export(c)
+}
+
+// Completion prints the completion script of the shell given as the argument.
+func Completion(c *cli.Context) error {
+	script, err := completion.Script(c.Args().First(), c.App.Name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(script)
+	return nil
+}
+
+func GC(c *cli.Context) error {
+	app, err := appentry.LocalApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.GC(c)
+}
+
+func ExportShim(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.ExportShim(c)
+}
+
+func ListTimeRanges(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.ListTimeRanges(c)
+}
+
+func CreateTimeRange(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.CreateTimeRange(c)
+}
+
+func SplitTimeRange(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.SplitTimeRange(c)
+}
+
+func MergeTimeRanges(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.MergeTimeRanges(c)
+}
+
+func CheckTimeRanges(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.CheckTimeRanges(c)
+}
+
+func Status(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.Status(c)
+}
+
+func AnalyzeMatrix(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.AnalyzeMatrix(c)
+}
+
+func AnalyzeBoundsAudit(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.AnalyzeBoundsAudit(c)
+}
+
+func ListAuditLog(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.ListAuditLog(c)
+}
+
+func ShowAuditLogEntry(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.ShowAuditLogEntry(c)
+}
+
+func Undo(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.Undo(c)
+}
+
+func Plan(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.Plan(c)
+}
+
+func Apply(c *cli.Context) error {
+	app, err := appentry.CliApp(c)
+	if err != nil {
+		return err
+	}
+
+	return app.Apply(c)
+}
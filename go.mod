@@ -2,23 +2,25 @@ module github.com/penguin-statistics/soracli
 
 go 1.18
 
-require github.com/urfave/cli/v2 v2.4.0
-
 require (
-	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
-	github.com/manifoldco/promptui v0.9.0 // indirect
+	github.com/manifoldco/promptui v0.9.0
+	github.com/mattn/go-sqlite3 v1.14.6
+	github.com/urfave/cli/v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
+require github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
+
 require (
 	github.com/ahmetb/go-linq/v3 v3.2.0
 	github.com/cpuguy83/go-md2man/v2 v2.0.1 // indirect
-	github.com/patrickmn/go-cache v2.1.0+incompatible // indirect
+	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/pkg/errors v0.9.1
 	github.com/rs/zerolog v1.26.1
-	github.com/russross/blackfriday/v2 v2.1.0 // indirect
+	github.com/russross/blackfriday/v2 v2.1.0
 	go.uber.org/atomic v1.6.0 // indirect
 	go.uber.org/dig v1.14.0 // indirect
-	go.uber.org/fx v1.17.1 // indirect
+	go.uber.org/fx v1.17.1
 	go.uber.org/multierr v1.5.0 // indirect
 	go.uber.org/zap v1.16.0 // indirect
 	golang.org/x/sys v0.0.0-20210903071746-97244b99971b // indirect
@@ -1,45 +1,63 @@
 package appentry
 
 import (
-	"fmt"
+	"crypto/sha1"
+	"encoding/hex"
 	"os"
-	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v2"
 	"go.uber.org/fx"
+	"go.uber.org/fx/fxevent"
 
 	"github.com/penguin-statistics/soracli/internal/cmd"
+	"github.com/penguin-statistics/soracli/internal/consts"
 	"github.com/penguin-statistics/soracli/internal/models/cache"
+	pkgcache "github.com/penguin-statistics/soracli/internal/pkg/cache"
 	"github.com/penguin-statistics/soracli/internal/pkg/client"
 	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
+	"github.com/penguin-statistics/soracli/internal/pkg/logfile"
 	"github.com/penguin-statistics/soracli/internal/services"
 )
 
 func CliApp(c *cli.Context) (*cmd.CliApp, error) {
-	logFileName := fmt.Sprintf("logs/soracli-%s.log", time.Now().Format("20060102-150405"))
-	logFile, err := os.OpenFile(filepath.UnderDataDir(logFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
-	if err != nil {
+	// --token is checked here rather than by the cli, so that shell completion works without it
+	if c.String("token") == "" {
+		return nil, errors.New(`Required flag "token" not set`)
+	}
+
+	if err := Setup(c); err != nil {
+		return nil, err
+	}
+	if removed, err := logfile.Prune(c, false); err != nil {
+		log.Warn().Err(err).Msg("failed to remove old log files")
+	} else if len(removed) > 0 {
+		log.Debug().Int("files", len(removed)).Msg("removed old log files")
+	}
+	if err := UseLocalCache(c); err != nil {
 		return nil, err
 	}
-	logWriters := zerolog.MultiLevelWriter(
-		zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "15:04:05"},
-		zerolog.ConsoleWriter{Out: logFile, NoColor: true, TimeFormat: "2006-01-02 15:04:05 Z07:00"},
-	)
 
-	level := zerolog.DebugLevel
-	if c.Bool("verbose") {
-		level = zerolog.TraceLevel
+	httpClient, err := client.NewHTTPFromCliContext(c)
+	if err != nil {
+		return nil, err
 	}
-	log.Logger = zerolog.New(logWriters).With().Timestamp().Logger().Level(level)
 
 	var app *cmd.CliApp
 
 	opts := []fx.Option{
-		fx.Supply(client.NewHTTPFromCliContext(c)),
+		// fx logs to stderr in a format of its own, breaking --log-format json
+		fx.WithLogger(func() fxevent.Logger { return fxevent.NopLogger }),
+		fx.Supply(httpClient),
 		fx.Provide(services.NewItemService),
+		fx.Provide(services.NewStageService),
+		fx.Provide(services.NewZoneService),
+		fx.Provide(services.NewActivityService),
 		fx.Provide(services.NewGameDataService),
+		fx.Provide(services.NewNoticeService),
+		fx.Provide(services.NewTimeRangeService),
 		fx.Provide(cmd.NewCliApp),
 		fx.Invoke(cache.Initialize),
 		fx.Populate(&app),
@@ -52,3 +70,70 @@ func CliApp(c *cli.Context) (*cmd.CliApp, error) {
 
 	return app, nil
 }
+
+// Setup points soracli at --data-dir and sets up logging to stderr and a log file, as every command needs
+// besides completing the command line.
+func Setup(c *cli.Context) error {
+	filepath.SetDataDir(c.String("data-dir"))
+
+	format := c.String("log-format")
+	if format != LogFormatConsole && format != LogFormatJSON {
+		return errors.Errorf("unsupported log format %q; supported: %s, %s", format, LogFormatConsole, LogFormatJSON)
+	}
+	logFile, err := logfile.Open()
+	if err != nil {
+		return err
+	}
+
+	// logs go to stderr, apart from the output of commands
+	logWriters := zerolog.MultiLevelWriter(os.Stderr, logFile)
+	if format == LogFormatConsole {
+		logWriters = zerolog.MultiLevelWriter(
+			zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: "15:04:05"},
+			zerolog.ConsoleWriter{Out: logFile, NoColor: true, TimeFormat: "2006-01-02 15:04:05 Z07:00"},
+		)
+	}
+
+	level := zerolog.DebugLevel
+	if c.Bool("verbose") {
+		level = zerolog.TraceLevel
+	}
+	log.Logger = zerolog.New(logWriters).With().Timestamp().Logger().Level(level)
+	return nil
+}
+
+// log formats of --log-format
+const (
+	LogFormatConsole = "console"
+	LogFormatJSON    = "json"
+)
+
+// UseLocalCache makes the caches read through to and write through to the on-disk cache of the admin api
+// at --baseUrl, unless --no-local-cache is given.
+func UseLocalCache(c *cli.Context) error {
+	if c.Bool("no-local-cache") {
+		return nil
+	}
+	// entries are namespaced by the admin api they were fetched from, so that e.g. the mock
+	// server and production never share cached data
+	sum := sha1.Sum([]byte(c.String("baseUrl")))
+	dir, err := filepath.UnderCacheDir(hex.EncodeToString(sum[:4]) + "/entries")
+	if err != nil {
+		return err
+	}
+	store, err := pkgcache.NewDiskStore(dir, consts.LocalCacheVersion)
+	if err != nil {
+		return err
+	}
+	pkgcache.UsePersistentStore(store)
+	return nil
+}
+
+// LocalApp sets up a CliApp for the commands that only work on the local files of soracli, which need
+// neither --token nor the admin api.
+func LocalApp(c *cli.Context) (*cmd.CliApp, error) {
+	if err := Setup(c); err != nil {
+		return nil, err
+	}
+	return &cmd.CliApp{}, nil
+}
@@ -0,0 +1,346 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/shims"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+	"github.com/penguin-statistics/soracli/internal/pkg/export"
+	"github.com/penguin-statistics/soracli/internal/services"
+)
+
+const (
+	matrixFormatTable = "table"
+	matrixFormatCSV   = "csv"
+	matrixFormatJSON  = "json"
+)
+
+// AnalyzeMatrix computes the drop matrix of each server from exported drop reports and drop patterns,
+// and writes it as a table, CSV, or the JSON of the v2 matrix shim.
+func (a *CliApp) AnalyzeMatrix(c *cli.Context) error {
+	format := c.String("format")
+	switch format {
+	case matrixFormatTable, matrixFormatCSV, matrixFormatJSON:
+	default:
+		return errors.Errorf("unsupported format %q; supported: %s, %s, %s", format, matrixFormatTable, matrixFormatCSV, matrixFormatJSON)
+	}
+	confidence := c.Float64("confidence")
+	if confidence <= 0 || confidence >= 1 {
+		return errors.Errorf("confidence must be between 0 and 1, exclusive; got %v", confidence)
+	}
+
+	inputs, err := a.readAnalyzeInputs(c)
+	if err != nil {
+		return err
+	}
+	if format == matrixFormatJSON && len(inputs.servers) > 1 {
+		return errors.Errorf("the matrix shim has no server; choose one of %v with --server", inputs.servers)
+	}
+
+	elements := make([]*types.DropMatrixElement, 0)
+	for _, server := range inputs.servers {
+		snapshot, err := inputs.snapshotOf(c, server)
+		if err != nil {
+			return err
+		}
+
+		serverElements, summary, err := services.ComputeDropMatrix(snapshot, server, inputs.reports, inputs.patterns, services.DropMatrixOptions{
+			Confidence:        confidence,
+			IncludeUnreliable: c.Bool("include-unreliable"),
+		})
+		if err != nil {
+			return err
+		}
+		log.Info().
+			Str("server", server).
+			Int("reports", summary.Reports).
+			Int("unreliable", summary.Unreliable).
+			Int("noTimeRange", summary.NoTimeRange).
+			Int("noPattern", summary.NoPattern).
+			Msg("computed drop matrix")
+		elements = append(elements, serverElements...)
+	}
+
+	out, closeOut, err := analyzeOutput(c)
+	if err != nil {
+		return err
+	}
+	defer closeOut()
+
+	switch format {
+	case matrixFormatCSV:
+		return writeMatrixCSV(out, elements)
+	case matrixFormatJSON:
+		return writeMatrixShim(out, elements)
+	default:
+		return writeMatrixTable(out, elements)
+	}
+}
+
+// analyzeInputs are the exported drop data analyze commands work on.
+type analyzeInputs struct {
+	reports  []*models.DropReport
+	patterns []*models.DropPattern
+	// servers are --server, or the servers of the reports
+	servers []string
+
+	a            *CliApp
+	fileSnapshot *types.Snapshot
+}
+
+func (a *CliApp) readAnalyzeInputs(c *cli.Context) (*analyzeInputs, error) {
+	reports, err := readJSONLines[models.DropReport](c.String("reports"))
+	if err != nil {
+		return nil, err
+	}
+	patterns, err := readJSONLines[models.DropPattern](c.String("patterns"))
+	if err != nil {
+		return nil, err
+	}
+	inputs := &analyzeInputs{reports: reports, patterns: patterns, servers: make([]string, 0), a: a}
+
+	if server := c.String("server"); server != "" {
+		inputs.servers = append(inputs.servers, server)
+	} else {
+		reported := make(map[string]bool)
+		for _, report := range reports {
+			reported[report.Server] = true
+		}
+		for _, server := range consts.Servers {
+			if reported[server] {
+				inputs.servers = append(inputs.servers, server)
+			}
+		}
+	}
+
+	if filename := c.String("snapshot"); filename != "" {
+		snapshot, err := export.ReadSnapshot(filename)
+		if err != nil {
+			return nil, err
+		}
+		inputs.fileSnapshot = snapshot
+	}
+	return inputs, nil
+}
+
+// snapshotOf returns the --snapshot file, or the live data of server.
+func (in *analyzeInputs) snapshotOf(c *cli.Context, server string) (*types.Snapshot, error) {
+	if in.fileSnapshot != nil {
+		return in.fileSnapshot, nil
+	}
+	return in.a.TimeRangeService.GetSnapshot(c.Context, server)
+}
+
+// analyzeOutput returns --out, or stdout, and a function to close it.
+func analyzeOutput(c *cli.Context) (io.Writer, func(), error) {
+	filename := c.String("out")
+	if filename == "" {
+		return os.Stdout, func() {}, nil
+	}
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { f.Close() }, nil
+}
+
+func writeMatrixTable(out io.Writer, elements []*types.DropMatrixElement) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVER\tSTAGE\tTIME RANGE\tITEM\tQUANTITY\tTIMES\tRATE\tINTERVAL")
+	for _, element := range elements {
+		fmt.Fprintf(w, "%s\t%s\t%d %s\t%s\t%d\t%d\t%.4f\t[%.4f, %.4f]\n",
+			element.Server, element.ArkStageID, element.TimeRange.RangeID, element.TimeRange.String(), element.ArkItemID,
+			element.Quantity, element.Times, element.Rate, element.Lower, element.Upper)
+	}
+	return w.Flush()
+}
+
+func writeMatrixCSV(out io.Writer, elements []*types.DropMatrixElement) error {
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"server", "stageId", "itemId", "rangeId", "start", "end", "quantity", "times", "rate", "lower", "upper"}); err != nil {
+		return err
+	}
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	for _, element := range elements {
+		if err := w.Write([]string{
+			element.Server,
+			element.ArkStageID,
+			element.ArkItemID,
+			strconv.Itoa(element.TimeRange.RangeID),
+			strconv.FormatInt(element.TimeRange.StartTime.UnixMilli(), 10),
+			strconv.FormatInt(element.TimeRange.EndTime.UnixMilli(), 10),
+			strconv.Itoa(element.Quantity),
+			strconv.Itoa(element.Times),
+			formatFloat(element.Rate),
+			formatFloat(element.Lower),
+			formatFloat(element.Upper),
+		}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeMatrixShim(out io.Writer, elements []*types.DropMatrixElement) error {
+	result := &shims.DropMatrixQueryResult{
+		Matrix: make([]*shims.OneDropMatrixElement, 0, len(elements)),
+	}
+	for _, element := range elements {
+		endTime := element.TimeRange.EndTime.UnixMilli()
+		result.Matrix = append(result.Matrix, &shims.OneDropMatrixElement{
+			StageID:   element.ArkStageID,
+			ItemID:    element.ArkItemID,
+			Quantity:  element.Quantity,
+			Times:     element.Times,
+			StartTime: element.TimeRange.StartTime.UnixMilli(),
+			EndTime:   null.NewInt(endTime, endTime != consts.FakeEndTimeMilli),
+		})
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// readJSONLines reads a file of one JSON value per line, skipping blank lines.
+func readJSONLines[T any](filename string) ([]*T, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make([]*T, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var value T
+		if err := json.Unmarshal(scanner.Bytes(), &value); err != nil {
+			return nil, errors.Wrapf(err, "%s:%d", filename, line)
+		}
+		values = append(values, &value)
+	}
+	return values, scanner.Err()
+}
+
+// AnalyzeBoundsAudit replays exported drop reports against the bounds of the drop infos in force, lists the
+// violations and suggests bounds adjustments.
+func (a *CliApp) AnalyzeBoundsAudit(c *cli.Context) error {
+	inputs, err := a.readAnalyzeInputs(c)
+	if err != nil {
+		return err
+	}
+	out, closeOut, err := analyzeOutput(c)
+	if err != nil {
+		return err
+	}
+	defer closeOut()
+
+	limit := c.Int("limit")
+	for i, server := range inputs.servers {
+		snapshot, err := inputs.snapshotOf(c, server)
+		if err != nil {
+			return err
+		}
+		audit, err := services.AuditDropReportBounds(snapshot, server, inputs.reports, inputs.patterns, services.BoundsAuditOptions{
+			MinSamples: c.Int("min-samples"),
+		})
+		if err != nil {
+			return err
+		}
+		arkStageIDs := make(map[int]string, len(snapshot.Stages))
+		for _, stage := range snapshot.Stages {
+			arkStageIDs[stage.StageID] = stage.ArkStageID
+		}
+		arkStageID := func(stageID int) string {
+			if arkStageID, ok := arkStageIDs[stageID]; ok {
+				return arkStageID
+			}
+			return fmt.Sprintf("#%d", stageID)
+		}
+
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "%s: %d reports, %d violations; %d reliable reports violate the bounds, %d unreliable reports satisfy them\n",
+			server, audit.Summary.Reports, len(audit.Violations), audit.WronglyAccepted, audit.WronglyRejected)
+		if skipped := audit.Summary.NoPattern + audit.Summary.NoTimeRange; skipped > 0 {
+			fmt.Fprintf(out, "%d reports skipped: %d of unknown patterns, %d outside the time ranges of their stages\n",
+				skipped, audit.Summary.NoPattern, audit.Summary.NoTimeRange)
+		}
+
+		if len(audit.Violations) > 0 {
+			fmt.Fprintln(out, "\nVIOLATIONS")
+			w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "REPORT\tSTAGE\tTIME RANGE\tTIMES\tRELIABILITY\tKIND\tSUBJECT\tVALUE\tBOUNDS")
+			for j, violation := range audit.Violations {
+				if limit > 0 && j >= limit {
+					break
+				}
+				bounds := "-"
+				if violation.DropInfo != nil {
+					bounds = formatBounds(violation.DropInfo.Bounds)
+				}
+				fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%s\t%s\t%d\t%s\n",
+					violation.Report.ReportID, arkStageID(violation.Report.StageID), violation.TimeRange.RangeID, violation.Report.Times,
+					violation.Report.Reliability, violation.Kind, violation.Subject, violation.Value, bounds)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if limit > 0 && len(audit.Violations) > limit {
+				fmt.Fprintf(out, "and %d more violations; use --limit 0 to list all\n", len(audit.Violations)-limit)
+			}
+		}
+
+		if len(audit.Suggestions) > 0 {
+			fmt.Fprintln(out, "\nSUGGESTIONS")
+			w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "DROP INFO\tSTAGE\tTIME RANGE\tSUBJECT\tCURRENT\tOBSERVED\tSUGGESTED\tREASON")
+			for _, suggestion := range audit.Suggestions {
+				fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t[%d, %d]\t%s\t%s\n",
+					suggestion.DropInfo.DropID, arkStageID(suggestion.DropInfo.StageID), suggestion.TimeRange.RangeID, suggestion.Subject,
+					formatBounds(suggestion.DropInfo.Bounds), suggestion.Observed.Lower, suggestion.Observed.Upper,
+					formatBounds(suggestion.Suggested), suggestion.Reason)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatBounds(bounds *models.Bounds) string {
+	s := fmt.Sprintf("[%d, %d]", bounds.Lower, bounds.Upper)
+	if len(bounds.Exceptions) > 0 {
+		exceptions := make([]string, 0, len(bounds.Exceptions))
+		for _, exception := range bounds.Exceptions {
+			exceptions = append(exceptions, strconv.Itoa(exception))
+		}
+		s += " except " + strings.Join(exceptions, ",")
+	}
+	return s
+}
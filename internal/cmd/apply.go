@@ -0,0 +1,361 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/guregu/null.v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
+	"github.com/penguin-statistics/soracli/internal/pkg/gdutils"
+)
+
+// manifestEntry is an entry of a manifest resolved for rendering.
+type manifestEntry struct {
+	index     int
+	info      *gamedata.NewEventBasicInfo
+	sourceUrl string
+	rules     *gamedata.RenderRules
+
+	rendered *gamedata.RenderedObjects
+	findings []*gamedata.PreflightFinding
+}
+
+func (e *manifestEntry) String() string {
+	return fmt.Sprintf("entry %d (%s on %s)", e.index+1, e.info.ArkZoneId, e.info.Server)
+}
+
+// ApplyManifest renders every entry of the manifest given by --file, validates them together and shows one
+// combined review, then saves them in order, stopping at the first failure.
+func (a *CliApp) ApplyManifest(c *cli.Context) error {
+	filename := c.String("file")
+	manifest, err := readManifest(filename)
+	if err != nil {
+		return err
+	}
+	// unless --sourceUrl is given, each entry takes the stage table of its region
+	defaultSourceUrl := consts.StageTableURLTemplate
+	if c.IsSet("sourceUrl") {
+		defaultSourceUrl = c.String("sourceUrl")
+	}
+	entries, errs := resolveManifest(manifest, defaultSourceUrl)
+	if len(errs) > 0 {
+		return manifestError(filename, errs)
+	}
+
+	// every entry is rendered before failing, so that all problems are reported at once
+	for _, entry := range entries {
+		rendered, err := a.GameDataService.RenderNewEvent(c.Context, entry.sourceUrl, entry.info)
+		if err == nil {
+			err = a.GameDataService.ApplyRenderRules(c.Context, rendered, entry.rules)
+		}
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, entry.String()))
+			continue
+		}
+		entry.rendered = rendered
+	}
+	errs = append(errs, checkManifestStages(entries)...)
+	if len(errs) > 0 {
+		return manifestError(filename, errs)
+	}
+
+	bundles := make([]*gamedata.RenderedObjects, 0, len(entries))
+	for _, entry := range entries {
+		bundles = append(bundles, entry.rendered)
+	}
+	// the time ranges of the entries are checked together, as they are saved together
+	findings, err := a.GameDataService.CheckRenderedTimeRanges(c.Context, bundles)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to check the rendered time ranges against existing time ranges; skipping")
+	}
+	for i, entryFindings := range findings {
+		entries[i].findings = entryFindings
+	}
+	renderedFile, err := filepath.UnderDataDir(fmt.Sprintf("rendered-manifest-%s.json", strings.TrimSuffix(path.Base(filename), path.Ext(filename))))
+	if err != nil {
+		return err
+	}
+	if err := writeToFile(renderedFile, bundles); err != nil {
+		return err
+	}
+
+	if err := printManifestReview(entries); err != nil {
+		return err
+	}
+	if c.Bool("dry-run") {
+		log.Info().Msgf("dry run; nothing is saved. the rendered game data is in %s", renderedFile)
+		return nil
+	}
+
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Save the %d events above in order", len(entries)),
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		return err
+	}
+
+	return a.submitManifest(c, entries)
+}
+
+// submitManifest saves the rendered entries in order, and shows the status of each. Entries after a failed
+// one are skipped, as they may depend on it.
+func (a *CliApp) submitManifest(c *cli.Context, entries []*manifestEntry) error {
+	statuses := make([]string, len(entries))
+	details := make([]string, len(entries))
+	var failed error
+	session := ""
+	for i, entry := range entries {
+		if failed != nil {
+			statuses[i] = "skipped"
+			continue
+		}
+		applied, err := a.GameDataService.UpdateNewEvent(c.Context, entry.rendered)
+		if err != nil {
+			statuses[i], details[i] = "failed", err.Error()
+			failed = errors.Wrap(err, entry.String())
+			continue
+		}
+		statuses[i] = "applied"
+		if applied == nil {
+			details[i] = "not recorded for undo: the saved entities cannot be told"
+			continue
+		}
+		session = applied.Session
+		details[i] = fmt.Sprintf("created %s; updated %s", countAppliedIDs(applied.Created), countAppliedIDs(applied.Updated))
+		if err := recordAppliedBundle(applied); err != nil {
+			details[i] += "; not recorded for undo: " + err.Error()
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tZONE\tSERVER\tSTATUS\tDETAIL")
+	for i, entry := range entries {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", i+1, entry.info.ArkZoneId, entry.info.Server, statuses[i], details[i])
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if session != "" {
+		log.Info().Msgf("to undo the applied entries, run: soracli undo %s", session)
+	}
+	return failed
+}
+
+func printManifestReview(entries []*manifestEntry) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tZONE\tNAME\tCATEGORY\tSERVER\tSTART\tEND\tSTAGES\tDROP INFOS\tSOURCE")
+	for _, entry := range entries {
+		dropInfos := 0
+		for _, stageDropInfos := range entry.rendered.DropInfosMap {
+			dropInfos += len(stageDropInfos)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			entry.index+1, entry.info.ArkZoneId, entry.info.ZoneName, entry.info.ZoneCategory, entry.info.Server,
+			formatServerTime(entry.info.StartTime, entry.info.Server), formatServerTime(entry.info.EndTime, entry.info.Server),
+			len(entry.rendered.Stages), dropInfos, entry.sourceUrl)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if len(entry.findings) > 0 {
+			fmt.Printf("\n%s:\n%s\n", entry, gamedata.FormatFindings(entry.findings))
+		}
+	}
+	return nil
+}
+
+// readManifest reads a manifest in YAML, or in JSON as a subset of it. Unknown fields are errors, so that
+// misspelled overrides are not silently ignored.
+func readManifest(filename string) (*gamedata.Manifest, error) {
+	if filename == "" {
+		return nil, errors.New("missing manifest; pass --file")
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var manifest gamedata.Manifest
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, errors.Wrapf(err, "invalid manifest %s", filename)
+	}
+	return &manifest, nil
+}
+
+// resolveManifest resolves the times, source url and rules of each entry of manifest. defaultSourceUrl is
+// used if neither the entry nor the manifest has one. The region of an entry only replaces
+// consts.GameDataRegionPlaceholder, so a region with a source url without it is an error.
+func resolveManifest(manifest *gamedata.Manifest, defaultSourceUrl string) ([]*manifestEntry, []error) {
+	if len(manifest.Entries) == 0 {
+		return nil, []error{errors.New("the manifest has no entries")}
+	}
+
+	entries := make([]*manifestEntry, 0, len(manifest.Entries))
+	errs := make([]error, 0)
+	seen := make(map[string]int)
+	for i, e := range manifest.Entries {
+		entry := &manifestEntry{
+			index: i,
+			info: &gamedata.NewEventBasicInfo{
+				ArkZoneId:    e.ArkZoneID,
+				ZoneName:     e.ZoneName,
+				ZoneCategory: e.ZoneCategory,
+				ZoneType:     null.NewString(e.ZoneType, e.ZoneType != ""),
+				Server:       e.Server,
+			},
+			rules: manifest.Rules.Merge(e.Rules),
+		}
+		fail := func(format string, args ...any) {
+			errs = append(errs, errors.Wrap(errors.Errorf(format, args...), entry.String()))
+		}
+
+		switch {
+		case e.ArkZoneID == "":
+			fail("missing arkZoneId")
+		case e.ZoneName == "":
+			fail("missing zoneName")
+		case e.ZoneCategory == "":
+			fail("missing zoneCategory")
+		case e.Server == "":
+			fail("missing server")
+		}
+		if _, ok := consts.ServerGameDataRegions[e.Server]; e.Server != "" && !ok {
+			fail("unknown server %s; expected one of %s", e.Server, strings.Join(consts.Servers, ", "))
+			continue
+		}
+		key := e.ArkZoneID + "@" + e.Server
+		if j, ok := seen[key]; ok {
+			fail("zone %s is already rendered for %s by entry %d", e.ArkZoneID, e.Server, j+1)
+		}
+		seen[key] = i
+
+		var err error
+		if entry.info.StartTime, err = manifestTime(e.StartTime, "startTime", e.StartDate, "startDate", e.Server); err != nil {
+			fail("%s", err)
+		} else if entry.info.StartTime == nil {
+			fail("either startTime or startDate is required")
+		}
+		if entry.info.EndTime, err = manifestTime(e.EndTime, "endTime", e.EndDate, "endDate", e.Server); err != nil {
+			fail("%s", err)
+		}
+		if entry.info.StartTime != nil && entry.info.EndTime != nil && !entry.info.StartTime.Before(*entry.info.EndTime) {
+			fail("start time must be before end time")
+		}
+
+		entry.sourceUrl = defaultSourceUrl
+		for _, sourceUrl := range []string{e.SourceURL, manifest.SourceURL} {
+			if sourceUrl != "" {
+				entry.sourceUrl = sourceUrl
+				break
+			}
+		}
+		if e.Region != "" && !strings.Contains(entry.sourceUrl, consts.GameDataRegionPlaceholder) {
+			fail("region %s has nothing to replace: source url %s has no %s", e.Region, entry.sourceUrl, consts.GameDataRegionPlaceholder)
+		}
+		region := e.Region
+		if region == "" {
+			region = consts.ServerGameDataRegions[e.Server]
+		}
+		entry.sourceUrl = strings.ReplaceAll(entry.sourceUrl, consts.GameDataRegionPlaceholder, region)
+
+		entries = append(entries, entry)
+	}
+	return entries, errs
+}
+
+// manifestTime parses the time of an entry given either as a time or as a date, like serverTimeFromFlags.
+func manifestTime(timeValue, timeField, dateValue, dateField, server string) (*time.Time, error) {
+	var t time.Time
+	var err error
+	switch {
+	case timeValue != "" && dateValue != "":
+		return nil, errors.Errorf("%s and %s cannot be used together", timeField, dateField)
+	case timeValue != "":
+		t, err = gdutils.ParseServerTime(timeValue, server)
+		err = errors.Wrap(err, timeField)
+	case dateValue != "":
+		t, err = gdutils.ParseServerDate(dateValue, server)
+		err = errors.Wrap(err, dateField)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// checkManifestStages checks that no stage is rendered by more than one entry for the same server, as the
+// later entry would overwrite the stage saved by the earlier one.
+func checkManifestStages(entries []*manifestEntry) []error {
+	errs := make([]error, 0)
+	renderedBy := make(map[string]*manifestEntry)
+	for _, entry := range entries {
+		if entry.rendered == nil {
+			continue
+		}
+		for _, stage := range entry.rendered.Stages {
+			key := stage.ArkStageID + "@" + entry.info.Server
+			if other, ok := renderedBy[key]; ok {
+				errs = append(errs, errors.Errorf("%s: stage %s is also rendered by %s", entry, stage.ArkStageID, other))
+				continue
+			}
+			renderedBy[key] = entry
+		}
+	}
+	return errs
+}
+
+func manifestError(filename string, errs []error) error {
+	lines := make([]string, 0, len(errs))
+	for _, err := range errs {
+		lines = append(lines, "  "+strings.ReplaceAll(err.Error(), "\n", "\n  "))
+	}
+	return errors.Errorf("manifest %s is invalid:\n%s", filename, strings.Join(lines, "\n"))
+}
+
+// countAppliedIDs summarizes the entities of ids, e.g. `1 zone, 5 stages`.
+func countAppliedIDs(ids *gamedata.AppliedIDs) string {
+	counts := make([]string, 0)
+	for _, kind := range []struct {
+		singular, plural string
+		ids              []int
+	}{
+		{"zone", "zones", ids.ZoneIDs},
+		{"stage", "stages", ids.StageIDs},
+		{"drop info", "drop infos", ids.DropIDs},
+		{"time range", "time ranges", ids.RangeIDs},
+		{"activity", "activities", ids.ActivityIDs},
+	} {
+		switch len(kind.ids) {
+		case 0:
+		case 1:
+			counts = append(counts, "1 "+kind.singular)
+		default:
+			counts = append(counts, fmt.Sprintf("%d %s", len(kind.ids), kind.plural))
+		}
+	}
+	if len(counts) == 0 {
+		return "nothing"
+	}
+	return strings.Join(counts, ", ")
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+)
+
+func TestReadManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr string
+	}{
+		{
+			name: "YAML",
+			content: `
+sourceUrl: https://example.com/{region}/stage_table.json
+rules:
+  excludeStages: [act1side_ex01]
+entries:
+  - arkZoneId: act1side_zone1
+    zoneName: Side Story
+    zoneCategory: ACTIVITY
+    server: CN
+    startTime: "2022-05-01 16:00"
+  - arkZoneId: act1side_zone1
+    zoneName: Side Story
+    zoneCategory: ACTIVITY
+    server: US
+    startDate: "2022-11-01"
+    region: en_US
+`,
+			want: 2,
+		},
+		{
+			name:    "JSON",
+			content: `{"entries": [{"arkZoneId": "act1side_zone1", "zoneName": "Side Story", "zoneCategory": "ACTIVITY", "server": "CN", "startDate": "2022-05-01"}]}`,
+			want:    1,
+		},
+		{
+			name:    "misspelled override",
+			content: "entries:\n  - arkZoneId: act1side_zone1\n    regoin: en_US\n",
+			wantErr: "field regoin not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "manifest.yaml")
+			if err := os.WriteFile(filename, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			manifest, err := readManifest(filename)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readManifest() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(manifest.Entries) != tt.want {
+				t.Errorf("readManifest() has %d entries, want %d", len(manifest.Entries), tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveManifest(t *testing.T) {
+	entry := func(server string, override func(e *gamedata.ManifestEntry)) *gamedata.ManifestEntry {
+		e := &gamedata.ManifestEntry{
+			ArkZoneID:    "act1side_zone1",
+			ZoneName:     "Side Story",
+			ZoneCategory: "ACTIVITY",
+			Server:       server,
+			StartTime:    "2022-05-01T08:00:00Z",
+			EndTime:      "2022-05-15T08:00:00Z",
+		}
+		if override != nil {
+			override(e)
+		}
+		return e
+	}
+
+	tests := []struct {
+		name          string
+		manifest      *gamedata.Manifest
+		wantSourceUrl []string
+		wantErrs      []string
+	}{
+		{
+			name:          "the default template takes the region of each server",
+			manifest:      &gamedata.Manifest{Entries: []*gamedata.ManifestEntry{entry("CN", nil), entry("US", nil)}},
+			wantSourceUrl: []string{"https://example.com/zh_CN/stage_table.json", "https://example.com/en_US/stage_table.json"},
+		},
+		{
+			name: "the entry overrides the manifest, and the region overrides the one of the server",
+			manifest: &gamedata.Manifest{
+				SourceURL: "https://manifest.example.com/{region}/stage_table.json",
+				Entries: []*gamedata.ManifestEntry{
+					entry("CN", nil),
+					entry("US", func(e *gamedata.ManifestEntry) { e.Region = "zh_CN" }),
+					entry("JP", func(e *gamedata.ManifestEntry) { e.SourceURL = "https://entry.example.com/retro_table.json" }),
+				},
+			},
+			wantSourceUrl: []string{
+				"https://manifest.example.com/zh_CN/stage_table.json",
+				"https://manifest.example.com/zh_CN/stage_table.json",
+				"https://entry.example.com/retro_table.json",
+			},
+		},
+		{
+			name: "a region with nothing to replace",
+			manifest: &gamedata.Manifest{
+				SourceURL: "https://example.com/zh_CN/stage_table.json",
+				Entries:   []*gamedata.ManifestEntry{entry("US", func(e *gamedata.ManifestEntry) { e.Region = "en_US" })},
+			},
+			wantErrs: []string{"entry 1 (act1side_zone1 on US): region en_US has nothing to replace: source url https://example.com/zh_CN/stage_table.json has no {region}"},
+		},
+		{
+			name: "invalid entries",
+			manifest: &gamedata.Manifest{Entries: []*gamedata.ManifestEntry{
+				entry("CN", func(e *gamedata.ManifestEntry) { e.ZoneName = "" }),
+				entry("CN", func(e *gamedata.ManifestEntry) { e.StartDate = "2022-05-01" }),
+				entry("TW", nil),
+				entry("US", func(e *gamedata.ManifestEntry) { e.EndTime = "2022-04-01T08:00:00Z" }),
+			}},
+			wantErrs: []string{
+				"entry 1 (act1side_zone1 on CN): missing zoneName",
+				"entry 2 (act1side_zone1 on CN): zone act1side_zone1 is already rendered for CN by entry 1",
+				"entry 2 (act1side_zone1 on CN): startTime and startDate cannot be used together",
+				"entry 3 (act1side_zone1 on TW): unknown server TW; expected one of " + strings.Join(consts.Servers, ", "),
+				"entry 4 (act1side_zone1 on US): start time must be before end time",
+			},
+		},
+		{
+			name:     "no entries",
+			manifest: &gamedata.Manifest{},
+			wantErrs: []string{"the manifest has no entries"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, errs := resolveManifest(tt.manifest, "https://example.com/"+consts.GameDataRegionPlaceholder+"/stage_table.json")
+			gotErrs := make([]string, 0)
+			for _, err := range errs {
+				gotErrs = append(gotErrs, err.Error())
+			}
+			if len(tt.wantErrs) > 0 || len(gotErrs) > 0 {
+				if !reflect.DeepEqual(gotErrs, tt.wantErrs) {
+					t.Errorf("resolveManifest() errors = %q, want %q", gotErrs, tt.wantErrs)
+				}
+				return
+			}
+			sourceUrls := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				sourceUrls = append(sourceUrls, entry.sourceUrl)
+			}
+			if !reflect.DeepEqual(sourceUrls, tt.wantSourceUrl) {
+				t.Errorf("resolveManifest() source urls = %q, want %q", sourceUrls, tt.wantSourceUrl)
+			}
+		})
+	}
+}
+
+func TestResolveManifestTimesAndRules(t *testing.T) {
+	manifest := &gamedata.Manifest{
+		Rules: &gamedata.RenderRules{
+			ItemBounds:    map[string]*models.Bounds{"30012": {Upper: 3}},
+			ExcludeStages: []string{"act1side_ex01"},
+		},
+		Entries: []*gamedata.ManifestEntry{{
+			ArkZoneID:    "act1side_zone1",
+			ZoneName:     "Side Story",
+			ZoneCategory: "ACTIVITY",
+			Server:       "US",
+			StartTime:    "2022-05-01 04:00",
+			EndDate:      "2022-05-15",
+			Rules: &gamedata.RenderRules{
+				ItemBounds:    map[string]*models.Bounds{"30012": {Upper: 5}, "30013": {Upper: 2}},
+				ExcludeStages: []string{"act1side_ex02"},
+			},
+		}},
+	}
+	entries, errs := resolveManifest(manifest, consts.StageTableURLTemplate)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	entry := entries[0]
+
+	// 04:00 in the local time of US is 11:00 UTC in May, when its game day starts too
+	if want := time.Date(2022, 5, 1, 11, 0, 0, 0, time.UTC); !entry.info.StartTime.Equal(want) {
+		t.Errorf("start time = %s, want %s", entry.info.StartTime, want)
+	}
+	if want := time.Date(2022, 5, 15, 11, 0, 0, 0, time.UTC); !entry.info.EndTime.Equal(want) {
+		t.Errorf("end time = %s, want %s", entry.info.EndTime, want)
+	}
+	wantRules := &gamedata.RenderRules{
+		ItemBounds:     map[string]*models.Bounds{"30012": {Upper: 5}, "30013": {Upper: 2}},
+		DropTypeBounds: map[string]*models.Bounds{},
+		ExcludeStages:  []string{"act1side_ex01", "act1side_ex02"},
+	}
+	if !reflect.DeepEqual(entry.rules, wantRules) {
+		t.Errorf("rules = %+v, want %+v", entry.rules, wantRules)
+	}
+	if want := "https://raw.githubusercontent.com/Kengxxiao/ArknightsGameData/master/en_US/gamedata/excel/stage_table.json"; entry.sourceUrl != want {
+		t.Errorf("source url = %s, want %s", entry.sourceUrl, want)
+	}
+}
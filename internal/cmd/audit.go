@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/pkg/audit"
+)
+
+// ListAuditLog lists the most recent entries of the audit log, newest last.
+func (a *CliApp) ListAuditLog(c *cli.Context) error {
+	entries, err := a.AuditLog.Entries()
+	if err != nil {
+		return err
+	}
+
+	session, operator, endpoint := c.String("session"), c.String("by"), c.String("endpoint")
+	filtered := make([]*audit.Entry, 0, len(entries))
+	for _, entry := range entries {
+		if session != "" && entry.Session != session {
+			continue
+		}
+		if operator != "" && entry.Operator != operator {
+			continue
+		}
+		if endpoint != "" && !strings.HasPrefix(entry.Endpoint, endpoint) {
+			continue
+		}
+		if c.Bool("failed") && entry.Succeeded() {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	if limit := c.Int("limit"); limit > 0 && len(filtered) > limit {
+		filtered = filtered[len(filtered)-limit:]
+	}
+
+	fmt.Printf("%d of %d entries in %s\n\n", len(filtered), len(entries), a.AuditLog.Filename())
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tREQUEST ID\tSESSION\tPROFILE\tOPERATOR\tREQUEST\tSTATUS\tPAYLOAD")
+	for _, entry := range filtered {
+		status := fmt.Sprint(entry.Status)
+		if entry.Status == 0 {
+			status = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s %s\t%s\t%s\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"), entry.RequestID, entry.Session, entry.Profile, entry.Operator,
+			entry.Method, entry.BaseURL+entry.Endpoint, status, shortHash(entry.PayloadHash))
+	}
+	return w.Flush()
+}
+
+// ShowAuditLogEntry prints an entry of the audit log, with its full payload and response.
+func (a *CliApp) ShowAuditLogEntry(c *cli.Context) error {
+	requestID := c.Args().First()
+	if requestID == "" {
+		return errors.New("missing request ID")
+	}
+
+	entries, err := a.AuditLog.Entries()
+	if err != nil {
+		return err
+	}
+	var found *audit.Entry
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.RequestID, requestID) {
+			continue
+		}
+		if found != nil {
+			return errors.Errorf("request ID %q is ambiguous; it is a prefix of both %s and %s", requestID, found.RequestID, entry.RequestID)
+		}
+		found = entry
+	}
+	if found == nil {
+		return errors.Errorf("no entry of request ID %q in %s", requestID, a.AuditLog.Filename())
+	}
+
+	b, err := json.MarshalIndent(found, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// shortHash shortens a hash like `sha256:<hex>` to its first 12 hex digits.
+func shortHash(hash string) string {
+	_, sum, ok := strings.Cut(hash, ":")
+	if !ok || len(sum) < 12 {
+		return hash
+	}
+	return sum[:12]
+}
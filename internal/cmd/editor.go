@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// openInEditor opens files in editor attached to the current terminal, and waits for
+// the editor to exit. editor is a command line, such as `code --wait`.
+func openInEditor(editor string, files ...string) error {
+	args, err := editorCommand(editor)
+	if err != nil {
+		return err
+	}
+	log.Info().Strs("files", files).Msgf("opening in editor: %s", editor)
+	cmd := exec.Command(args[0], append(args[1:], files...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// runEditor and confirmEdit open files in the editor and ask for confirmation; tests replace them.
+var (
+	runEditor   = openInEditor
+	confirmEdit = func(label string) error {
+		prompt := promptui.Prompt{
+			Label:     label,
+			IsConfirm: true,
+		}
+		_, err := prompt.Run()
+		return err
+	}
+)
+
+// editorCommand splits the command line editor into its arguments, as a POSIX shell does for quotes and
+// backslashes. As in the shell, a backslash within double quotes only escapes the characters special there,
+// so that Windows paths may be double-quoted.
+func editorCommand(editor string) ([]string, error) {
+	var (
+		args    []string
+		arg     strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	runes := []rune(editor)
+	for i, r := range runes {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\\' && quote == '"':
+			if i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]) {
+				escaped = true
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.Errorf("invalid editor %q: unterminated quote or escape", editor)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("no editor set; use --editor, or set $VISUAL or $EDITOR")
+	}
+	return args, nil
+}
+
+// editUntilValid opens filename in editor, asks with label whether to continue with the edited file, and
+// reads it back with read. While read fails, the errors are reported in a sidecar file next to filename,
+// which is opened along with it again, until the file reads back or the user aborts. The alongside files
+// are opened along with filename every time, for edits spanning several files.
+func editUntilValid(editor, filename, label string, read func() error, alongside ...string) error {
+	errorsFile := filename + ".errors"
+	defer os.Remove(errorsFile)
+
+	files := append([]string{filename}, alongside...)
+	for {
+		if err := runEditor(editor, files...); err != nil {
+			log.Error().Err(err).Msgf("failed to open %s in editor. you may want to open it manually", strings.Join(files, " and "))
+		}
+
+		if err := confirmEdit(label); err != nil {
+			return err
+		}
+
+		readErr := read()
+		if readErr == nil {
+			return nil
+		}
+
+		report := editErrorReport(filename, readErr)
+		fmt.Fprint(os.Stderr, report)
+		if err := os.WriteFile(errorsFile, []byte(report), 0o644); err != nil {
+			return err
+		}
+
+		if err := confirmEdit(fmt.Sprintf("Reopen the editor to fix them? (otherwise aborts; the edits are kept in %s)", filename)); err != nil {
+			return errors.Wrapf(readErr, "invalid %s", filename)
+		}
+		files = append([]string{filename, errorsFile}, alongside...)
+	}
+}
+
+// editErrorLinePattern matches the lines of error messages of YAML, and of soracli itself, naming the
+// offending line, like `line 12: ...`.
+var editErrorLinePattern = regexp.MustCompile(`line (\d+): (.*)$`)
+
+// editErrorReport reports the errors of reading filename back, as `filename:line: message` followed by the
+// offending line, so that editors and terminals can jump to it.
+func editErrorReport(filename string, err error) string {
+	content, _ := os.ReadFile(filename)
+	lines := strings.Split(string(content), "\n")
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "# %s could not be read back; fix the errors below in it, save it, and exit the editor.\n", path.Base(filename))
+	fmt.Fprintf(&report, "# This file only reports them, and is removed once %s is valid.\n\n", path.Base(filename))
+
+	reportLine := func(line int, message string) {
+		fmt.Fprintf(&report, "%s:%d: %s\n", filename, line, message)
+		if line >= 1 && line <= len(lines) {
+			fmt.Fprintf(&report, "%6d | %s\n", line, lines[line-1])
+		}
+	}
+
+	// JSON errors only know their offset in the file
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		reportLine(offsetLine(content, syntaxErr.Offset), err.Error())
+		return report.String()
+	case errors.As(err, &typeErr):
+		reportLine(offsetLine(content, typeErr.Offset), err.Error())
+		return report.String()
+	}
+
+	for _, message := range strings.Split(err.Error(), "\n") {
+		message = strings.TrimSpace(message)
+		if message == "" {
+			continue
+		}
+		if m := editErrorLinePattern.FindStringSubmatch(message); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			reportLine(line, m[2])
+			continue
+		}
+		fmt.Fprintf(&report, "%s: %s\n", filename, message)
+	}
+	return report.String()
+}
+
+// offsetLine returns the line of content the byte offset is on, counting from 1.
+func offsetLine(content []byte, offset int64) int {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	return strings.Count(string(content[:offset]), "\n") + 1
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/manifoldco/promptui"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+}
+
+func TestEditorCommand(t *testing.T) {
+	tests := []struct {
+		editor  string
+		want    []string
+		wantErr string
+	}{
+		{editor: "vim", want: []string{"vim"}},
+		{editor: "  code   --wait ", want: []string{"code", "--wait"}},
+		{editor: "'/Applications/Sublime Text.app/subl' -w", want: []string{"/Applications/Sublime Text.app/subl", "-w"}},
+		{editor: `"C:\Program Files\Notepad++\notepad++.exe" -multiInst`, want: []string{`C:\Program Files\Notepad++\notepad++.exe`, "-multiInst"}},
+		{editor: `/opt/my\ editor/bin/ed --flag`, want: []string{"/opt/my editor/bin/ed", "--flag"}},
+		{editor: `emacsclient -a '' -t`, want: []string{"emacsclient", "-a", "", "-t"}},
+		{editor: `sh -c 'vi "$1"' --`, want: []string{"sh", "-c", `vi "$1"`, "--"}},
+		{editor: `ed "a\"b"`, want: []string{"ed", `a"b`}},
+		{editor: `ed 'a\b'`, want: []string{"ed", `a\b`}},
+		{editor: "ed pre'quoted'post", want: []string{"ed", "prequotedpost"}},
+		{editor: "vim 'unterminated", wantErr: "unterminated quote or escape"},
+		{editor: `vim "unterminated`, wantErr: "unterminated quote or escape"},
+		{editor: `vim \`, wantErr: "unterminated quote or escape"},
+		{editor: "", wantErr: "no editor set"},
+		{editor: " \t ", wantErr: "no editor set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.editor, func(t *testing.T) {
+			got, err := editorCommand(tt.editor)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("editorCommand(%q) = %q, want %q", tt.editor, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeEditor stands in for the editor and the prompts of editUntilValid: each time the editor opens, the
+// next of edits is written to the edited file, and the answer to a prompt to reopen it is reopen.
+type fakeEditor struct {
+	t      *testing.T
+	edits  []string
+	reopen bool
+
+	opened [][]string
+	// errors is the content of the sidecar file of errors each time the editor opens
+	errors []string
+}
+
+func (f *fakeEditor) install() {
+	run, confirm := runEditor, confirmEdit
+	runEditor, confirmEdit = f.run, f.confirm
+	f.t.Cleanup(func() {
+		runEditor, confirmEdit = run, confirm
+	})
+}
+
+func (f *fakeEditor) run(editor string, files ...string) error {
+	f.opened = append(f.opened, files)
+	errors := ""
+	if len(files) > 1 {
+		b, err := os.ReadFile(files[1])
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		errors = string(b)
+	}
+	f.errors = append(f.errors, errors)
+
+	if len(f.edits) == 0 {
+		f.t.Fatal("the editor is opened more times than expected")
+	}
+	edit := f.edits[0]
+	f.edits = f.edits[1:]
+	return os.WriteFile(files[0], []byte(edit), 0o644)
+}
+
+func (f *fakeEditor) confirm(label string) error {
+	if strings.HasPrefix(label, "Reopen") && !f.reopen {
+		return promptui.ErrAbort
+	}
+	return nil
+}
+
+type editedItem struct {
+	ArkItemID string `json:"itemId"`
+	Name      string `json:"name"`
+	SortID    int    `json:"sortId"`
+}
+
+func TestEditUntilValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		edits   []string
+		reopen  bool
+		want    editedItem
+		wantErr string
+		// line of the error reported in the sidecar file of errors when the editor is reopened
+		wantReported string
+	}{
+		{
+			name:  "valid at once",
+			edits: []string{`{"itemId": "30012", "name": "Orirock Cube", "sortId": 1}`},
+			want:  editedItem{ArkItemID: "30012", Name: "Orirock Cube", SortID: 1},
+		},
+		{
+			name: "fixed after a syntax error",
+			edits: []string{
+				"{\n  \"itemId\": \"30012\",\n  \"name\": \"Orirock Cube\"\n  \"sortId\": 1\n}",
+				"{\n  \"itemId\": \"30012\",\n  \"name\": \"Orirock Cube\",\n  \"sortId\": 1\n}",
+			},
+			reopen:       true,
+			want:         editedItem{ArkItemID: "30012", Name: "Orirock Cube", SortID: 1},
+			wantReported: ":4: invalid character '\"' after object key:value pair\n     4 |   \"sortId\": 1",
+		},
+		{
+			// the first read fails on sortId after taking itemId; the fields dropped on the way to the
+			// fix must not be kept from it
+			name: "fixed after a type error",
+			edits: []string{
+				"{\n  \"itemId\": \"30012\",\n  \"sortId\": \"one\"\n}",
+				"{\n  \"name\": \"Orirock Cube\",\n  \"sortId\": 1\n}",
+			},
+			reopen:       true,
+			want:         editedItem{Name: "Orirock Cube", SortID: 1},
+			wantReported: ":3: json: cannot unmarshal string",
+		},
+		{
+			name:    "aborted",
+			edits:   []string{`{"itemId": 30012}`},
+			wantErr: "cannot unmarshal number",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "item.json")
+			editor := &fakeEditor{t: t, edits: tt.edits, reopen: tt.reopen}
+			editor.install()
+
+			var edited editedItem
+			err := editUntilValid("fake", filename, "Submit?", func() error {
+				edited = editedItem{}
+				return readJSONFromFile(filename, &edited)
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(editor.edits) > 0 {
+				t.Errorf("%d edits left; the editor was opened %d times", len(editor.edits), len(editor.opened))
+			}
+			if tt.wantErr == "" && edited != tt.want {
+				t.Errorf("edited = %+v, want %+v", edited, tt.want)
+			}
+			for i, files := range editor.opened {
+				// the file of errors is opened along with the edited file once there are errors
+				wantFiles := 2
+				if i == 0 {
+					wantFiles = 1
+				}
+				if len(files) != wantFiles {
+					t.Errorf("opening %d opened %v, want %d files", i, files, wantFiles)
+				}
+			}
+			if tt.wantReported != "" && !strings.Contains(editor.errors[1], tt.wantReported) {
+				t.Errorf("reported errors %q, want them to contain %q", editor.errors[1], tt.wantReported)
+			}
+			if _, err := os.Stat(filename + ".errors"); !os.IsNotExist(err) {
+				t.Errorf("the sidecar file of errors is left behind: %v", err)
+			}
+		})
+	}
+}
+
+// TestEditUntilValidAlongside opens the files alongside the edited one every time, after the sidecar file
+// of errors once there are errors.
+func TestEditUntilValidAlongside(t *testing.T) {
+	dir := t.TempDir()
+	filename, alongside := filepath.Join(dir, "existence.json"), filepath.Join(dir, "en.md")
+	if err := os.WriteFile(alongside, []byte("notice"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	editor := &fakeEditor{t: t, edits: []string{`{"CN": `, `{"CN": {"exist": true}}`}, reopen: true}
+	editor.install()
+
+	err := editUntilValid("fake", filename, "Done editing?", func() error {
+		var existence map[string]any
+		return readJSONFromFile(filename, &existence)
+	}, alongside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{filename, alongside}, {filename, filename + ".errors", alongside}}
+	if !reflect.DeepEqual(editor.opened, want) {
+		t.Errorf("opened %v, want %v", editor.opened, want)
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/pkg/export"
+)
+
+// Export downloads every admin-managed entity into an export directory at --out, and into a SQLite
+// database at --sqlite, as a point-in-time backup and an offline dataset for --snapshot.
+func (a *CliApp) Export(c *cli.Context) error {
+	out, sqlite := c.String("out"), c.String("sqlite")
+	if out == "" && sqlite == "" {
+		return errors.New("missing --out directory or --sqlite database to export to")
+	}
+	if sqlite != "" && !export.SQLiteSupported {
+		return export.ErrSQLiteUnsupported
+	}
+
+	snapshot, err := a.GameDataService.Export(c.Context, a.NoticeService)
+	if err != nil {
+		return err
+	}
+	export.SortByID(snapshot)
+
+	manifest := &export.Manifest{
+		FormatVersion: export.FormatVersion,
+		ExportedAt:    time.Now().UTC(),
+		BaseURL:       c.String("baseUrl"),
+	}
+	if out != "" {
+		if manifest, err = export.WriteDir(out, snapshot, c.String("baseUrl")); err != nil {
+			return err
+		}
+		log.Info().Msgf("exported to %s", out)
+	}
+	if sqlite != "" {
+		if err := export.WriteSQLite(sqlite, snapshot, manifest); err != nil {
+			return err
+		}
+		log.Info().Msgf("exported to %s", sqlite)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tCOUNT")
+	for _, count := range []struct {
+		kind  string
+		count int
+	}{
+		{"zones", len(snapshot.Zones)},
+		{"stages", len(snapshot.Stages)},
+		{"time ranges", len(snapshot.TimeRanges)},
+		{"drop infos", len(snapshot.DropInfos)},
+		{"activities", len(snapshot.Activities)},
+		{"items", len(snapshot.Items)},
+		{"notices", len(snapshot.Notices)},
+	} {
+		fmt.Fprintf(w, "%s\t%d\n", count.kind, count.count)
+	}
+	return w.Flush()
+}
+
+// ExportShim converts a rendered bundle, as written by render, into the v2 shim shapes, and
+// writes them to --out or stdout.
+func (a *CliApp) ExportShim(c *cli.Context) error {
+	filename := c.Args().First()
+	if filename == "" {
+		return errors.New("missing rendered bundle file")
+	}
+
+	rendered, err := readFromFile(filename)
+	if err != nil {
+		return err
+	}
+	bundle, err := a.GameDataService.RenderShimBundle(c.Context, rendered)
+	if err != nil {
+		return err
+	}
+
+	if out := c.String("out"); out != "" {
+		if err := writeToFile(out, bundle); err != nil {
+			return err
+		}
+		log.Info().Msgf("exported shim bundle to %s", out)
+		return nil
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bundle)
+}
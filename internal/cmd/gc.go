@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/pkg/cache"
+	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
+	"github.com/penguin-statistics/soracli/internal/pkg/logfile"
+	"github.com/penguin-statistics/soracli/internal/pkg/retention"
+)
+
+// gcCount is what soracli gc removed of a kind of files.
+type gcCount struct {
+	kind  string
+	files int
+	size  int64
+}
+
+// GC removes the log files beyond their retention, the sessions older than --session-max-age, and the
+// entries of the local caches of every admin api that are expired or stale, and lists how much it removed.
+func (a *CliApp) GC(c *cli.Context) error {
+	dryRun := c.Bool("dry-run")
+	now := time.Now()
+	dirs, err := filepath.Locate()
+	if err != nil {
+		return err
+	}
+
+	logs, err := logfile.Prune(c, dryRun)
+	if err != nil {
+		return err
+	}
+	sessions, err := retention.Prune(path.Join(dirs.Data, "applied"), func(name string) bool {
+		return path.Ext(name) == ".json"
+	}, retention.Policy{MaxAge: c.Duration("session-max-age")}, now, dryRun)
+	if err != nil {
+		return err
+	}
+	caches, err := pruneLocalCaches(dirs.Cache, now, dryRun)
+	if err != nil {
+		return err
+	}
+
+	counts := []*gcCount{
+		retentionCount("logs", logs),
+		retentionCount("sessions", sessions),
+		caches,
+	}
+	if dryRun {
+		fmt.Println("would remove, with --dry-run:")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tFILES\tSIZE")
+	for _, count := range counts {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", count.kind, count.files, formatBytes(count.size))
+	}
+	return w.Flush()
+}
+
+func retentionCount(kind string, files []*retention.File) *gcCount {
+	count := &gcCount{kind: kind, files: len(files)}
+	for _, file := range files {
+		count.size += file.Size
+	}
+	return count
+}
+
+// pruneLocalCaches prunes the local cache of each admin api under dir, as does cache.DiskStore.Prune, and
+// removes the caches left empty.
+func pruneLocalCaches(dir string, now time.Time, dryRun bool) (*gcCount, error) {
+	count := &gcCount{kind: "cache entries"}
+	namespaces, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return count, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, namespace := range namespaces {
+		if !namespace.IsDir() {
+			continue
+		}
+		entriesDir := path.Join(dir, namespace.Name(), "entries")
+		if _, err := os.Stat(entriesDir); err != nil {
+			continue
+		}
+		store, err := cache.NewDiskStore(entriesDir, consts.LocalCacheVersion)
+		if err != nil {
+			return nil, err
+		}
+		removed, size, err := store.Prune(now, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		count.files += removed
+		count.size += size
+
+		if dryRun {
+			continue
+		}
+		// os.Remove only removes empty directories
+		if err := os.Remove(entriesDir); err == nil {
+			os.Remove(path.Join(dir, namespace.Name()))
+			log.Debug().Str("dir", path.Join(dir, namespace.Name())).Msg("removed empty local cache")
+		}
+	}
+	return count, nil
+}
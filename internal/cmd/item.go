@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
+)
+
+func (a *CliApp) AddItem(c *cli.Context) error {
+	arkItemId := c.Args().First()
+	if arkItemId == "" {
+		return errors.New("missing ark item ID")
+	}
+	if err := requireUnverifiedEndpoints(c); err != nil {
+		return err
+	}
+
+	itemTables, err := a.GameDataService.FetchItemTables(c.Context, c.String("item-table-url"))
+	if err != nil {
+		return err
+	}
+	item, err := a.GameDataService.RenderNewItem(c.Context, itemTables, arkItemId)
+	if err != nil {
+		return err
+	}
+
+	filename, err := filepath.UnderDataDir(fmt.Sprintf("item-%s.json", arkItemId))
+	if err != nil {
+		return err
+	}
+	if err := writeToFile(filename, item); err != nil {
+		return err
+	}
+
+	var edited models.Item
+	err = editUntilValid(c.String("editor"), filename, "Submit the item with edited file content? (soracli will read the file back before continuing)", func() error {
+		// a read that failed part way must not leave fields of the previous attempt behind
+		edited = models.Item{}
+		return readJSONFromFile(filename, &edited)
+	})
+	if err != nil {
+		return err
+	}
+
+	created, err := a.ItemService.CreateItem(c.Context, &edited)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int("penguinItemId", created.ItemID).Str("arkItemId", created.ArkItemID).Msg("successfully added item")
+	return nil
+}
+
+func (a *CliApp) SyncItems(c *cli.Context) error {
+	itemTables, err := a.GameDataService.FetchItemTables(c.Context, c.String("item-table-url"))
+	if err != nil {
+		return err
+	}
+	report, err := a.GameDataService.DiffItems(c.Context, itemTables, c.StringSlice("item-type"))
+	if err != nil {
+		return err
+	}
+
+	printItemSyncReport(report)
+
+	if len(report.Payload.Create) == 0 && len(report.Payload.Update) == 0 {
+		log.Info().Msg("items are in sync with game data")
+		return nil
+	}
+	if c.Bool("report-only") {
+		return nil
+	}
+	if err := requireUnverifiedEndpoints(c); err != nil {
+		return errors.Wrap(err, "submitting the fixes; pass --report-only to only report")
+	}
+
+	filename, err := filepath.UnderDataDir(fmt.Sprintf("item-sync-%s.json", time.Now().Format("20060102-150405")))
+	if err != nil {
+		return err
+	}
+	if err := writeToFile(filename, report.Payload); err != nil {
+		return err
+	}
+
+	var payload gamedata.ItemSyncPayload
+	err = editUntilValid(c.String("editor"), filename, "Submit the item sync payloads with edited file content? (soracli will read the file back before continuing)", func() error {
+		payload = gamedata.ItemSyncPayload{}
+		return readJSONFromFile(filename, &payload)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range payload.Create {
+		created, err := a.ItemService.CreateItem(c.Context, item)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create item %s", item.ArkItemID)
+		}
+		log.Info().Int("penguinItemId", created.ItemID).Str("arkItemId", created.ArkItemID).Msg("created item")
+	}
+	for _, item := range payload.Update {
+		if _, err := a.ItemService.UpdateItem(c.Context, item); err != nil {
+			return errors.Wrapf(err, "failed to update item %s", item.ArkItemID)
+		}
+		log.Info().Int("penguinItemId", item.ItemID).Str("arkItemId", item.ArkItemID).Msg("updated item")
+	}
+
+	log.Info().Int("created", len(payload.Create)).Int("updated", len(payload.Update)).Msg("successfully synced items")
+	return nil
+}
+
+func printItemSyncReport(report *gamedata.ItemSyncReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Missing from Penguin (%d)\n", len(report.Missing))
+	fmt.Fprintln(w, "  ARK ITEM ID\tNAME\tSERVERS")
+	for _, missing := range report.Missing {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", missing.ArkItemID, missing.Name, strings.Join(missing.Servers, ","))
+	}
+
+	fmt.Fprintf(w, "\nName mismatches (%d)\n", len(report.NameMismatches))
+	fmt.Fprintln(w, "  ID\tARK ITEM ID\tLANG\tPENGUIN\tGAME DATA")
+	for _, mismatch := range report.NameMismatches {
+		fmt.Fprintf(w, "  %d\t%s\t%s\t%s\t%s\n", mismatch.ItemID, mismatch.ArkItemID, mismatch.Lang, mismatch.PenguinName, mismatch.GameDataName)
+	}
+
+	fmt.Fprintf(w, "\nExisting in game data but not on Penguin (%d)\n", len(report.ExistenceMismatches))
+	fmt.Fprintln(w, "  ID\tARK ITEM ID\tSERVER")
+	for _, mismatch := range report.ExistenceMismatches {
+		fmt.Fprintf(w, "  %d\t%s\t%s\n", mismatch.ItemID, mismatch.ArkItemID, mismatch.Server)
+	}
+
+	w.Flush()
+	fmt.Println()
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/pkg/cache"
+)
+
+var errLocalCacheDisabled = errors.New("local cache is disabled by --no-local-cache")
+
+func (a *CliApp) ListLocalCache(c *cli.Context) error {
+	store := cache.PersistentStore()
+	if store == nil {
+		return errLocalCacheDisabled
+	}
+	entries, err := store.Entries()
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	fmt.Printf("local cache of %s at %s (version %s)\n\n", c.String("baseUrl"), store.Dir(), store.Version())
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSIZE\tCREATED\tEXPIRES\tSTATE")
+	for _, entry := range entries {
+		expires := "never"
+		if entry.ExpiresAt != nil {
+			expires = entry.ExpiresAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			entry.Name,
+			formatBytes(entry.Size),
+			entry.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			expires,
+			localCacheEntryState(entry, store.Version(), now),
+		)
+	}
+	return w.Flush()
+}
+
+func (a *CliApp) ClearLocalCache(c *cli.Context) error {
+	store := cache.PersistentStore()
+	if store == nil {
+		return errLocalCacheDisabled
+	}
+	entries, err := store.Entries()
+	if err != nil {
+		return err
+	}
+
+	prefixes := c.Args().Slice()
+	now := time.Now()
+	deleted := 0
+	for _, entry := range entries {
+		if c.Bool("stale") && localCacheEntryState(entry, store.Version(), now) == "ok" {
+			continue
+		}
+		if len(prefixes) > 0 && !hasAnyPrefix(entry.Name, prefixes) {
+			continue
+		}
+		if err := store.Delete(entry.Name); err != nil {
+			return err
+		}
+		deleted++
+	}
+
+	log.Info().Int("deleted", deleted).Int("remaining", len(entries)-deleted).Msg("cleared local cache")
+	return nil
+}
+
+func localCacheEntryState(entry *cache.DiskEntry, version string, now time.Time) string {
+	switch {
+	case entry.Version != version:
+		return "stale (version " + entry.Version + ")"
+	case entry.Expired(now):
+		return "expired"
+	default:
+		return "ok"
+	}
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/mockserver"
+	"github.com/penguin-statistics/soracli/internal/pkg/cache"
+)
+
+func (a *CliApp) ServeMockServer(c *cli.Context) error {
+	// the cache registry of the mock server stands in for the caches of the admin api; it must
+	// not reach into the local cache of the CLI
+	cache.UsePersistentStore(nil)
+
+	store, err := mockserver.NewStore(c.String("state"), c.String("snapshot"))
+	if err != nil {
+		return err
+	}
+
+	addr := c.String("listen")
+	log.Info().Str("addr", addr).Msg("mock admin api listening; use --baseUrl http://" + addr + " --unverified-endpoints to talk to it")
+
+	return http.ListenAndServe(addr, mockserver.New(store, c.String("token")))
+}
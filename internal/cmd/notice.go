@@ -0,0 +1,368 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
+	"github.com/penguin-statistics/soracli/internal/pkg/mdterm"
+)
+
+const noticeExistenceFile = "existence.json"
+
+func (a *CliApp) ListNotices(c *cli.Context) error {
+	notices, err := a.NoticeService.GetNotices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSEVERITY\t"+strings.Join(consts.Servers, "\t")+"\tCONTENT")
+	for _, notice := range notices {
+		existence, err := models.ParseExistence(notice.Existence)
+		if err != nil {
+			return err
+		}
+		severity := "-"
+		if notice.Severity.Valid {
+			severity = strconv.FormatInt(notice.Severity.Int64, 10)
+		}
+		states := make([]string, 0, len(consts.Servers))
+		for _, server := range consts.Servers {
+			states = append(states, noticeState(existence, server, now))
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", notice.NoticeID, severity, strings.Join(states, "\t"), noticeSummary(notice))
+	}
+	return w.Flush()
+}
+
+func (a *CliApp) PreviewNotice(c *cli.Context) error {
+	id, err := strconv.Atoi(c.Args().First())
+	if err != nil {
+		return errors.Wrap(err, "invalid notice id")
+	}
+	notice, err := a.NoticeService.GetNoticeByID(c.Context, id)
+	if err != nil {
+		return err
+	}
+	return previewNotice(notice)
+}
+
+func (a *CliApp) CreateNotice(c *cli.Context) error {
+	if err := requireUnverifiedEndpoints(c); err != nil {
+		return err
+	}
+	notice := &models.Notice{
+		Severity: null.NewInt(c.Int64("severity"), c.IsSet("severity")),
+	}
+	existence := make(models.Existence)
+	for _, server := range consts.Servers {
+		existence[server] = &models.ServerExistence{Exist: false}
+	}
+	if err := applyExistenceFlags(c, existence); err != nil {
+		return err
+	}
+
+	if err := a.editNotice(c, "new-"+time.Now().Format("20060102-150405"), notice, existence); err != nil {
+		return err
+	}
+
+	created, err := a.NoticeService.CreateNotice(c.Context, notice)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int("id", created.NoticeID).Msg("successfully created notice")
+	return nil
+}
+
+func (a *CliApp) UpdateNotice(c *cli.Context) error {
+	if err := requireUnverifiedEndpoints(c); err != nil {
+		return err
+	}
+	id, err := strconv.Atoi(c.Args().First())
+	if err != nil {
+		return errors.Wrap(err, "invalid notice id")
+	}
+	cached, err := a.NoticeService.GetNoticeByID(c.Context, id)
+	if err != nil {
+		return err
+	}
+	// the cached notice must stay as it is on the server if the update fails
+	notice := *cached
+	if c.IsSet("severity") {
+		notice.Severity = null.IntFrom(c.Int64("severity"))
+	}
+	existence, err := models.ParseExistence(notice.Existence)
+	if err != nil {
+		return err
+	}
+	if err := applyExistenceFlags(c, existence); err != nil {
+		return err
+	}
+
+	if err := a.editNotice(c, strconv.Itoa(id), &notice, existence); err != nil {
+		return err
+	}
+
+	if _, err := a.NoticeService.UpdateNotice(c.Context, &notice); err != nil {
+		return err
+	}
+
+	log.Info().Int("id", id).Msg("successfully updated notice")
+	return nil
+}
+
+func (a *CliApp) ExpireNotice(c *cli.Context) error {
+	if err := requireUnverifiedEndpoints(c); err != nil {
+		return err
+	}
+	id, err := strconv.Atoi(c.Args().First())
+	if err != nil {
+		return errors.Wrap(err, "invalid notice id")
+	}
+	cached, err := a.NoticeService.GetNoticeByID(c.Context, id)
+	if err != nil {
+		return err
+	}
+	// the cached notice must stay as it is on the server if the update fails
+	notice := *cached
+	existence, err := models.ParseExistence(notice.Existence)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	servers := c.StringSlice("server")
+	if len(servers) == 0 {
+		servers = consts.Servers
+	}
+	for _, server := range servers {
+		se, ok := existence[server]
+		if !ok || se == nil || !se.Exist {
+			continue
+		}
+		// --at is in the local time of each server
+		at, err := serverTimeFromFlags(c, "at", "", server)
+		if err != nil {
+			return err
+		}
+		if at == nil {
+			at = &now
+		}
+		closeTime := at.UnixMilli()
+		if se.CloseTime != nil && *se.CloseTime <= closeTime {
+			continue
+		}
+		se.CloseTime = &closeTime
+		log.Info().Str("server", server).Time("closeTime", *at).Msg("expiring notice")
+	}
+
+	notice.Existence, err = existence.Marshal()
+	if err != nil {
+		return err
+	}
+	if _, err := a.NoticeService.UpdateNotice(c.Context, &notice); err != nil {
+		return err
+	}
+
+	log.Info().Int("id", id).Msg("successfully expired notice")
+	return nil
+}
+
+// editNotice writes the notice content as one markdown file per language, together with
+// its existence, into a workspace directory, lets the user edit them, reads them back into
+// notice and asks for confirmation after a preview.
+func (a *CliApp) editNotice(c *cli.Context, workspace string, notice *models.Notice, existence models.Existence) error {
+	content := make(map[string]string)
+	if len(notice.Content) > 0 {
+		if err := json.Unmarshal(notice.Content, &content); err != nil {
+			return err
+		}
+	}
+
+	files := make([]string, 0, len(consts.Languages))
+	for _, lang := range consts.Languages {
+		filename, err := filepath.UnderDataDir(fmt.Sprintf("notices/%s/%s.md", workspace, lang))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filename, []byte(content[lang]), 0o644); err != nil {
+			return err
+		}
+		files = append(files, filename)
+	}
+	existenceFilename, err := filepath.UnderDataDir(fmt.Sprintf("notices/%s/%s", workspace, noticeExistenceFile))
+	if err != nil {
+		return err
+	}
+	if err := writeToFile(existenceFilename, existence); err != nil {
+		return err
+	}
+
+	// the existence is the file whose errors can be pointed at, so the read errors are reported next to it
+	err = editUntilValid(c.String("editor"), existenceFilename, "Done editing? (soracli will read the files back and show a preview)", func() error {
+		content = make(map[string]string)
+		for i, lang := range consts.Languages {
+			b, err := os.ReadFile(files[i])
+			if err != nil {
+				return err
+			}
+			if text := strings.TrimSpace(string(b)); text != "" {
+				content[lang] = text + "\n"
+			}
+		}
+		if len(content) == 0 {
+			return errors.New("notice content is empty for every language")
+		}
+
+		b, err := os.ReadFile(existenceFilename)
+		if err != nil {
+			return err
+		}
+		existence, err = models.ParseExistence(b)
+		return err
+	}, files...)
+	if err != nil {
+		return err
+	}
+	for _, lang := range consts.Languages {
+		if _, ok := content[lang]; !ok {
+			log.Warn().Str("lang", lang).Msg("notice content is empty for language; it will be omitted")
+		}
+	}
+
+	notice.Content, err = json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	notice.Existence, err = existence.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := previewNotice(notice); err != nil {
+		return err
+	}
+
+	prompt := promptui.Prompt{
+		Label:     "Submit this notice?",
+		IsConfirm: true,
+	}
+	_, err = prompt.Run()
+	return err
+}
+
+// applyExistenceFlags shows the notice on the servers of --server, from --open-time to --close-time in the
+// local time of each server.
+func applyExistenceFlags(c *cli.Context, existence models.Existence) error {
+	for _, server := range c.StringSlice("server") {
+		se := &models.ServerExistence{Exist: true}
+		openTime, err := serverTimeFromFlags(c, "open-time", "", server)
+		if err != nil {
+			return err
+		}
+		if openTime != nil {
+			t := openTime.UnixMilli()
+			se.OpenTime = &t
+		}
+		closeTime, err := serverTimeFromFlags(c, "close-time", "", server)
+		if err != nil {
+			return err
+		}
+		if closeTime != nil {
+			t := closeTime.UnixMilli()
+			se.CloseTime = &t
+		}
+		existence[server] = se
+	}
+	return nil
+}
+
+func previewNotice(notice *models.Notice) error {
+	content := make(map[string]string)
+	if err := json.Unmarshal(notice.Content, &content); err != nil {
+		return err
+	}
+	existence, err := models.ParseExistence(notice.Existence)
+	if err != nil {
+		return err
+	}
+
+	severity := "none"
+	if notice.Severity.Valid {
+		severity = strconv.FormatInt(notice.Severity.Int64, 10)
+	}
+	fmt.Printf("Notice #%d (severity: %s)\n\n", notice.NoticeID, severity)
+	for _, server := range consts.Servers {
+		fmt.Printf("  %s: %s\n", server, describeExistence(existence[server], server))
+	}
+	for _, lang := range consts.Languages {
+		fmt.Printf("\n──────── %s ────────\n\n", lang)
+		if text, ok := content[lang]; ok {
+			fmt.Print(mdterm.Render(text))
+		} else {
+			fmt.Println("(missing)")
+		}
+	}
+	fmt.Println()
+	return nil
+}
+
+func describeExistence(se *models.ServerExistence, server string) string {
+	if se == nil || !se.Exist {
+		return "not shown"
+	}
+	format := func(ms *int64) string {
+		if ms == nil {
+			return "∞"
+		}
+		return time.UnixMilli(*ms).In(consts.LocMap[server]).Format("2006-01-02 15:04 Z07:00")
+	}
+	return "shown from " + format(se.OpenTime) + " until " + format(se.CloseTime)
+}
+
+func noticeState(existence models.Existence, server string, now time.Time) string {
+	se, ok := existence[server]
+	switch {
+	case !ok || se == nil || !se.Exist:
+		return "-"
+	case existence.ExistsAt(server, now):
+		return "open"
+	case se.OpenTime != nil && now.UnixMilli() < *se.OpenTime:
+		return "scheduled"
+	default:
+		return "expired"
+	}
+}
+
+func noticeSummary(notice *models.Notice) string {
+	content := make(map[string]string)
+	if err := json.Unmarshal(notice.Content, &content); err != nil {
+		return "(invalid content)"
+	}
+	for _, lang := range consts.Languages {
+		if text, ok := content[lang]; ok {
+			line := strings.SplitN(strings.TrimSpace(text), "\n", 2)[0]
+			if r := []rune(line); len(r) > 40 {
+				line = string(r[:40]) + "…"
+			}
+			return line
+		}
+	}
+	return ""
+}
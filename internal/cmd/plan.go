@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/services"
+)
+
+// Plan prints the changes reconciling the live admin data with the desired-state directory given as the
+// argument, and saves them to --out to be applied later with soracli apply.
+func (a *CliApp) Plan(c *cli.Context) error {
+	dir := c.Args().First()
+	if dir == "" {
+		return errors.New("missing desired-state directory")
+	}
+
+	plan, err := a.planReconciliation(c, dir)
+	if err != nil {
+		return err
+	}
+	printPlan(os.Stdout, plan, isTerminal(os.Stdout))
+
+	if out := c.String("out"); out != "" {
+		if err := writeToFile(out, plan); err != nil {
+			return err
+		}
+		log.Info().Msgf("to apply exactly this plan, run: soracli apply %s", out)
+	}
+	return nil
+}
+
+// Apply saves the events of a manifest given by --file, or else applies the plan reconciling the live
+// admin data with a desired-state directory, or a plan saved by soracli plan, given as the argument.
+func (a *CliApp) Apply(c *cli.Context) error {
+	if c.IsSet("file") {
+		return a.ApplyManifest(c)
+	}
+
+	target := c.Args().First()
+	if target == "" {
+		return errors.New("missing desired-state directory or saved plan; or pass --file to apply a manifest")
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+
+	var plan *gamedata.Plan
+	if info.IsDir() {
+		if plan, err = a.planReconciliation(c, target); err != nil {
+			return err
+		}
+	} else {
+		plan = &gamedata.Plan{}
+		if err := readJSONFromFile(target, plan); err != nil {
+			return errors.Wrapf(err, "invalid plan %s", target)
+		}
+		if baseUrl := c.String("baseUrl"); plan.BaseURL != baseUrl {
+			return errors.Errorf("plan %s is for %s, not %s; pass --baseUrl %s", target, plan.BaseURL, baseUrl, plan.BaseURL)
+		}
+	}
+	printPlan(os.Stdout, plan, isTerminal(os.Stdout))
+	if len(plan.Changes) == 0 || c.Bool("dry-run") {
+		return nil
+	}
+	if err := requireUnverifiedEndpoints(c); err != nil {
+		return err
+	}
+
+	prompt := promptui.Prompt{
+		Label:     "Apply the plan above",
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		return err
+	}
+
+	// the plan is only applied as shown, i.e. if the live data has not changed since it was planned
+	live, err := a.GameDataService.LiveState(c.Context)
+	if err != nil {
+		return err
+	}
+	digest, err := services.LiveDigest(live)
+	if err != nil {
+		return err
+	}
+	if digest != plan.LiveDigest {
+		return errors.New("the live data has changed since the plan was made; run soracli plan again")
+	}
+
+	if err := a.GameDataService.ApplyPlan(c.Context, plan); err != nil {
+		return err
+	}
+	log.Info().Msgf("applied %d changes", len(plan.Changes))
+	return nil
+}
+
+func (a *CliApp) planReconciliation(c *cli.Context, dir string) (*gamedata.Plan, error) {
+	desired, err := readDesiredState(dir)
+	if err != nil {
+		return nil, err
+	}
+	live, err := a.GameDataService.LiveState(c.Context)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := services.LiveDigest(live)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := services.PlanReconciliation(desired, live)
+	if err != nil {
+		return nil, err
+	}
+	return &gamedata.Plan{
+		BaseURL:    c.String("baseUrl"),
+		Dir:        dir,
+		PlannedAt:  time.Now(),
+		LiveDigest: digest,
+		Changes:    changes,
+	}, nil
+}
+
+// bundleFields are the top-level fields of a rendered bundle.
+var bundleFields = map[string]bool{"zone": true, "stages": true, "dropInfosMap": true, "timeRange": true, "activity": true}
+
+// readDesiredState reads every JSON file under dir as a rendered bundle, in the order of their paths. Plans
+// saved by soracli plan --out into dir are skipped; any other JSON that is not a bundle is an error.
+func readDesiredState(dir string) ([]*gamedata.DesiredBundle, error) {
+	desired := make([]*gamedata.DesiredBundle, 0)
+	err := fs.WalkDir(os.DirFS(dir), ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || path.Ext(name) != ".json" {
+			return err
+		}
+		file := path.Join(dir, name)
+		var fields map[string]json.RawMessage
+		if err := readJSONFromFile(file, &fields); err != nil {
+			return errors.Wrapf(err, "invalid bundle %s", file)
+		}
+		if _, ok := fields["liveDigest"]; ok {
+			if _, ok := fields["changes"]; ok {
+				log.Info().Str("file", file).Msg("skipping saved plan in the desired-state directory")
+				return nil
+			}
+		}
+		for field := range fields {
+			if !bundleFields[field] {
+				return errors.Errorf("%s is not a rendered bundle: unknown field %q; keep only bundles in the desired-state directory", file, field)
+			}
+		}
+		if fields["zone"] == nil && fields["timeRange"] == nil {
+			return errors.Errorf("%s is not a rendered bundle: it has neither a zone nor a time range", file)
+		}
+
+		bundle := &gamedata.DesiredBundle{File: file, RenderedObjects: &gamedata.RenderedObjects{}}
+		if err := readJSONFromFile(file, bundle.RenderedObjects); err != nil {
+			return errors.Wrapf(err, "invalid bundle %s", file)
+		}
+		desired = append(desired, bundle)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(desired) == 0 {
+		return nil, errors.Errorf("no JSON bundles in %s", dir)
+	}
+	return desired, nil
+}
+
+var planKindHeadings = []struct {
+	kind, heading string
+}{
+	{gamedata.PlanKindZone, "ZONES"},
+	{gamedata.PlanKindTimeRange, "TIME RANGES"},
+	{gamedata.PlanKindStage, "STAGES"},
+	{gamedata.PlanKindDropInfo, "DROP INFOS"},
+}
+
+// printPlan prints the changes of plan by the kind of their entities: `+` creates, `~` updates with the
+// fields changed, and `-` deletes.
+func printPlan(out io.Writer, plan *gamedata.Plan, color bool) {
+	if len(plan.Changes) == 0 {
+		fmt.Fprintf(out, "No changes; the live data matches %s.\n", plan.Dir)
+		return
+	}
+
+	for _, heading := range planKindHeadings {
+		lines := make([]string, 0)
+		for _, change := range plan.Changes {
+			if change.Kind != heading.kind {
+				continue
+			}
+			var line, ansi string
+			switch change.Action {
+			case gamedata.PlanActionCreate:
+				line, ansi = "+ "+change.Key, ansiGreen
+			case gamedata.PlanActionUpdate:
+				line, ansi = fmt.Sprintf("~ %s (#%d): %s", change.Key, change.ID, strings.Join(change.Fields, ", ")), ansiYellow
+			case gamedata.PlanActionDelete:
+				line, ansi = fmt.Sprintf("- %s (#%d)", change.Key, change.ID), ansiRed
+			}
+			if color {
+				line = ansi + line + ansiReset
+			}
+			lines = append(lines, "  "+line)
+		}
+		if len(lines) > 0 {
+			fmt.Fprintf(out, "%s\n%s\n", heading.heading, strings.Join(lines, "\n"))
+		}
+	}
+	fmt.Fprintf(out, "\nPlan: %d to create, %d to update, %d to delete.\n",
+		plan.Count(gamedata.PlanActionCreate), plan.Count(gamedata.PlanActionUpdate), plan.Count(gamedata.PlanActionDelete))
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadDesiredState(t *testing.T) {
+	const bundle = `{"zone": {"zoneId": "act1side_zone1"}, "stages": [], "dropInfosMap": {}, "timeRange": null, "activity": null}`
+	const plan = `{"baseUrl": "http://127.0.0.1:9010", "dir": ".", "liveDigest": "abc", "changes": []}`
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "bundles in subdirectories",
+			files: map[string]string{"b.json": bundle, "sub/a.json": bundle, "notes.txt": "not json"},
+			want:  []string{"b.json", "sub/a.json"},
+		},
+		{
+			name:  "plan written into the directory",
+			files: map[string]string{"a.json": bundle, "plan.json": plan},
+			want:  []string{"a.json"},
+		},
+		{
+			name:    "other JSON",
+			files:   map[string]string{"a.json": bundle, "package.json": `{"name": "site"}`},
+			wantErr: `package.json is not a rendered bundle: unknown field "name"`,
+		},
+		{
+			name:    "empty object",
+			files:   map[string]string{"a.json": `{}`},
+			wantErr: "neither a zone nor a time range",
+		},
+		{
+			name:    "array",
+			files:   map[string]string{"a.json": `[]`},
+			wantErr: "invalid bundle",
+		},
+		{
+			name:    "only a plan",
+			files:   map[string]string{"plan.json": plan},
+			wantErr: "no JSON bundles",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				file := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			desired, err := readDesiredState(dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(desired) != len(tt.want) {
+				t.Fatalf("read %d bundles, want %d", len(desired), len(tt.want))
+			}
+			for i, bundle := range desired {
+				if want := filepath.Join(dir, tt.want[i]); bundle.File != want {
+					t.Errorf("bundle %d is %s, want %s", i, bundle.File, want)
+				}
+			}
+		})
+	}
+}
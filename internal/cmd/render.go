@@ -4,94 +4,203 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
+	"strings"
 
 	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v2"
 	"gopkg.in/guregu/null.v3"
 
 	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/pkg/audit"
+	"github.com/penguin-statistics/soracli/internal/pkg/client"
 	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
 	"github.com/penguin-statistics/soracli/internal/services"
 )
 
 type CliApp struct {
-	GameDataService *services.GameDataService
+	GameDataService  *services.GameDataService
+	ItemService      *services.ItemService
+	NoticeService    *services.NoticeService
+	TimeRangeService *services.TimeRangeService
+	StageService     *services.StageService
+	ZoneService      *services.ZoneService
+	ActivityService  *services.ActivityService
+	AuditLog         *audit.Log
 }
 
-func NewCliApp(gameDataService *services.GameDataService) *CliApp {
+func NewCliApp(gameDataService *services.GameDataService, itemService *services.ItemService, noticeService *services.NoticeService, timeRangeService *services.TimeRangeService, stageService *services.StageService, zoneService *services.ZoneService, activityService *services.ActivityService, client *client.Penguin) *CliApp {
 	return &CliApp{
-		GameDataService: gameDataService,
+		GameDataService:  gameDataService,
+		ItemService:      itemService,
+		NoticeService:    noticeService,
+		TimeRangeService: timeRangeService,
+		StageService:     stageService,
+		ZoneService:      zoneService,
+		ActivityService:  activityService,
+		AuditLog:         client.AuditLog(),
 	}
 }
 
+// requireUnverifiedEndpoints fails unless --unverified-endpoints is given, for commands posting to endpoints
+// other than client.KnownEndpoints to fail before anything is edited or confirmed.
+func requireUnverifiedEndpoints(c *cli.Context) error {
+	if c.Bool("unverified-endpoints") {
+		return nil
+	}
+	return client.ErrUnverifiedEndpoint
+}
+
+// previewFormatShim previews the rendered bundle in the v2 shim shapes.
+const previewFormatShim = "shim"
+
 func (a *CliApp) RenderGameData(c *cli.Context) error {
+	preview := c.String("preview")
+	if preview != "" && preview != previewFormatShim {
+		return errors.Errorf("unsupported preview format %q; supported: %s", preview, previewFormatShim)
+	}
+	editFormat := c.String("edit-format")
+	if editFormat != editFormatJSON && editFormat != editFormatYAML {
+		return errors.Errorf("unsupported edit format %q; supported: %s, %s", editFormat, editFormatJSON, editFormatYAML)
+	}
+
+	if missing := missingRenderFlags(c); len(missing) > 0 {
+		if !isInteractive() {
+			return errors.Errorf("required flags %s not set; run in a terminal to be prompted for them", strings.Join(missing, ", "))
+		}
+		if err := a.renderWizard(c); err != nil {
+			return err
+		}
+	}
+
+	server := c.String("server")
+	startTime, err := serverTimeFromFlags(c, "start-time", "start-date", server)
+	if err != nil {
+		return err
+	}
+	if startTime == nil {
+		return errors.New("either --start-time or --start-date is required")
+	}
+	endTime, err := serverTimeFromFlags(c, "end-time", "end-date", server)
+	if err != nil {
+		return err
+	}
+	if endTime != nil && !startTime.Before(*endTime) {
+		return errors.New("start time must be before end time")
+	}
+	if err := confirmServerTimes(server, serverTime{"start", startTime}, serverTime{"end", endTime}); err != nil {
+		return err
+	}
+
 	rendered, err := a.GameDataService.RenderNewEvent(c.Context, c.String("sourceUrl"), &gamedata.NewEventBasicInfo{
 		ArkZoneId:    c.String("ark-zone-id"),
 		ZoneName:     c.String("zone-name"),
 		ZoneCategory: c.String("zone-category"),
 		ZoneType:     null.NewString(c.String("zone-type"), c.IsSet("zone-type")),
-		Server:       c.String("server"),
-		StartTime:    c.Timestamp("start-time"),
-		EndTime:      c.Timestamp("end-time"),
+		Server:       server,
+		StartTime:    startTime,
+		EndTime:      endTime,
 	})
 	if err != nil {
 		return err
 	}
 
-	filename := filepath.UnderDataDir(fmt.Sprintf("rendered-%s.json", rendered.Zone.ArkZoneID))
-	writeToFile(filename, rendered)
+	filename, err := a.writeEditFile(c.Context, rendered, editFormat)
+	if err != nil {
+		return err
+	}
 
-	// open rendered file in editor
-	editor := c.String("editor")
-	log.Info().Msgf("opening rendered file in editor: %s", editor)
-	if err := exec.Command(editor, filename).Run(); err != nil {
-		log.Error().Err(err).Msg("failed to open rendered file in editor. you may want to open it manually")
+	err = editUntilValid(c.String("editor"), filename, "Continue with edited file content? (you can edit the file before continuing, soracli will read the file back before continuing)", func() error {
+		edited, err := readEditFile(filename, editFormat)
+		if err != nil {
+			return err
+		}
+		if err := validateRendered(edited); err != nil {
+			return err
+		}
+		rendered = edited
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	prompt := promptui.Prompt{
-		Label:     "Continue with edited file content? (you can edit the file before continuing, soracli will read the file back before continuing)",
-		IsConfirm: true,
+	if preview == previewFormatShim {
+		if err := a.previewShimBundle(c, rendered); err != nil {
+			return err
+		}
 	}
 
-	if _, err := prompt.Run(); err != nil {
+	applied, err := a.GameDataService.UpdateNewEvent(c.Context, rendered)
+	if err != nil {
 		return err
 	}
 
-	rendered, err = readFromFile(filename)
+	log.Info().Msg("successfully updated game data")
+	if applied == nil {
+		log.Warn().Msg("the saved bundle is not recorded; it cannot be undone with soracli undo")
+		return nil
+	}
+	if err := recordAppliedBundle(applied); err != nil {
+		log.Error().Err(err).Msg("failed to record the applied bundle; it cannot be undone with soracli undo")
+		return nil
+	}
+	log.Info().Msgf("to undo, run: soracli undo %s", applied.Session)
+	return nil
+}
+
+// previewShimBundle writes rendered in the v2 shim shapes next to the rendered file, opens it in
+// the editor and asks whether to continue saving.
+func (a *CliApp) previewShimBundle(c *cli.Context, rendered *gamedata.RenderedObjects) error {
+	bundle, err := a.GameDataService.RenderShimBundle(c.Context, rendered)
 	if err != nil {
 		return err
 	}
 
-	if err = a.GameDataService.UpdateNewEvent(c.Context, rendered); err != nil {
+	filename, err := filepath.UnderDataDir(fmt.Sprintf("rendered-%s.shim.json", rendered.Zone.ArkZoneID))
+	if err != nil {
+		return err
+	}
+	if err := writeToFile(filename, bundle); err != nil {
 		return err
 	}
 
-	log.Info().Msg("successfully updated game data")
-	return nil
+	if err := openInEditor(c.String("editor"), filename); err != nil {
+		log.Error().Err(err).Msgf("failed to open shim preview in editor. you may want to open %s manually", filename)
+	}
+
+	prompt := promptui.Prompt{
+		Label:     "Continue saving the game data previewed? (edits to the preview are not saved)",
+		IsConfirm: true,
+	}
+	_, err = prompt.Run()
+	return err
 }
 
 func readFromFile(filename string) (*gamedata.RenderedObjects, error) {
 	log.Info().Msgf("reading rendered game data back from %s", filename)
-	f, err := os.Open(filename)
-	if err != nil {
+	var rendered gamedata.RenderedObjects
+	if err := readJSONFromFile(filename, &rendered); err != nil {
 		return nil, err
 	}
-	defer f.Close()
 
-	var rendered gamedata.RenderedObjects
-	err = json.NewDecoder(f).Decode(&rendered)
+	return &rendered, nil
+}
+
+func readJSONFromFile(filename string, v any) error {
+	f, err := os.Open(filename)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer f.Close()
 
-	return &rendered, nil
+	return json.NewDecoder(f).Decode(v)
 }
 
 func writeToFile(filename string, data interface{}) error {
 	log.Info().Msgf("writing rendered game data to %s", filename)
-	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY, 0o644)
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/pkg/bundleyaml"
+	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
+)
+
+// edit formats of rendered bundles, as of --edit-format
+const (
+	editFormatJSON = "json"
+	editFormatYAML = "yaml"
+)
+
+// writeEditFile writes rendered in format for editing, and returns the name of the file written.
+func (a *CliApp) writeEditFile(ctx context.Context, rendered *gamedata.RenderedObjects, format string) (string, error) {
+	switch format {
+	case editFormatJSON:
+		filename, err := filepath.UnderDataDir(fmt.Sprintf("rendered-%s.json", rendered.Zone.ArkZoneID))
+		if err != nil {
+			return "", err
+		}
+		return filename, writeToFile(filename, rendered)
+	case editFormatYAML:
+		filename, err := filepath.UnderDataDir(fmt.Sprintf("rendered-%s.yaml", rendered.Zone.ArkZoneID))
+		if err != nil {
+			return "", err
+		}
+
+		// items are only named in comments, so the bundle is still editable without them
+		itemsById := make(map[int]*models.Item)
+		items, err := a.ItemService.GetItems(ctx)
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to fetch items; items are not named in the rendered file")
+		}
+		for _, item := range items {
+			itemsById[item.ItemID] = item
+		}
+
+		b, err := bundleyaml.Encode(rendered, itemsById)
+		if err != nil {
+			return "", err
+		}
+		log.Info().Msgf("writing rendered game data to %s", filename)
+		return filename, os.WriteFile(filename, b, 0o644)
+	default:
+		return "", errors.Errorf("unsupported edit format %q; supported: %s, %s", format, editFormatJSON, editFormatYAML)
+	}
+}
+
+// readEditFile reads back a rendered bundle written by writeEditFile in format.
+func readEditFile(filename, format string) (*gamedata.RenderedObjects, error) {
+	if format != editFormatYAML {
+		return readFromFile(filename)
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := bundleyaml.Decode(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", filename)
+	}
+	return rendered, nil
+}
+
+// validateRendered checks what the editor may have broken in rendered but reading it back does not, reporting
+// each problem on a line of its own.
+func validateRendered(rendered *gamedata.RenderedObjects) error {
+	problems := make([]string, 0)
+	if rendered.Zone == nil || rendered.Zone.ArkZoneID == "" {
+		problems = append(problems, "zone.zoneId is required")
+	}
+	if rendered.TimeRange == nil {
+		problems = append(problems, "timeRange is required")
+	}
+	arkStageIds := make(map[string]bool)
+	for i, stage := range rendered.Stages {
+		if stage == nil || stage.ArkStageID == "" {
+			problems = append(problems, fmt.Sprintf("stages[%d].stageId is required", i))
+			continue
+		}
+		arkStageIds[stage.ArkStageID] = true
+	}
+	for arkStageId := range rendered.DropInfosMap {
+		if !arkStageIds[arkStageId] {
+			problems = append(problems, fmt.Sprintf("dropInfosMap.%s is not a stage of the bundle", arkStageId))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return errors.New(strings.Join(problems, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/pkg/gdutils"
+)
+
+// zoneTypeNone is the choice of rendering a zone without a type.
+const zoneTypeNone = "(none)"
+
+// renderCommandFlags are the flags of render repeated by the command line printed by renderWizard.
+var renderCommandFlags = []string{
+	"ark-zone-id", "zone-name", "zone-category", "zone-type", "server",
+	"start-time", "start-date", "end-time", "end-date", "editor", "preview", "edit-format",
+}
+
+// missingRenderFlags returns the flags render requires but c does not set.
+func missingRenderFlags(c *cli.Context) []string {
+	missing := make([]string, 0)
+	for _, name := range []string{"ark-zone-id", "zone-name", "zone-category", "server"} {
+		if !c.IsSet(name) {
+			missing = append(missing, "--"+name)
+		}
+	}
+	if !c.IsSet("start-time") && !c.IsSet("start-date") {
+		missing = append(missing, "--start-time")
+	}
+	return missing
+}
+
+// renderWizard prompts for the render flags c does not set, sets them on c, and prints the equivalent
+// command line for running render again without prompts.
+func (a *CliApp) renderWizard(c *cli.Context) error {
+	fmt.Println("Some flags of render are not set; answer the prompts below to fill them in.")
+
+	if !c.IsSet("server") {
+		if err := selectFlag(c, "server", "Server", consts.Servers); err != nil {
+			return err
+		}
+	}
+	if !c.IsSet("ark-zone-id") {
+		if err := a.pickZone(c); err != nil {
+			return err
+		}
+	}
+	if !c.IsSet("zone-name") {
+		prompt := promptui.Prompt{
+			Label: "Zone name",
+			Validate: func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return errors.New("zone name is required")
+				}
+				return nil
+			},
+		}
+		name, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		if err := c.Set("zone-name", strings.TrimSpace(name)); err != nil {
+			return err
+		}
+	}
+	if !c.IsSet("zone-category") {
+		if err := selectFlag(c, "zone-category", "Zone category", consts.ZoneCategories); err != nil {
+			return err
+		}
+	}
+	// zones only have types in the mainline category
+	if !c.IsSet("zone-type") && c.String("zone-category") == consts.ZoneCategoryMainline {
+		prompt := promptui.Select{
+			Label: "Zone type",
+			Items: append([]string{zoneTypeNone}, consts.ZoneTypes...),
+		}
+		_, zoneType, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		if zoneType != zoneTypeNone {
+			if err := c.Set("zone-type", zoneType); err != nil {
+				return err
+			}
+		}
+	}
+	if !c.IsSet("start-time") && !c.IsSet("start-date") {
+		if err := promptServerTime(c, "start", true); err != nil {
+			return err
+		}
+	}
+	if !c.IsSet("end-time") && !c.IsSet("end-date") {
+		if err := promptServerTime(c, "end", false); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("\nTo render the same without prompts, run:\n  %s\n\n", renderCommandLine(c))
+	return nil
+}
+
+func selectFlag(c *cli.Context, name, label string, items []string) error {
+	prompt := promptui.Select{
+		Label: label,
+		Items: items,
+	}
+	_, value, err := prompt.Run()
+	if err != nil {
+		return err
+	}
+	return c.Set(name, value)
+}
+
+// zoneChoice is a zone of the stage table offered by pickZone.
+type zoneChoice struct {
+	ID     string
+	Stages string
+}
+
+// pickZone offers the zones of the stage table at --sourceUrl to search and pick from, or asks for the ark
+// zone ID if the stage table cannot be fetched.
+func (a *CliApp) pickZone(c *cli.Context) error {
+	zones, err := a.GameDataService.FetchZoneStages(c.Context, c.String("sourceUrl"))
+	if err != nil || len(zones) == 0 {
+		log.Warn().Err(err).Str("sourceUrl", c.String("sourceUrl")).Msg("cannot list the zones of the stage table; enter the ark zone ID instead")
+		prompt := promptui.Prompt{
+			Label: "Ark zone ID",
+			Validate: func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return errors.New("ark zone ID is required")
+				}
+				return nil
+			},
+		}
+		arkZoneId, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		return c.Set("ark-zone-id", strings.TrimSpace(arkZoneId))
+	}
+
+	choices := make([]*zoneChoice, 0, len(zones))
+	for _, zone := range zones {
+		codes := make([]string, 0, len(zone.Stages))
+		for _, stage := range zone.Stages {
+			codes = append(codes, stage.Code)
+		}
+		stages := strings.Join(codes, ", ")
+		if len(codes) > 6 {
+			stages = fmt.Sprintf("%s, … (%d stages)", strings.Join(codes[:6], ", "), len(codes))
+		}
+		choices = append(choices, &zoneChoice{ID: zone.ArkZoneID, Stages: stages})
+	}
+
+	prompt := promptui.Select{
+		Label: "Ark zone ID (type / to search by zone ID or stage code)",
+		Items: choices,
+		Size:  10,
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ . }}",
+			Active:   "▸ {{ .ID | cyan }}  {{ .Stages | faint }}",
+			Inactive: "  {{ .ID }}  {{ .Stages | faint }}",
+			Selected: "✔ {{ .ID }}",
+		},
+		Searcher: func(input string, index int) bool {
+			input = strings.ToLower(strings.TrimSpace(input))
+			choice := choices[index]
+			return strings.Contains(strings.ToLower(choice.ID), input) || strings.Contains(strings.ToLower(choice.Stages), input)
+		},
+	}
+	index, _, err := prompt.Run()
+	if err != nil {
+		return err
+	}
+	return c.Set("ark-zone-id", choices[index].ID)
+}
+
+// serverDatePattern matches the dates of --start-date and --end-date, as opposed to times.
+var serverDatePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// promptServerTime asks for the start or end of the zone as a date or a time in the local time of
+// --server, previews it, and sets the --<label>-date or --<label>-time flag. An optional time may be left
+// empty.
+func promptServerTime(c *cli.Context, label string, required bool) error {
+	server := c.String("server")
+	parse := func(s string) (string, time.Time, error) {
+		s = strings.TrimSpace(s)
+		if serverDatePattern.MatchString(s) {
+			t, err := gdutils.ParseServerDate(s, server)
+			return label + "-date", t, err
+		}
+		t, err := gdutils.ParseServerTime(s, server)
+		return label + "-time", t, err
+	}
+
+	hint := "never ending if empty"
+	if required {
+		hint = "required"
+	}
+	prompt := promptui.Prompt{
+		Label: fmt.Sprintf("Zone %s as 2006-01-02 (start of the game day) or 2006-01-02 16:00, %s time; %s", label, server, hint),
+		Validate: func(s string) error {
+			if strings.TrimSpace(s) == "" {
+				if required {
+					return errors.Errorf("zone %s is required", label)
+				}
+				return nil
+			}
+			_, _, err := parse(s)
+			return err
+		},
+	}
+	value, err := prompt.Run()
+	if err != nil {
+		return err
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	name, t, err := parse(value)
+	if err != nil {
+		return err
+	}
+	if err := printServerTimes(server, serverTime{label, &t}); err != nil {
+		return err
+	}
+	return c.Set(name, value)
+}
+
+// renderCommandLine returns the command line running render with the flags set on c. The token is left
+// as a placeholder, so that it is not printed.
+func renderCommandLine(c *cli.Context) string {
+	args := []string{"soracli"}
+	for _, name := range []string{"baseUrl", "sourceUrl", "profile"} {
+		if c.IsSet(name) {
+			args = append(args, "--"+name, shellQuote(c.String(name)))
+		}
+	}
+	args = append(args, "--token", "<token>", "render")
+	for _, name := range renderCommandFlags {
+		if c.IsSet(name) {
+			args = append(args, "--"+name, shellQuote(c.String(name)))
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+var shellSafePattern = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes s for POSIX shells, if needed.
+func shellQuote(s string) string {
+	if shellSafePattern.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isInteractive reports whether soracli can prompt, i.e. both stdin and stdout are terminals.
+func isInteractive() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+// newRenderContext returns the context of render, with the global flags of soracli on its parent, given
+// the arguments of each.
+func newRenderContext(t *testing.T, globalArgs, renderArgs []string) *cli.Context {
+	t.Helper()
+	global := flag.NewFlagSet("soracli", flag.ContinueOnError)
+	for _, name := range []string{"baseUrl", "sourceUrl", "profile", "token"} {
+		global.String(name, "", "")
+	}
+	if err := global.Parse(globalArgs); err != nil {
+		t.Fatal(err)
+	}
+	render := flag.NewFlagSet("render", flag.ContinueOnError)
+	for _, name := range renderCommandFlags {
+		render.String(name, "", "")
+	}
+	if err := render.Parse(renderArgs); err != nil {
+		t.Fatal(err)
+	}
+	app := cli.NewApp()
+	return cli.NewContext(app, render, cli.NewContext(app, global, nil))
+}
+
+func TestMissingRenderFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"none set", nil, []string{"--ark-zone-id", "--zone-name", "--zone-category", "--server", "--start-time"}},
+		{
+			name: "start date instead of start time",
+			args: []string{"--ark-zone-id", "act1side_zone1", "--zone-name", "Side Story", "--zone-category", "ACTIVITY", "--server", "CN", "--start-date", "2022-05-01"},
+			want: []string{},
+		},
+		{"some set", []string{"--server", "CN", "--start-time", "2022-05-01 16:00"}, []string{"--ark-zone-id", "--zone-name", "--zone-category"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missingRenderFlags(newRenderContext(t, nil, tt.args)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("missingRenderFlags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderCommandLine(t *testing.T) {
+	c := newRenderContext(t,
+		[]string{"--baseUrl", "https://example.com", "--token", "secret"},
+		[]string{"--server", "CN", "--zone-category", "ACTIVITY"},
+	)
+	// as answered to the prompts
+	for name, value := range map[string]string{"ark-zone-id": "act1side_zone1", "zone-name": "Tom's Side Story", "start-time": "2022-05-01 16:00"} {
+		if err := c.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := `soracli --baseUrl https://example.com --token <token> render --ark-zone-id act1side_zone1 --zone-name 'Tom'\''s Side Story' --zone-category ACTIVITY --server CN --start-time '2022-05-01 16:00'`
+	if got := renderCommandLine(c); got != want {
+		t.Errorf("renderCommandLine() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"act1side_zone1":       "act1side_zone1",
+		"https://example.com/": "https://example.com/",
+		"Side Story":           "'Side Story'",
+		"it's":                 `'it'\''s'`,
+		"":                     "''",
+		"$HOME":                "'$HOME'",
+	}
+	for s, want := range tests {
+		if got := shellQuote(s); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
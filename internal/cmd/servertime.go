@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/pkg/gdutils"
+)
+
+// serverTimeFromFlags parses the time given by timeFlag, or by dateFlag snapped to the start of the
+// game day, in the local time of server. It returns nil if neither flag is set.
+func serverTimeFromFlags(c *cli.Context, timeFlag, dateFlag, server string) (*time.Time, error) {
+	if c.IsSet(timeFlag) && dateFlag != "" && c.IsSet(dateFlag) {
+		return nil, errors.Errorf("--%s and --%s cannot be used together", timeFlag, dateFlag)
+	}
+
+	var t time.Time
+	var err error
+	switch {
+	case c.IsSet(timeFlag):
+		t, err = gdutils.ParseServerTime(c.String(timeFlag), server)
+		err = errors.Wrapf(err, "--%s", timeFlag)
+	case dateFlag != "" && c.IsSet(dateFlag):
+		t, err = gdutils.ParseServerDate(c.String(dateFlag), server)
+		err = errors.Wrapf(err, "--%s", dateFlag)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// serverTime is a parsed time shown by confirmServerTimes.
+type serverTime struct {
+	Label string
+	Time  *time.Time
+}
+
+// confirmServerTimes shows times in UTC and in the local time of server, and asks whether they are right.
+func confirmServerTimes(server string, times ...serverTime) error {
+	if err := printServerTimes(server, times...); err != nil {
+		return err
+	}
+
+	prompt := promptui.Prompt{
+		Label:     "Are the times above right?",
+		IsConfirm: true,
+	}
+	_, err := prompt.Run()
+	return err
+}
+
+// printServerTimes shows times in UTC and in the local time of server.
+func printServerTimes(server string, times ...serverTime) error {
+	loc, err := gdutils.ServerLocation(server)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "\tUTC\t%s (%s)\n", server, consts.LocZoneMap[server])
+	for _, st := range times {
+		if st.Time == nil || st.Time.UnixMilli() == consts.FakeEndTimeMilli {
+			fmt.Fprintf(w, "%s\t∞\t∞\n", st.Label)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", st.Label, st.Time.UTC().Format("2006-01-02 15:04:05 MST"), st.Time.In(loc).Format("2006-01-02 15:04:05 MST -07:00"))
+	}
+	return w.Flush()
+}
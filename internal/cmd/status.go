@@ -0,0 +1,334 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/pkg/gdutils"
+	"github.com/penguin-statistics/soracli/internal/services"
+)
+
+const (
+	// statusLanguage is the language names are shown in, the same one the v2 shims use.
+	statusLanguage = "zh"
+
+	ganttWidth      = 60
+	ganttLabelWidth = 32
+
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31;1m"
+	ansiGreen  = "\x1b[32;1m"
+	ansiYellow = "\x1b[33;1m"
+	ansiFaint  = "\x1b[2m"
+)
+
+// Status shows the zones, stages and activities open on each server at --at, followed by a timeline
+// of the time ranges around then.
+func (a *CliApp) Status(c *cli.Context) error {
+	servers := consts.Servers
+	if server := c.String("server"); server != "" {
+		servers = []string{server}
+	}
+
+	at := time.Now()
+	if c.IsSet("at") {
+		t, err := gdutils.ParseServerTime(c.String("at"), c.String("server"))
+		if err != nil {
+			return err
+		}
+		at = t
+	}
+	window := time.Duration(c.Int("days")) * 24 * time.Hour
+
+	zones, err := a.ZoneService.GetZones(c.Context)
+	if err != nil {
+		return err
+	}
+	activities, err := a.ActivityService.GetActivities(c.Context)
+	if err != nil {
+		return err
+	}
+
+	color := isTerminal(os.Stdout)
+	for i, server := range servers {
+		if i > 0 {
+			fmt.Println()
+		}
+		snapshot, err := a.TimeRangeService.GetSnapshot(c.Context, server)
+		if err != nil {
+			return err
+		}
+		st := &serverStatus{
+			server:     server,
+			at:         at,
+			zones:      zones,
+			activities: activities,
+			stages:     snapshot.Stages,
+			timeRanges: snapshot.TimeRanges,
+			dropInfos:  snapshot.DropInfos,
+			all:        c.Bool("all"),
+			color:      color,
+		}
+		if err := st.print(os.Stdout); err != nil {
+			return err
+		}
+		fmt.Println()
+		if err := st.printTimeline(os.Stdout, at.Add(-window), at.Add(window)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type serverStatus struct {
+	server     string
+	at         time.Time
+	zones      []*models.Zone
+	activities []*models.Activity
+	stages     []*models.Stage
+	timeRanges []*models.TimeRange
+	dropInfos  []*models.DropInfo
+	all        bool
+	color      bool
+}
+
+func (st *serverStatus) print(out io.Writer) error {
+	loc, err := gdutils.ServerLocation(st.server)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s (%s) at %s\n\n", st.server, consts.LocZoneMap[st.server], st.at.In(loc).Format("2006-01-02 15:04:05 MST -07:00"))
+
+	fmt.Fprintln(out, "ACTIVITIES")
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTART\tEND")
+	for _, activity := range st.activities {
+		existence, err := models.ParseExistence(activity.Existence)
+		if err != nil {
+			return err
+		}
+		if se, ok := existence[st.server]; !ok || se == nil || !se.Exist || !activityOpenAt(activity, st.at) {
+			continue
+		}
+		name, err := services.I18nValue(activity.Name, statusLanguage)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", activity.ActivityID, name, formatServerTime(activity.StartTime, st.server), st.endTime(activity.EndTime))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	// time ranges each stage has drops in at the moment
+	openRanges := make(map[int]map[int]bool)
+	timeRangesMap := make(map[int]*models.TimeRange, len(st.timeRanges))
+	for _, timeRange := range st.timeRanges {
+		timeRangesMap[timeRange.RangeID] = timeRange
+	}
+	for _, dropInfo := range st.dropInfos {
+		timeRange, ok := timeRangesMap[dropInfo.RangeID]
+		if !ok || st.at.Before(*timeRange.StartTime) || !st.at.Before(*timeRange.EndTime) {
+			continue
+		}
+		if openRanges[dropInfo.StageID] == nil {
+			openRanges[dropInfo.StageID] = make(map[int]bool)
+		}
+		openRanges[dropInfo.StageID][dropInfo.RangeID] = true
+	}
+
+	stagesByZone := make(map[int][]*models.Stage)
+	for _, stage := range st.stages {
+		stagesByZone[stage.ZoneID] = append(stagesByZone[stage.ZoneID], stage)
+	}
+
+	fmt.Fprintln(out, "\nZONES")
+	w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ZONE\tCATEGORY\tOPEN\tCLOSE\tSTAGES (TIME RANGES)")
+	permanent := 0
+	for _, zone := range st.zones {
+		existence, err := models.ParseExistence(zone.Existence)
+		if err != nil {
+			return err
+		}
+		if !existence.ExistsAt(st.server, st.at) {
+			continue
+		}
+		se := existence[st.server]
+		if se.CloseTime == nil && !st.all {
+			permanent++
+			continue
+		}
+
+		stages := make([]string, 0)
+		for _, stage := range stagesByZone[zone.ZoneID] {
+			stageExistence, err := models.ParseExistence(stage.Existence)
+			if err != nil {
+				return err
+			}
+			if !stageExistence.ExistsAt(st.server, st.at) {
+				continue
+			}
+			code, err := services.I18nValue(stage.Code, statusLanguage)
+			if err != nil {
+				return err
+			}
+			if code == "" {
+				code = stage.ArkStageID
+			}
+			rangeIDs := make([]int, 0, len(openRanges[stage.StageID]))
+			for rangeID := range openRanges[stage.StageID] {
+				rangeIDs = append(rangeIDs, rangeID)
+			}
+			sort.Ints(rangeIDs)
+			stages = append(stages, fmt.Sprintf("%s (%s)", code, joinInts(rangeIDs)))
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", zone.ArkZoneID, zone.Category, st.endTimeMilli(se.OpenTime), st.endTimeMilli(se.CloseTime), strings.Join(stages, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if permanent > 0 {
+		fmt.Fprintf(out, "and %d zones open without a close time; use --all to list them\n", permanent)
+	}
+	return nil
+}
+
+// printTimeline prints a Gantt-style timeline of the time ranges of the server overlapping [from, to).
+// Time ranges open throughout the window are only counted, as they are mostly the permanent ones.
+func (st *serverStatus) printTimeline(out io.Writer, from, to time.Time) error {
+	loc, err := gdutils.ServerLocation(st.server)
+	if err != nil {
+		return err
+	}
+
+	ranges := make([]*models.TimeRange, 0)
+	throughout := 0
+	for _, timeRange := range st.timeRanges {
+		if !timeRange.StartTime.Before(to) || !timeRange.EndTime.After(from) {
+			continue
+		}
+		if timeRange.StartTime.Before(from) && timeRange.EndTime.After(to) {
+			throughout++
+			continue
+		}
+		ranges = append(ranges, timeRange)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].StartTime.Before(*ranges[j].StartTime)
+	})
+
+	column := func(t time.Time) int {
+		col := int(float64(t.Sub(from)) / float64(to.Sub(from)) * ganttWidth)
+		if col < 0 {
+			return 0
+		}
+		if col > ganttWidth {
+			return ganttWidth
+		}
+		return col
+	}
+	atColumn := column(st.at)
+
+	fmt.Fprintln(out, "TIMELINE")
+	axis := []rune(strings.Repeat(" ", ganttWidth+1))
+	axis[atColumn] = '▼'
+	fmt.Fprintf(out, "%-*s %s\n", ganttLabelWidth, "", string(axis))
+
+	for _, timeRange := range ranges {
+		start, end := column(*timeRange.StartTime), column(*timeRange.EndTime)
+		if end <= start {
+			end = start + 1
+		}
+		bar := []rune(strings.Repeat(" ", ganttWidth+1))
+		bar[atColumn] = '│'
+		for i := start; i < end && i <= ganttWidth; i++ {
+			bar[i] = '█'
+		}
+		if timeRange.StartTime.Before(from) {
+			bar[0] = '◀'
+		}
+		placeholder := timeRange.EndTime.UnixMilli() == consts.FakeEndTimeMilli
+		if timeRange.EndTime.After(to) {
+			bar[ganttWidth] = '▶'
+		}
+
+		label := "#" + strconv.Itoa(timeRange.RangeID)
+		if timeRange.Name.Valid {
+			label += " " + timeRange.Name.String
+		}
+		if len([]rune(label)) > ganttLabelWidth {
+			label = string([]rune(label)[:ganttLabelWidth-1]) + "…"
+		}
+
+		line := fmt.Sprintf("%-*s %s %s - %s", ganttLabelWidth, label, string(bar), timeRange.StartTime.In(loc).Format("01-02 15:04"), st.endTime(timeRange.EndTime))
+		if placeholder && st.color {
+			line = ansiYellow + line + ansiReset
+		}
+		fmt.Fprintln(out, line)
+	}
+
+	footer := fmt.Sprintf("%s … %s, %d time ranges open throughout", from.In(loc).Format("2006-01-02"), to.In(loc).Format("2006-01-02"), throughout)
+	if st.color {
+		footer = ansiFaint + footer + ansiReset
+	}
+	fmt.Fprintf(out, "%-*s %s\n", ganttLabelWidth, "", footer)
+	return nil
+}
+
+// endTime formats an end time, marking consts.FakeEndTimeMilli as a placeholder to be replaced.
+func (st *serverStatus) endTime(t *time.Time) string {
+	if t != nil && t.UnixMilli() == consts.FakeEndTimeMilli {
+		if st.color {
+			return ansiYellow + "∞ (placeholder)" + ansiReset
+		}
+		return "∞ (placeholder)"
+	}
+	return formatServerTime(t, st.server)
+}
+
+func (st *serverStatus) endTimeMilli(ms *int64) string {
+	if ms == nil {
+		return formatServerTime(nil, st.server)
+	}
+	t := time.UnixMilli(*ms)
+	return st.endTime(&t)
+}
+
+func activityOpenAt(activity *models.Activity, t time.Time) bool {
+	if activity.StartTime != nil && t.Before(*activity.StartTime) {
+		return false
+	}
+	if activity.EndTime != nil && !t.Before(*activity.EndTime) {
+		return false
+	}
+	return true
+}
+
+func joinInts(ints []int) string {
+	if len(ints) == 0 {
+		return "-"
+	}
+	s := make([]string, 0, len(ints))
+	for _, i := range ints {
+		s = append(s, "#"+strconv.Itoa(i))
+	}
+	return strings.Join(s, ", ")
+}
+
+// isTerminal reports whether f is a terminal, to decide whether to color the output.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models"
+)
+
+var (
+	statusAt   = time.Date(2022, 5, 8, 8, 0, 0, 0, time.UTC)
+	statusOpen = statusAt.AddDate(0, 0, -7)
+	fakeEnd    = time.UnixMilli(consts.FakeEndTimeMilli)
+)
+
+// statusExistence is the existence of an object on CN from open until close; a nil close means it never closes.
+func statusExistence(open time.Time, close *time.Time) json.RawMessage {
+	if close == nil {
+		return json.RawMessage(fmt.Sprintf(`{"CN": {"exist": true, "openTime": %d}}`, open.UnixMilli()))
+	}
+	return json.RawMessage(fmt.Sprintf(`{"CN": {"exist": true, "openTime": %d, "closeTime": %d}}`, open.UnixMilli(), close.UnixMilli()))
+}
+
+func testServerStatus() *serverStatus {
+	closed, closing := statusAt.AddDate(0, 0, -1), statusAt.AddDate(0, 0, 7)
+	return &serverStatus{
+		server: "CN",
+		at:     statusAt,
+		zones: []*models.Zone{
+			{ZoneID: 1, ArkZoneID: "act1side_zone1", Category: "ACTIVITY", Existence: statusExistence(statusOpen, &closing)},
+			{ZoneID: 2, ArkZoneID: "act2side_zone1", Category: "ACTIVITY", Existence: statusExistence(statusOpen, &closed)},
+			{ZoneID: 3, ArkZoneID: "main_0", Category: "MAINLINE", Existence: statusExistence(statusOpen, nil)},
+			{ZoneID: 4, ArkZoneID: "act3side_zone1", Category: "ACTIVITY", Existence: statusExistence(statusOpen, &fakeEnd)},
+		},
+		activities: []*models.Activity{
+			{ActivityID: 1, StartTime: &statusOpen, EndTime: &closing, Name: json.RawMessage(`{"zh": "开放中"}`), Existence: statusExistence(statusOpen, &closing)},
+			{ActivityID: 2, StartTime: &statusOpen, EndTime: &closed, Name: json.RawMessage(`{"zh": "已结束"}`), Existence: statusExistence(statusOpen, &closed)},
+			// open, but not on CN
+			{ActivityID: 3, StartTime: &statusOpen, EndTime: &closing, Name: json.RawMessage(`{"zh": "美服"}`), Existence: json.RawMessage(`{"US": {"exist": true}}`)},
+		},
+		stages: []*models.Stage{
+			{StageID: 1, ZoneID: 1, ArkStageID: "act1side_01", Code: json.RawMessage(`{"zh": "SS-1"}`), Existence: statusExistence(statusOpen, &closing)},
+			// no code, so shown by its ark stage ID
+			{StageID: 2, ZoneID: 1, ArkStageID: "act1side_02", Existence: statusExistence(statusOpen, &closing)},
+			// not yet open
+			{StageID: 3, ZoneID: 1, ArkStageID: "act1side_03", Code: json.RawMessage(`{"zh": "SS-3"}`), Existence: statusExistence(closing, nil)},
+			{StageID: 4, ZoneID: 2, ArkStageID: "act2side_01", Existence: statusExistence(statusOpen, &closed)},
+		},
+		timeRanges: []*models.TimeRange{
+			{RangeID: 1, Server: "CN", StartTime: &statusOpen, EndTime: &closing},
+			{RangeID: 2, Server: "CN", StartTime: &statusOpen, EndTime: &fakeEnd, Name: null.StringFrom("rerun")},
+			{RangeID: 3, Server: "CN", StartTime: &statusOpen, EndTime: &closed},
+		},
+		dropInfos: []*models.DropInfo{
+			{StageID: 1, RangeID: 2},
+			{StageID: 1, RangeID: 1},
+			{StageID: 1, RangeID: 1},
+			// the time range has ended
+			{StageID: 2, RangeID: 3},
+		},
+	}
+}
+
+func TestServerStatusPrint(t *testing.T) {
+	tests := []struct {
+		name    string
+		all     bool
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "open events",
+			want: []string{
+				"开放中",
+				"act1side_zone1  ACTIVITY",
+				"SS-1 (#1, #2), act1side_02 (-)",
+				"act3side_zone1",
+				"and 1 zones open without a close time; use --all to list them",
+			},
+			notWant: []string{"已结束", "美服", "act2side_zone1", "SS-3", "main_0"},
+		},
+		{
+			name:    "all",
+			all:     true,
+			want:    []string{"main_0"},
+			notWant: []string{"use --all to list them"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := testServerStatus()
+			st.all = tt.all
+			var out bytes.Buffer
+			if err := st.print(&out); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("status does not show %q:\n%s", want, out.String())
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out.String(), notWant) {
+					t.Errorf("status shows %q:\n%s", notWant, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestServerStatusPlaceholder(t *testing.T) {
+	for _, color := range []bool{false, true} {
+		t.Run(fmt.Sprintf("color %v", color), func(t *testing.T) {
+			st := testServerStatus()
+			st.color = color
+			var out bytes.Buffer
+			if err := st.print(&out); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.printTimeline(&out, statusAt.AddDate(0, 0, -14), statusAt.AddDate(0, 0, 14)); err != nil {
+				t.Fatal(err)
+			}
+
+			placeholder := "∞ (placeholder)"
+			if color {
+				placeholder = ansiYellow + placeholder + ansiReset
+			}
+			for _, line := range strings.Split(out.String(), "\n") {
+				fake := strings.Contains(line, "act3side_zone1") || strings.Contains(line, "#2 rerun")
+				if fake != strings.Contains(line, placeholder) {
+					t.Errorf("line %q shows the placeholder %v, want %v", line, !fake, fake)
+				}
+				// the whole timeline bar of a time range of the fake end time is highlighted
+				if highlighted := strings.HasPrefix(line, ansiYellow+"#"); highlighted != (color && strings.Contains(line, "#2 rerun")) {
+					t.Errorf("line %q is highlighted %v", line, highlighted)
+				}
+			}
+		})
+	}
+}
+
+func TestActivityOpenAt(t *testing.T) {
+	start, end := statusOpen, statusAt.AddDate(0, 0, 7)
+	tests := []struct {
+		name     string
+		activity *models.Activity
+		at       time.Time
+		want     bool
+	}{
+		{"open", &models.Activity{StartTime: &start, EndTime: &end}, statusAt, true},
+		{"at the start", &models.Activity{StartTime: &start, EndTime: &end}, start, true},
+		{"at the end", &models.Activity{StartTime: &start, EndTime: &end}, end, false},
+		{"not yet started", &models.Activity{StartTime: &start, EndTime: &end}, start.Add(-time.Second), false},
+		{"no start or end", &models.Activity{}, statusAt, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := activityOpenAt(tt.activity, tt.at); got != tt.want {
+				t.Errorf("activityOpenAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+	"github.com/penguin-statistics/soracli/internal/pkg/export"
+	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
+	"github.com/penguin-statistics/soracli/internal/pkg/gdutils"
+	"github.com/penguin-statistics/soracli/internal/services"
+)
+
+func (a *CliApp) ListTimeRanges(c *cli.Context) error {
+	server := c.String("server")
+	timeRanges, err := a.TimeRangeService.GetTimeRanges(c.Context, server)
+	if err != nil {
+		return err
+	}
+
+	var overlapping *models.TimeRange
+	if c.IsSet("overlapping") {
+		overlapping, err = parseTimeRange(c.String("overlapping"))
+		if err != nil {
+			return err
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTART\tEND\tRANGE\tNAME\tCOMMENT")
+	for _, timeRange := range timeRanges {
+		if overlapping != nil && !timeRangesOverlap(timeRange, overlapping) {
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			timeRange.RangeID,
+			formatServerTime(timeRange.StartTime, server),
+			formatServerTime(timeRange.EndTime, server),
+			timeRange.String(),
+			timeRange.Name.ValueOrZero(),
+			timeRange.Comment.ValueOrZero(),
+		)
+	}
+	return w.Flush()
+}
+
+func (a *CliApp) CreateTimeRange(c *cli.Context) error {
+	if err := requireUnverifiedEndpoints(c); err != nil {
+		return err
+	}
+	timeRange := &models.TimeRange{
+		Name:    null.NewString(c.String("name"), c.IsSet("name")),
+		Comment: null.NewString(c.String("comment"), c.IsSet("comment")),
+		Server:  c.String("server"),
+	}
+
+	if c.IsSet("range") {
+		parsed, err := parseTimeRange(c.String("range"))
+		if err != nil {
+			return err
+		}
+		timeRange.StartTime, timeRange.EndTime = parsed.StartTime, parsed.EndTime
+	} else {
+		startTime, err := serverTimeFromFlags(c, "start-time", "start-date", timeRange.Server)
+		if err != nil {
+			return err
+		}
+		if startTime == nil {
+			return errors.New("either --range, --start-time or --start-date is required")
+		}
+		timeRange.StartTime = startTime
+		endTime, err := serverTimeFromFlags(c, "end-time", "end-date", timeRange.Server)
+		if err != nil {
+			return err
+		}
+		if endTime == nil {
+			fakeEndTime := time.UnixMilli(consts.FakeEndTimeMilli)
+			endTime = &fakeEndTime
+		}
+		timeRange.EndTime = endTime
+	}
+	if !timeRange.StartTime.Before(*timeRange.EndTime) {
+		return errors.New("start time must be before end time")
+	}
+	if err := printServerTimes(timeRange.Server, serverTime{"start", timeRange.StartTime}, serverTime{"end", timeRange.EndTime}); err != nil {
+		return err
+	}
+
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Create time range %s on %s?", timeRange.String(), timeRange.Server),
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		return err
+	}
+
+	created, err := a.TimeRangeService.CreateTimeRange(c.Context, timeRange)
+	if err != nil {
+		return err
+	}
+	log.Info().Int("rangeId", created.RangeID).Str("range", created.String()).Msg("successfully created time range")
+	return nil
+}
+
+// SplitTimeRange ends a time range at --at, and creates a new time range from then on, onto which
+// the drop infos of the original range are duplicated. The duplicated drop infos are reviewed in
+// the editor before submitting, so that the drops changed mid-run can be edited right away.
+func (a *CliApp) SplitTimeRange(c *cli.Context) error {
+	if err := requireUnverifiedEndpoints(c); err != nil {
+		return err
+	}
+	original, err := a.timeRangeFromArg(c, c.Args().First())
+	if err != nil {
+		return err
+	}
+	splitTime, err := serverTimeFromFlags(c, "at", "at-date", original.Server)
+	if err != nil {
+		return err
+	}
+	if splitTime == nil {
+		return errors.New("either --at or --at-date is required")
+	}
+	if original.StartTime == nil || original.EndTime == nil {
+		return errors.Errorf("time range %d has no start or end time", original.RangeID)
+	}
+	if !splitTime.After(*original.StartTime) || !splitTime.Before(*original.EndTime) {
+		return errors.Errorf("--at must be within time range %d, from %s until %s", original.RangeID, formatServerTime(original.StartTime, original.Server), formatServerTime(original.EndTime, original.Server))
+	}
+
+	if err := printServerTimes(original.Server, serverTime{"split at", splitTime}); err != nil {
+		return err
+	}
+
+	dropInfos, err := a.TimeRangeService.GetDropInfosByRangeID(c.Context, original.RangeID)
+	if err != nil {
+		return err
+	}
+	duplicated := make([]*models.DropInfo, 0, len(dropInfos))
+	for _, dropInfo := range dropInfos {
+		dup := *dropInfo
+		dup.DropID = 0
+		dup.RangeID = 0
+		if dropInfo.Bounds != nil {
+			bounds := *dropInfo.Bounds
+			bounds.Exceptions = append([]int(nil), dropInfo.Bounds.Exceptions...)
+			dup.Bounds = &bounds
+		}
+		duplicated = append(duplicated, &dup)
+	}
+
+	req := &types.SplitTimeRangeRequest{
+		RangeID:   original.RangeID,
+		SplitTime: splitTime.UnixMilli(),
+		Name:      null.NewString(c.String("name"), c.IsSet("name")),
+		Comment:   null.NewString(c.String("comment"), c.IsSet("comment")),
+		DropInfos: duplicated,
+	}
+
+	filename, err := filepath.UnderDataDir(fmt.Sprintf("timerange-split-%d.json", original.RangeID))
+	if err != nil {
+		return err
+	}
+	if err := writeToFile(filename, req); err != nil {
+		return err
+	}
+	var edited types.SplitTimeRangeRequest
+	label := fmt.Sprintf("Split time range %d at %s, with the drop infos in the edited file? (soracli will read the file back before continuing)", original.RangeID, formatServerTime(splitTime, original.Server))
+	err = editUntilValid(c.String("editor"), filename, label, func() error {
+		edited = types.SplitTimeRangeRequest{}
+		return readJSONFromFile(filename, &edited)
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := a.TimeRangeService.SplitTimeRange(c.Context, &edited)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Int("originalRangeId", resp.Original.RangeID).
+		Str("originalRange", resp.Original.String()).
+		Int("rangeId", resp.TimeRange.RangeID).
+		Str("range", resp.TimeRange.String()).
+		Int("dropInfos", len(resp.DropInfos)).
+		Msg("successfully split time range")
+	return nil
+}
+
+func (a *CliApp) MergeTimeRanges(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return errors.New("at least two time range IDs are required")
+	}
+	if err := requireUnverifiedEndpoints(c); err != nil {
+		return err
+	}
+
+	req := &types.MergeTimeRangesRequest{}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSERVER\tSTART\tEND\tRANGE")
+	for _, arg := range c.Args().Slice() {
+		timeRange, err := a.timeRangeFromArg(c, arg)
+		if err != nil {
+			return err
+		}
+		req.RangeIDs = append(req.RangeIDs, timeRange.RangeID)
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", timeRange.RangeID, timeRange.Server, formatServerTime(timeRange.StartTime, timeRange.Server), formatServerTime(timeRange.EndTime, timeRange.Server), timeRange.String())
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	prompt := promptui.Prompt{
+		Label:     "Merge the time ranges above into the earliest one? (drop infos of the later ranges are moved onto it, or deleted if it has the same drop already)",
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		return err
+	}
+
+	resp, err := a.TimeRangeService.MergeTimeRanges(c.Context, req)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Int("rangeId", resp.TimeRange.RangeID).
+		Str("range", resp.TimeRange.String()).
+		Ints("deletedRangeIds", resp.DeletedRangeIDs).
+		Ints("movedDropIds", resp.MovedDropIDs).
+		Ints("deletedDropIds", resp.DeletedDropIDs).
+		Msg("successfully merged time ranges")
+	return nil
+}
+
+// CheckTimeRanges reports overlapping time ranges, short gaps between them and conflicting Accumulable
+// flags of drop infos of a server, over live data or a snapshot.
+func (a *CliApp) CheckTimeRanges(c *cli.Context) error {
+	server := c.String("server")
+
+	var snapshot *types.Snapshot
+	if filename := c.String("snapshot"); filename != "" {
+		var err error
+		if snapshot, err = export.ReadSnapshot(filename); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		snapshot, err = a.TimeRangeService.GetSnapshot(c.Context, server)
+		if err != nil {
+			return err
+		}
+	}
+
+	findings := services.CheckTimeRanges(snapshot, server, 0)
+	if len(findings) == 0 {
+		fmt.Printf("no problems found in the time ranges of %s\n", server)
+	} else {
+		fmt.Printf("%d problems found in the time ranges of %s:\n%s\n", len(findings), server, gamedata.FormatFindings(findings))
+	}
+
+	if !c.Bool("accumulable") {
+		return nil
+	}
+
+	arkStageIDs := make(map[int]string, len(snapshot.Stages))
+	for _, stage := range snapshot.Stages {
+		arkStageIDs[stage.StageID] = stage.ArkStageID
+	}
+	arkItemIDs := make(map[int]string, len(snapshot.Items))
+	for _, item := range snapshot.Items {
+		arkItemIDs[item.ItemID] = item.ArkItemID
+	}
+
+	maxAccumulableTimeRanges := services.ComputeMaxAccumulableTimeRanges(snapshot, server)
+	stageIDs := make([]int, 0, len(maxAccumulableTimeRanges))
+	for stageID := range maxAccumulableTimeRanges {
+		stageIDs = append(stageIDs, stageID)
+	}
+	sort.Ints(stageIDs)
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STAGE\tITEM\tMAX ACCUMULABLE TIME RANGES")
+	for _, stageID := range stageIDs {
+		itemIDs := make([]int, 0, len(maxAccumulableTimeRanges[stageID]))
+		for itemID := range maxAccumulableTimeRanges[stageID] {
+			itemIDs = append(itemIDs, itemID)
+		}
+		sort.Ints(itemIDs)
+		for _, itemID := range itemIDs {
+			ranges := make([]string, 0)
+			for _, timeRange := range maxAccumulableTimeRanges[stageID][itemID] {
+				ranges = append(ranges, strconv.Itoa(timeRange.RangeID))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", gdutils.ArkIDOf(arkStageIDs, stageID), gdutils.ArkIDOf(arkItemIDs, itemID), strings.Join(ranges, ", "))
+		}
+	}
+	return w.Flush()
+}
+
+// timeRangeFromArg looks up the time range of an ID argument, checking that it is of --server, if given.
+func (a *CliApp) timeRangeFromArg(c *cli.Context, arg string) (*models.TimeRange, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid time range id %q", arg)
+	}
+	timeRange, err := a.TimeRangeService.GetTimeRangeByID(c.Context, id)
+	if err != nil {
+		return nil, err
+	}
+	if server := c.String("server"); server != "" && timeRange.Server != server {
+		return nil, errors.Errorf("time range %d is of server %s, not %s", id, timeRange.Server, server)
+	}
+	return timeRange, nil
+}
+
+// parseTimeRange parses a time range in the millisecond format of models.TimeRange.String,
+// e.g. `1651392000000-1652601599000`.
+func parseTimeRange(s string) (*models.TimeRange, error) {
+	timeRange := models.TimeRangeFromString(s)
+	if timeRange == nil || timeRange.StartTime.UnixMilli() == 0 || timeRange.EndTime.UnixMilli() == 0 {
+		return nil, errors.Errorf("invalid time range %q; expected <startMillis>-<endMillis>", s)
+	}
+	return timeRange, nil
+}
+
+// timeRangesOverlap tells whether a and b overlap, taking a missing start or end time as open.
+func timeRangesOverlap(a, b *models.TimeRange) bool {
+	before := func(start, end *time.Time) bool {
+		return start == nil || end == nil || start.Before(*end)
+	}
+	return before(a.StartTime, b.EndTime) && before(b.StartTime, a.EndTime)
+}
+
+// formatServerTime formats t in the timezone of server, with consts.FakeEndTimeMilli as ∞.
+func formatServerTime(t *time.Time, server string) string {
+	if t == nil || t.UnixMilli() == consts.FakeEndTimeMilli {
+		return "∞"
+	}
+	loc, ok := consts.LocMap[server]
+	if !ok {
+		loc = time.UTC
+	}
+	return t.In(loc).Format("2006-01-02 15:04:05.000 Z07:00")
+}
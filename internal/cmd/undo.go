@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
+	"github.com/penguin-statistics/soracli/internal/services"
+)
+
+// Undo removes what the bundles applied in a session created, and restores the existences of what they
+// updated, after previewing the requests to make.
+func (a *CliApp) Undo(c *cli.Context) error {
+	session := c.Args().First()
+	if session == "" {
+		return errors.New("missing session; see soracli audit list")
+	}
+
+	filename, err := appliedBundlesFile(session)
+	if err != nil {
+		return err
+	}
+	var bundles []*gamedata.AppliedBundle
+	if err := readJSONFromFile(filename, &bundles); err != nil {
+		if os.IsNotExist(err) {
+			return errors.Errorf("no bundle was applied in session %s", session)
+		}
+		return err
+	}
+
+	// the latest bundle is undone first
+	pending := make([]*gamedata.AppliedBundle, 0, len(bundles))
+	for i := len(bundles) - 1; i >= 0; i-- {
+		if bundles[i].UndoneAt == nil {
+			pending = append(pending, bundles[i])
+		}
+	}
+	if len(pending) == 0 {
+		return errors.Errorf("every bundle applied in session %s is undone already", session)
+	}
+	for _, bundle := range pending {
+		if baseUrl := c.String("baseUrl"); bundle.BaseURL != baseUrl {
+			return errors.Errorf("session %s was applied to %s, not %s; pass --baseUrl %s", session, bundle.BaseURL, baseUrl, bundle.BaseURL)
+		}
+	}
+
+	plans := make([][]*gamedata.UndoStep, 0, len(pending))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ZONE\tSERVER\tAPPLIED\tACTION\tKIND\tIDS\tREQUEST")
+	for _, bundle := range pending {
+		steps := services.PlanUndo(bundle)
+		plans = append(plans, steps)
+		for _, step := range steps {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\tPOST %s\n",
+				bundle.Rendered.Zone.ArkZoneID, bundle.Server, bundle.AppliedAt.Local().Format("2006-01-02 15:04:05"),
+				step.Action, step.Kind, joinIDs(step.IDs), step.Endpoint)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if c.Bool("dry-run") {
+		log.Info().Msg("dry run; nothing is undone")
+		return nil
+	}
+	if err := requireUnverifiedEndpoints(c); err != nil {
+		return err
+	}
+
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Undo session %s as listed above", session),
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		return err
+	}
+
+	for i, bundle := range pending {
+		if err := a.GameDataService.Undo(c.Context, bundle, plans[i]); err != nil {
+			return err
+		}
+		now := time.Now()
+		bundle.UndoneAt = &now
+		if err := writeToFile(filename, bundles); err != nil {
+			return err
+		}
+		log.Info().Str("zone", bundle.Rendered.Zone.ArkZoneID).Msg("successfully undid the applied bundle")
+	}
+	return nil
+}
+
+func appliedBundlesFile(session string) (string, error) {
+	return filepath.UnderDataDir(fmt.Sprintf("applied/%s.json", session))
+}
+
+// recordAppliedBundle adds applied to the bundles applied in its session.
+func recordAppliedBundle(applied *gamedata.AppliedBundle) error {
+	filename, err := appliedBundlesFile(applied.Session)
+	if err != nil {
+		return err
+	}
+	bundles := make([]*gamedata.AppliedBundle, 0, 1)
+	if err := readJSONFromFile(filename, &bundles); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return writeToFile(filename, append(bundles, applied))
+}
+
+func joinIDs(ids []int) string {
+	s := make([]string, 0, len(ids))
+	for _, id := range ids {
+		s = append(s, fmt.Sprint(id))
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,234 @@
+package completion
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/appentry"
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models/cache"
+	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
+)
+
+// completer returns the values to complete. Completers only read local files and caches, so that
+// completion stays instant and works offline.
+type completer func(c *cli.Context) []string
+
+// flagValues complete the values of the flags of every command by the names of the flags.
+var flagValues = map[string]completer{
+	"server":        values(consts.Servers),
+	"zone-category": values(consts.ZoneCategories),
+	"zone-type":     values(consts.ZoneTypes),
+	"ark-zone-id":   stageTableZoneIDs,
+	"session":       sessions,
+	"edit-format":   values([]string{"json", "yaml"}),
+	"log-format":    values([]string{"console", "json"}),
+}
+
+// argValues complete the arguments of commands by their full names.
+var argValues = map[string]completer{
+	"undo":              sessions,
+	"local-cache clear": cacheNames,
+}
+
+// Install enables shell completion of app, completing the values of flags and arguments of its commands.
+func Install(app *cli.App) {
+	app.EnableBashCompletion = true
+	install(app.Commands, "")
+}
+
+func install(commands []*cli.Command, parent string) {
+	for _, command := range commands {
+		name := strings.TrimSpace(parent + " " + command.Name)
+		command.BashComplete = complete(command, argValues[name])
+		install(command.Subcommands, name)
+	}
+}
+
+// complete completes the value of the flag the command line ends with, or else the arguments of command
+// along with its subcommands, or the flags starting with what is typed; see cli.DefaultCompleteWithFlags.
+func complete(command *cli.Command, args completer) cli.BashCompleteFunc {
+	defaultComplete := cli.DefaultCompleteWithFlags(command)
+	return func(c *cli.Context) {
+		// whatever is printed is taken as completions
+		log.Logger = zerolog.Nop()
+		filepath.SetDataDir(c.String("data-dir"))
+
+		// the last argument is --generate-bash-completion, and the one before it what is being completed
+		if len(os.Args) > 2 {
+			if last := os.Args[len(os.Args)-2]; strings.HasPrefix(last, "-") {
+				if values, ok := flagValues[flagName(c, command, last)]; ok {
+					printValues(values(c))
+					return
+				}
+			}
+		}
+		if args != nil && c.NArg() == 0 {
+			printValues(args(c))
+		}
+		defaultComplete(c)
+	}
+}
+
+// flagName returns the name of the flag of command, or of the commands and app it runs under, given as arg,
+// e.g. server for -s.
+func flagName(c *cli.Context, command *cli.Command, arg string) string {
+	name := strings.TrimLeft(arg, "-")
+	flags := append([]cli.Flag(nil), command.Flags...)
+	for _, ctx := range c.Lineage() {
+		if ctx.App != nil {
+			flags = append(flags, ctx.App.Flags...)
+		}
+	}
+	for _, flag := range flags {
+		for _, alias := range flag.Names() {
+			if alias == name {
+				return flag.Names()[0]
+			}
+		}
+	}
+	return name
+}
+
+func printValues(values []string) {
+	for _, value := range values {
+		fmt.Println(value)
+	}
+}
+
+func values(values []string) completer {
+	return func(*cli.Context) []string {
+		return values
+	}
+}
+
+// stageTableZoneIDs returns the zones of the stage table at --sourceUrl cached by the last render of it.
+func stageTableZoneIDs(c *cli.Context) []string {
+	if err := appentry.UseLocalCache(c); err != nil {
+		return nil
+	}
+	cache.Initialize()
+
+	var ids []string
+	if err := cache.StageTableZoneIDs.Get(c.String("sourceUrl"), &ids); err != nil {
+		return nil
+	}
+	return ids
+}
+
+// sessions returns the sessions bundles were applied in, as recorded for soracli undo.
+func sessions(*cli.Context) []string {
+	// the sessions are the names of the files under applied/
+	dir, err := filepath.UnderDataDir("applied")
+	if err != nil {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	sessions := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && path.Ext(entry.Name()) == ".json" {
+			sessions = append(sessions, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+	return sessions
+}
+
+// cacheNames returns the names of the caches of the registry.
+func cacheNames(*cli.Context) []string {
+	cache.Initialize()
+
+	names := make([]string, 0, len(cache.CacheSingularFlusherMap)+len(cache.CacheSetMap))
+	for name := range cache.CacheSingularFlusherMap {
+		names = append(names, name)
+	}
+	for name := range cache.CacheSetMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Script returns the completion script of prog for shell, one of bash, zsh and fish.
+func Script(shell, prog string) (string, error) {
+	var script string
+	switch shell {
+	case "bash":
+		script = bashScript
+	case "zsh":
+		script = zshScript
+	case "fish":
+		script = fishScript
+	default:
+		return "", errors.Errorf("unsupported shell %q; supported: bash, zsh, fish", shell)
+	}
+	return strings.ReplaceAll(script, "{prog}", prog), nil
+}
+
+// the scripts run prog with the words typed so far and --generate-bash-completion, passing the word being
+// completed only if it is a flag, as cli.DefaultCompleteWithFlags expects
+
+const bashScript = `# bash completion for {prog}; load with: source <({prog} completion bash)
+_{prog}_complete() {
+  local cur opts
+  COMPREPLY=()
+  cur="${COMP_WORDS[COMP_CWORD]}"
+  if [[ "$cur" == "-"* ]]; then
+    opts=$( "${COMP_WORDS[@]:0:$COMP_CWORD}" "$cur" --generate-bash-completion 2>/dev/null )
+  else
+    opts=$( "${COMP_WORDS[@]:0:$COMP_CWORD}" --generate-bash-completion 2>/dev/null )
+  fi
+  local IFS=$'\n'
+  COMPREPLY=( $(compgen -W "${opts}" -- "$cur") )
+  return 0
+}
+
+complete -o bashdefault -o default -F _{prog}_complete {prog}
+`
+
+const zshScript = `#compdef {prog}
+# zsh completion for {prog}; load with: source <({prog} completion zsh)
+
+_{prog}_complete() {
+  local -a opts
+  local cur
+  cur=${words[-1]}
+  if [[ "$cur" == "-"* ]]; then
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} ${cur} --generate-bash-completion 2>/dev/null)}")
+  else
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} --generate-bash-completion 2>/dev/null)}")
+  fi
+
+  if [[ "${opts[1]}" != "" ]]; then
+    _describe 'values' opts
+  else
+    _files
+  fi
+}
+
+compdef _{prog}_complete {prog}
+`
+
+const fishScript = `# fish completion for {prog}; load with: {prog} completion fish | source
+function __{prog}_complete
+    set -l args (commandline -opc)
+    set -l cur (commandline -ct)
+    if string match -q -- '-*' $cur
+        $args $cur --generate-bash-completion 2>/dev/null
+    else
+        $args --generate-bash-completion 2>/dev/null
+    end
+end
+
+complete -c {prog} -f -a '(__{prog}_complete)'
+`
@@ -0,0 +1,145 @@
+package completion
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/appentry"
+	"github.com/penguin-statistics/soracli/internal/models/cache"
+	pkgcache "github.com/penguin-statistics/soracli/internal/pkg/cache"
+	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
+)
+
+func init() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+}
+
+const testSourceUrl = "https://example.com/zh_CN/stage_table.json"
+
+// newTestContext returns a context of the flags the completers read, with the data directory under a
+// temporary directory, and an admin api at --baseUrl failing the test once it is requested.
+func newTestContext(t *testing.T, args ...string) *cli.Context {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("completion requested %s of the admin api", r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(api.Close)
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.String("data-dir", t.TempDir(), "")
+	set.String("baseUrl", api.URL, "")
+	set.String("sourceUrl", testSourceUrl, "")
+	set.Bool("no-local-cache", false, "")
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	c := cli.NewContext(cli.NewApp(), set, nil)
+	filepath.SetDataDir(c.String("data-dir"))
+	t.Cleanup(func() {
+		filepath.SetDataDir("")
+		pkgcache.UsePersistentStore(nil)
+		for _, flush := range cache.CacheSingularFlusherMap {
+			flush()
+		}
+		for _, flush := range cache.CacheSetMap {
+			flush()
+		}
+	})
+	return c
+}
+
+func TestStageTableZoneIDs(t *testing.T) {
+	c := newTestContext(t)
+	if ids := stageTableZoneIDs(c); ids != nil {
+		t.Errorf("zone IDs %v before any render, want none", ids)
+	}
+
+	// as cached by a render of the stage table in an earlier run
+	if err := appentry.UseLocalCache(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := pkgcache.PersistentStore().Store("stageTableZoneIds#sourceUrl:"+testSourceUrl, []string{"act1side_zone1", "act2side_zone1"}, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	pkgcache.UsePersistentStore(nil)
+
+	want := []string{"act1side_zone1", "act2side_zone1"}
+	if ids := stageTableZoneIDs(c); !reflect.DeepEqual(ids, want) {
+		t.Errorf("zone IDs %v, want %v", ids, want)
+	}
+	if err := c.Set("sourceUrl", "https://example.com/en_US/stage_table.json"); err != nil {
+		t.Fatal(err)
+	}
+	if ids := stageTableZoneIDs(c); ids != nil {
+		t.Errorf("zone IDs %v of a stage table not rendered, want none", ids)
+	}
+}
+
+func TestStageTableZoneIDsWithoutLocalCache(t *testing.T) {
+	c := newTestContext(t, "--no-local-cache")
+	cache.Initialize()
+	if ids := stageTableZoneIDs(c); ids != nil {
+		t.Errorf("zone IDs %v without the local cache, want none", ids)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	c := newTestContext(t)
+	dir := path.Join(c.String("data-dir"), "applied")
+	if err := os.MkdirAll(path.Join(dir, "nested.json"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"20220501-a1b2.json", "20220502-c3d4.json", "notes.txt"} {
+		if err := os.WriteFile(path.Join(dir, name), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"20220501-a1b2", "20220502-c3d4"}
+	if got := sessions(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("sessions %v, want %v", got, want)
+	}
+}
+
+func TestCacheNames(t *testing.T) {
+	names := cacheNames(newTestContext(t))
+	found := false
+	for i, name := range names {
+		if i > 0 && names[i-1] > name {
+			t.Errorf("cache names %v are not sorted", names)
+		}
+		found = found || name == "stageTableZoneIds#sourceUrl"
+	}
+	if !found {
+		t.Errorf("cache names %v, want them to include stageTableZoneIds#sourceUrl", names)
+	}
+}
+
+func TestFlagName(t *testing.T) {
+	command := &cli.Command{Name: "render", Flags: []cli.Flag{&cli.StringFlag{Name: "server", Aliases: []string{"s"}}}}
+	app := cli.NewApp()
+	app.Flags = []cli.Flag{&cli.StringFlag{Name: "log-format", Aliases: []string{"L"}}}
+	c := cli.NewContext(app, flag.NewFlagSet("test", flag.ContinueOnError), nil)
+
+	tests := map[string]string{
+		"-s":           "server",
+		"--server":     "server",
+		"-L":           "log-format",
+		"--unknown":    "unknown",
+		"--log-format": "log-format",
+	}
+	for arg, want := range tests {
+		if got := flagName(c, command, arg); got != want {
+			t.Errorf("flagName(%s) = %s, want %s", arg, got, want)
+		}
+	}
+}
@@ -1,3 +1,7 @@
 package consts
 
 const CacheSep = "|"
+
+// LocalCacheVersion versions the entries of the on-disk cache. Bump it whenever the shape
+// of a cached value changes, so that entries written by older builds are discarded.
+const LocalCacheVersion = "1"
@@ -1,3 +1,13 @@
 package consts
 
+// AppName names the directories of soracli under the XDG base directories.
+const AppName = "soracli"
+
+// DataDir is the legacy directory under the home directory soracli keeps every file in, if it exists.
 var DataDir = ".soracli"
+
+// AuditLogFile is the file under the data directory every mutating request to the admin api is recorded in.
+const AuditLogFile = "audit.jsonl"
+
+// RequestIDHeader carries the ID soracli generates for each request to the admin api.
+const RequestIDHeader = "X-Request-ID"
@@ -0,0 +1,24 @@
+package consts
+
+// GameDataRegionPlaceholder is replaced with the region of a server in game data source URLs.
+const GameDataRegionPlaceholder = "{region}"
+
+// StageTableURLTemplate is the source URL of stage_table.json, with GameDataRegionPlaceholder in place of
+// the game data region.
+const StageTableURLTemplate = "https://raw.githubusercontent.com/Kengxxiao/ArknightsGameData/master/" + GameDataRegionPlaceholder + "/gamedata/excel/stage_table.json"
+
+// ServerGameDataRegions maps a server to the region directory of its game data.
+var ServerGameDataRegions = map[string]string{
+	"CN": "zh_CN",
+	"US": "en_US",
+	"JP": "ja_JP",
+	"KR": "ko_KR",
+}
+
+// ServerLanguages maps a server to the language its game data is written in.
+var ServerLanguages = map[string]string{
+	"CN": "zh",
+	"US": "en",
+	"JP": "ja",
+	"KR": "ko",
+}
@@ -9,4 +9,9 @@ const (
 	ItemTypeActivity   = "ACTIVITY_ITEM"
 	ItemTypeTemp       = "TEMP"
 	ItemTypeLggShd     = "LGG_SHD"
+	ItemTypeGold       = "GOLD"
+	ItemTypeDiamond    = "DIAMOND"
+	ItemTypeDiamondShd = "DIAMOND_SHD"
+	ItemTypeChar       = "CHAR"
+	ItemTypeExpPlayer  = "EXP_PLAYER"
 )
@@ -1,6 +1,10 @@
 package consts
 
-import "time"
+import (
+	"time"
+	// embeds the IANA time zone database, so that LocMap does not depend on the system one
+	_ "time/tzdata"
+)
 
 const (
 	SiteDefaultHost = "penguin-stats.io"
@@ -31,9 +35,25 @@ var ServerNameMapping = map[string]string{
 	"KR": "韩服",
 }
 
+// LocZoneMap maps a server to the IANA time zone its game days follow, including daylight saving time.
+var LocZoneMap = map[string]string{
+	"CN": "Asia/Shanghai",
+	"US": "America/Los_Angeles",
+	"JP": "Asia/Tokyo",
+	"KR": "Asia/Seoul",
+}
+
 var LocMap = map[string]*time.Location{
-	"CN": time.FixedZone("UTC+8", +8*60*60),
-	"US": time.FixedZone("UTC-7", -7*60*60),
-	"JP": time.FixedZone("UTC+9", +9*60*60),
-	"KR": time.FixedZone("UTC+9", +9*60*60),
+	"CN": mustLoadLocation(LocZoneMap["CN"]),
+	"US": mustLoadLocation(LocZoneMap["US"]),
+	"JP": mustLoadLocation(LocZoneMap["JP"]),
+	"KR": mustLoadLocation(LocZoneMap["KR"]),
+}
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
 }
@@ -6,3 +6,9 @@ const (
 	StageTableDropTypeSpecial    = "SPECIAL"
 	StageTableDropTypeAdditional = "ADDITIONAL"
 )
+
+// These are drop types used in stage_table.json that are not rendered as drop infos
+const (
+	StageTableDropTypeOnce     = "ONCE"
+	StageTableDropTypeComplete = "COMPLETE"
+)
@@ -20,3 +20,19 @@ const (
 	ZoneTypeInterlude     = "INTERLUDE"
 	ZoneTypeSidestory     = "SIDESTORY"
 )
+
+var ZoneCategories = []string{
+	ZoneCategoryActivity,
+	ZoneCategoryMainline,
+	ZoneCategoryWeekly,
+	ZoneCategoryGachabox,
+	ZoneCategoryActivityPermanent,
+}
+
+var ZoneTypes = []string{
+	ZoneTypeAwakeningHour,
+	ZoneTypeVisionShatter,
+	ZoneTypeDyingSun,
+	ZoneTypeInterlude,
+	ZoneTypeSidestory,
+}
@@ -0,0 +1,561 @@
+package mockserver
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/cache"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+)
+
+// Server is a local stand-in for the admin API of Penguin Statistics. It implements
+// the endpoints soracli talks to, backed by a Store.
+type Server struct {
+	store *Store
+	token string
+	mux   *http.ServeMux
+}
+
+func New(store *Store, token string) *Server {
+	s := &Server{
+		store: store,
+		token: token,
+		mux:   http.NewServeMux(),
+	}
+
+	cache.Initialize()
+
+	s.mux.HandleFunc("/cli/gamedata/seed", s.method(http.MethodGet, s.handleSeed))
+	s.mux.HandleFunc("/save", s.method(http.MethodPost, s.handleSave))
+	s.mux.HandleFunc("/purge", s.method(http.MethodPost, s.handlePurge))
+	s.mux.HandleFunc("/items", s.method(http.MethodPost, s.handleCreateItem))
+	s.mux.HandleFunc("/items/", s.method(http.MethodPost, s.handleUpdateItem))
+	s.mux.HandleFunc("/notices", s.method(http.MethodPost, s.handleCreateNotice))
+	s.mux.HandleFunc("/notices/", s.method(http.MethodPost, s.handleUpdateNotice))
+	s.mux.HandleFunc("/timeranges", s.method(http.MethodPost, s.handleCreateTimeRange))
+	s.mux.HandleFunc("/timeranges/delete", s.method(http.MethodPost, s.handleDelete(s.store.DeleteTimeRanges)))
+	s.mux.HandleFunc("/timeranges/", s.method(http.MethodPost, s.handleUpdateTimeRange))
+	s.mux.HandleFunc("/zones", s.method(http.MethodPost, s.handleCreateZone))
+	s.mux.HandleFunc("/zones/", s.method(http.MethodPost, s.handleUpdateZone))
+	s.mux.HandleFunc("/zones/delete", s.method(http.MethodPost, s.handleDelete(s.store.DeleteZones)))
+	s.mux.HandleFunc("/stages", s.method(http.MethodPost, s.handleCreateStage))
+	s.mux.HandleFunc("/stages/", s.method(http.MethodPost, s.handleUpdateStage))
+	s.mux.HandleFunc("/stages/delete", s.method(http.MethodPost, s.handleDelete(s.store.DeleteStages)))
+	s.mux.HandleFunc("/dropinfos", s.method(http.MethodPost, s.handleCreateDropInfo))
+	s.mux.HandleFunc("/dropinfos/", s.method(http.MethodPost, s.handleUpdateDropInfo))
+	s.mux.HandleFunc("/dropinfos/delete", s.method(http.MethodPost, s.handleDelete(s.store.DeleteDropInfos)))
+	s.mux.HandleFunc("/activities/delete", s.method(http.MethodPost, s.handleDelete(s.store.DeleteActivities)))
+
+	s.mux.HandleFunc("/cli/items", s.method(http.MethodGet, s.listing(func(d *types.Snapshot) any { return d.Items })))
+	s.mux.HandleFunc("/cli/zones", s.method(http.MethodGet, s.listing(func(d *types.Snapshot) any { return d.Zones })))
+	s.mux.HandleFunc("/cli/stages", s.method(http.MethodGet, s.listing(func(d *types.Snapshot) any { return d.Stages })))
+	s.mux.HandleFunc("/cli/dropinfos", s.method(http.MethodGet, s.listing(func(d *types.Snapshot) any { return d.DropInfos })))
+	s.mux.HandleFunc("/cli/timeranges", s.method(http.MethodGet, s.listing(func(d *types.Snapshot) any { return d.TimeRanges })))
+	s.mux.HandleFunc("/cli/activities", s.method(http.MethodGet, s.listing(func(d *types.Snapshot) any { return d.Activities })))
+	s.mux.HandleFunc("/cli/notices", s.method(http.MethodGet, s.listing(func(d *types.Snapshot) any { return d.Notices })))
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+	if s.authorized(r) {
+		s.mux.ServeHTTP(rec, r)
+	} else {
+		writeError(rec, http.StatusUnauthorized, errors.New("invalid or missing bearer token"))
+	}
+
+	log.Info().
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Int("status", rec.status).
+		Dur("took", time.Since(start)).
+		Msg("mock server handled request")
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func (s *Server) method(method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			writeError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) listing(get func(d *types.Snapshot) any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.store.Read(func(d *types.Snapshot) {
+			writeJSON(w, http.StatusOK, get(d))
+		})
+	}
+}
+
+func (s *Server) handleSeed(w http.ResponseWriter, r *http.Request) {
+	s.store.Read(func(d *types.Snapshot) {
+		writeJSON(w, http.StatusOK, types.CliGameDataSeedResponse{Items: d.Items})
+	})
+}
+
+func (s *Server) handleSave(w http.ResponseWriter, r *http.Request) {
+	var rendered gamedata.RenderedObjects
+	if err := json.NewDecoder(r.Body).Decode(&rendered); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if rendered.Zone == nil || rendered.TimeRange == nil {
+		writeError(w, http.StatusBadRequest, errors.New("zone and timeRange are required"))
+		return
+	}
+
+	if err := s.store.Save(&rendered); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rendered)
+}
+
+func (s *Server) handlePurge(w http.ResponseWriter, r *http.Request) {
+	var req []types.PurgeCacheRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	for _, pair := range req {
+		_, isSet := cache.CacheSetMap[pair.Name]
+		_, isSingular := cache.CacheSingularFlusherMap[pair.Name]
+		if !isSet && !isSingular {
+			writeError(w, http.StatusBadRequest, errors.Errorf("unknown cache name: %s", pair.Name))
+			return
+		}
+		if err := cache.Delete(pair.Name, pair.Key); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleCreateItem(w http.ResponseWriter, r *http.Request) {
+	var item models.Item
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err := s.store.Write(func(d *types.Snapshot) error {
+		for _, existing := range d.Items {
+			if existing.ArkItemID == item.ArkItemID {
+				return errors.Wrapf(errConflict, "item %s already exists", item.ArkItemID)
+			}
+		}
+		item.ItemID = nextItemID(d)
+		d.Items = append(d.Items, &item)
+		return nil
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (s *Server) handleUpdateItem(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "/items/")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var item models.Item
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	item.ItemID = id
+
+	err = s.store.Write(func(d *types.Snapshot) error {
+		for _, existing := range d.Items {
+			if existing.ItemID == id {
+				*existing = item
+				return nil
+			}
+		}
+		return errNotFound
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (s *Server) handleCreateNotice(w http.ResponseWriter, r *http.Request) {
+	var notice models.Notice
+	if err := json.NewDecoder(r.Body).Decode(&notice); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err := s.store.Write(func(d *types.Snapshot) error {
+		notice.NoticeID = nextNoticeID(d)
+		d.Notices = append(d.Notices, &notice)
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, notice)
+}
+
+func (s *Server) handleUpdateNotice(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "/notices/")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var notice models.Notice
+	if err := json.NewDecoder(r.Body).Decode(&notice); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	notice.NoticeID = id
+
+	err = s.store.Write(func(d *types.Snapshot) error {
+		for _, existing := range d.Notices {
+			if existing.NoticeID == id {
+				*existing = notice
+				return nil
+			}
+		}
+		return errNotFound
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, notice)
+}
+
+func (s *Server) handleCreateTimeRange(w http.ResponseWriter, r *http.Request) {
+	var timeRange models.TimeRange
+	if err := json.NewDecoder(r.Body).Decode(&timeRange); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if timeRange.StartTime == nil || timeRange.EndTime == nil || !timeRange.StartTime.Before(*timeRange.EndTime) {
+		writeError(w, http.StatusBadRequest, errors.New("startTime must be before endTime"))
+		return
+	}
+
+	err := s.store.Write(func(d *types.Snapshot) error {
+		timeRange.RangeID = nextTimeRangeID(d)
+		d.TimeRanges = append(d.TimeRanges, &timeRange)
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, timeRange)
+}
+
+func (s *Server) handleUpdateTimeRange(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "/timeranges/")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var timeRange models.TimeRange
+	if err := json.NewDecoder(r.Body).Decode(&timeRange); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if timeRange.StartTime == nil || timeRange.EndTime == nil || !timeRange.StartTime.Before(*timeRange.EndTime) {
+		writeError(w, http.StatusBadRequest, errors.New("startTime must be before endTime"))
+		return
+	}
+	timeRange.RangeID = id
+
+	err = s.store.Write(func(d *types.Snapshot) error {
+		existing := findTimeRange(d, id)
+		if existing == nil {
+			return errNotFound
+		}
+		*existing = timeRange
+		return nil
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, timeRange)
+}
+
+func (s *Server) handleCreateZone(w http.ResponseWriter, r *http.Request) {
+	var zone models.Zone
+	if err := json.NewDecoder(r.Body).Decode(&zone); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err := s.store.Write(func(d *types.Snapshot) error {
+		for _, existing := range d.Zones {
+			if existing.ArkZoneID == zone.ArkZoneID {
+				return errors.Wrapf(errConflict, "zone %s already exists", zone.ArkZoneID)
+			}
+		}
+		zone.ZoneID = nextZoneID(d)
+		d.Zones = append(d.Zones, &zone)
+		return nil
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, zone)
+}
+
+func (s *Server) handleUpdateZone(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "/zones/")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var zone models.Zone
+	if err := json.NewDecoder(r.Body).Decode(&zone); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	zone.ZoneID = id
+
+	err = s.store.Write(func(d *types.Snapshot) error {
+		for _, existing := range d.Zones {
+			if existing.ZoneID == id {
+				*existing = zone
+				return nil
+			}
+		}
+		return errNotFound
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, zone)
+}
+
+func (s *Server) handleCreateStage(w http.ResponseWriter, r *http.Request) {
+	var stage models.Stage
+	if err := json.NewDecoder(r.Body).Decode(&stage); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err := s.store.Write(func(d *types.Snapshot) error {
+		for _, existing := range d.Stages {
+			if existing.ArkStageID == stage.ArkStageID {
+				return errors.Wrapf(errConflict, "stage %s already exists", stage.ArkStageID)
+			}
+		}
+		stage.StageID = nextStageID(d)
+		d.Stages = append(d.Stages, &stage)
+		return nil
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stage)
+}
+
+func (s *Server) handleUpdateStage(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "/stages/")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var stage models.Stage
+	if err := json.NewDecoder(r.Body).Decode(&stage); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	stage.StageID = id
+
+	err = s.store.Write(func(d *types.Snapshot) error {
+		for _, existing := range d.Stages {
+			if existing.StageID == id {
+				*existing = stage
+				return nil
+			}
+		}
+		return errNotFound
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stage)
+}
+
+func (s *Server) handleCreateDropInfo(w http.ResponseWriter, r *http.Request) {
+	var dropInfo models.DropInfo
+	if err := json.NewDecoder(r.Body).Decode(&dropInfo); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err := s.store.Write(func(d *types.Snapshot) error {
+		if err := checkDropInfoRefs(d, &dropInfo); err != nil {
+			return err
+		}
+		dropInfo.DropID = nextDropInfoID(d)
+		d.DropInfos = append(d.DropInfos, &dropInfo)
+		return nil
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dropInfo)
+}
+
+func (s *Server) handleUpdateDropInfo(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "/dropinfos/")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var dropInfo models.DropInfo
+	if err := json.NewDecoder(r.Body).Decode(&dropInfo); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	dropInfo.DropID = id
+
+	err = s.store.Write(func(d *types.Snapshot) error {
+		if err := checkDropInfoRefs(d, &dropInfo); err != nil {
+			return err
+		}
+		for _, existing := range d.DropInfos {
+			if existing.DropID == id {
+				*existing = dropInfo
+				return nil
+			}
+		}
+		return errNotFound
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dropInfo)
+}
+
+// checkDropInfoRefs checks that the stage and time range of a drop info exist, and that the time range
+// is of the server of the drop info.
+func checkDropInfoRefs(d *types.Snapshot, dropInfo *models.DropInfo) error {
+	timeRange := findTimeRange(d, dropInfo.RangeID)
+	if timeRange == nil {
+		return errors.Wrapf(errInvalid, "time range %d not found", dropInfo.RangeID)
+	}
+	if timeRange.Server != dropInfo.Server {
+		return errors.Wrapf(errInvalid, "time range %d is of server %s, not %s", dropInfo.RangeID, timeRange.Server, dropInfo.Server)
+	}
+	for _, stage := range d.Stages {
+		if stage.StageID == dropInfo.StageID {
+			return nil
+		}
+	}
+	return errors.Wrapf(errInvalid, "stage %d not found", dropInfo.StageID)
+}
+
+func (s *Server) handleDelete(deleteFunc func(ids []int) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.DeleteEntitiesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := deleteFunc(req.IDs); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, req)
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg("failed to write response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"message": err.Error()})
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errNotFound) {
+		writeError(w, http.StatusNotFound, err)
+	} else if errors.Is(err, errConflict) || errors.Is(err, errInUse) {
+		writeError(w, http.StatusConflict, err)
+	} else if errors.Is(err, errInvalid) {
+		writeError(w, http.StatusBadRequest, err)
+	} else {
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func pathID(r *http.Request, prefix string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, prefix))
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid id in path")
+	}
+	return id, nil
+}
@@ -0,0 +1,304 @@
+package mockserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/cache"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+	"github.com/penguin-statistics/soracli/internal/pkg/client"
+	"github.com/penguin-statistics/soracli/internal/services"
+)
+
+func init() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+}
+
+// stageTable is a stage table with one side story stage dropping an orirock cube.
+const stageTable = `{"stages": {"act1side_01": {
+	"stageId": "act1side_01", "stageType": "ACTIVITY", "apCost": 9, "code": "SS-1", "zoneId": "act1side_zone1",
+	"stageDropInfo": {"displayDetailRewards": [{"id": "30012", "dropType": "NORMAL", "type": "MATERIAL"}]}
+}}}`
+
+// newTestClient starts a mock server holding an orirock cube and the furniture, returning a client of it
+// allowed to use the unverified endpoints.
+func newTestClient(t *testing.T) (*Store, *client.Penguin) {
+	t.Helper()
+	store, err := NewStore("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Write(func(d *types.Snapshot) error {
+		d.Items = []*models.Item{
+			{ItemID: 1, ArkItemID: "30012", Type: consts.ItemTypeMaterial, Rarity: 2},
+			{ItemID: 2, ArkItemID: consts.FurnitureArkItemID, Type: consts.ItemTypeFurniture},
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock := httptest.NewServer(New(store, "token"))
+	t.Cleanup(mock.Close)
+	t.Cleanup(func() {
+		for _, flush := range cache.CacheSingularFlusherMap {
+			flush()
+		}
+		for _, flush := range cache.CacheSetMap {
+			flush()
+		}
+	})
+
+	pg := client.NewHTTP(mock.URL, "token")
+	pg.AllowUnverifiedEndpoints()
+	return store, pg
+}
+
+func newTestServices(t *testing.T) (*Store, *services.GameDataService, string) {
+	t.Helper()
+	store, pg := newTestClient(t)
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(stageTable))
+	}))
+	t.Cleanup(source.Close)
+
+	itemService := services.NewItemService(pg)
+	stageService := services.NewStageService(pg)
+	gameData := services.NewGameDataService(itemService, stageService, services.NewTimeRangeService(itemService, stageService, pg), services.NewZoneService(pg), services.NewActivityService(pg), pg)
+	return store, gameData, source.URL + "/stage_table.json"
+}
+
+// renderEvent renders the side story of stageTable, running for two weeks from start on CN.
+func renderEvent(t *testing.T, s *services.GameDataService, sourceUrl string, start time.Time) *gamedata.RenderedObjects {
+	t.Helper()
+	end := start.Add(14 * 24 * time.Hour)
+	rendered, err := s.RenderNewEvent(context.Background(), sourceUrl, &gamedata.NewEventBasicInfo{
+		ArkZoneId:    "act1side_zone1",
+		ZoneName:     "Side Story",
+		ZoneCategory: consts.ZoneCategoryActivity,
+		Server:       "CN",
+		StartTime:    &start,
+		EndTime:      &end,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rendered
+}
+
+// TestRenderAndSave drives the render, edit and save flow of an event and of its rerun against the mock server.
+func TestRenderAndSave(t *testing.T) {
+	ctx := context.Background()
+	store, s, sourceUrl := newTestServices(t)
+
+	render := func(start time.Time) *gamedata.RenderedObjects {
+		return renderEvent(t, s, sourceUrl, start)
+	}
+
+	// the edited file is read back as JSON
+	edit := func(rendered *gamedata.RenderedObjects, comment string) *gamedata.RenderedObjects {
+		b, err := json.Marshal(rendered)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var edited gamedata.RenderedObjects
+		if err := json.Unmarshal(b, &edited); err != nil {
+			t.Fatal(err)
+		}
+		edited.TimeRange.Comment.SetValid(comment)
+		return &edited
+	}
+
+	first := time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+	applied, err := s.UpdateNewEvent(ctx, edit(render(first), "first run"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied == nil {
+		t.Fatal("the saved bundle cannot be undone")
+	}
+	if len(applied.Created.ZoneIDs) != 1 || len(applied.Created.StageIDs) != 1 || len(applied.Created.RangeIDs) != 1 || len(applied.Created.ActivityIDs) != 1 {
+		t.Errorf("created %+v, want a zone, a stage, a time range and an activity", applied.Created)
+	}
+
+	rerun := first.AddDate(0, 3, 0)
+	reapplied, err := s.UpdateNewEvent(ctx, edit(render(rerun), "rerun"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reapplied == nil {
+		t.Fatal("the saved rerun cannot be undone")
+	}
+	if len(reapplied.Created.ZoneIDs) != 0 || len(reapplied.Updated.ZoneIDs) != 1 || len(reapplied.Updated.StageIDs) != 1 {
+		t.Errorf("rerun created %+v and updated %+v, want the zone and stage updated", reapplied.Created, reapplied.Updated)
+	}
+
+	store.Read(func(d *types.Snapshot) {
+		if len(d.Zones) != 1 || len(d.Stages) != 1 || len(d.TimeRanges) != 2 || len(d.Activities) != 2 {
+			t.Fatalf("the mock holds %d zones, %d stages, %d time ranges and %d activities; want 1, 1, 2 and 2",
+				len(d.Zones), len(d.Stages), len(d.TimeRanges), len(d.Activities))
+		}
+		comments := make(map[int]string)
+		for _, timeRange := range d.TimeRanges {
+			comments[timeRange.RangeID] = timeRange.Comment.String
+		}
+		if comments[applied.Created.RangeIDs[0]] != "first run" || comments[reapplied.Created.RangeIDs[0]] != "rerun" {
+			t.Errorf("time range comments %v, want the edited ones", comments)
+		}
+		// the orirock cube, the three drop types and the furniture of each run
+		for _, rangeIDs := range [][]int{applied.Created.RangeIDs, reapplied.Created.RangeIDs} {
+			dropInfos := 0
+			for _, dropInfo := range d.DropInfos {
+				if dropInfo.RangeID == rangeIDs[0] {
+					dropInfos++
+				}
+			}
+			if dropInfos != 5 {
+				t.Errorf("time range %d has %d drop infos, want 5", rangeIDs[0], dropInfos)
+			}
+		}
+	})
+}
+
+// TestUndoRerun undoes a rerun saved over an event, and then the event.
+func TestUndoRerun(t *testing.T) {
+	ctx := context.Background()
+	store, s, sourceUrl := newTestServices(t)
+
+	first := time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+	applied, err := s.UpdateNewEvent(ctx, renderEvent(t, s, sourceUrl, first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var zoneExistence, stageExistence string
+	store.Read(func(d *types.Snapshot) {
+		zoneExistence, stageExistence = string(d.Zones[0].Existence), string(d.Stages[0].Existence)
+	})
+	reapplied, err := s.UpdateNewEvent(ctx, renderEvent(t, s, sourceUrl, first.AddDate(0, 3, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Undo(ctx, reapplied, services.PlanUndo(reapplied)); err != nil {
+		t.Fatal(err)
+	}
+	store.Read(func(d *types.Snapshot) {
+		if len(d.Zones) != 1 || len(d.Stages) != 1 {
+			t.Fatalf("the mock holds %d zones and %d stages after undoing the rerun, want the ones of the first run", len(d.Zones), len(d.Stages))
+		}
+		if got := string(d.Zones[0].Existence); got != zoneExistence {
+			t.Errorf("zone existence %s after undoing the rerun, want %s", got, zoneExistence)
+		}
+		if got := string(d.Stages[0].Existence); got != stageExistence {
+			t.Errorf("stage existence %s after undoing the rerun, want %s", got, stageExistence)
+		}
+		if len(d.TimeRanges) != 1 || d.TimeRanges[0].RangeID != applied.Created.RangeIDs[0] {
+			t.Errorf("time ranges %v after undoing the rerun, want the one of the first run", d.TimeRanges)
+		}
+		if len(d.DropInfos) != len(applied.Created.DropIDs) || len(d.Activities) != 1 {
+			t.Errorf("%d drop infos and %d activities after undoing the rerun, want %d and 1", len(d.DropInfos), len(d.Activities), len(applied.Created.DropIDs))
+		}
+	})
+
+	if err := s.Undo(ctx, applied, services.PlanUndo(applied)); err != nil {
+		t.Fatal(err)
+	}
+	store.Read(func(d *types.Snapshot) {
+		if len(d.Zones)+len(d.Stages)+len(d.TimeRanges)+len(d.DropInfos)+len(d.Activities) != 0 {
+			t.Errorf("the mock holds %+v after undoing both, want nothing", d)
+		}
+	})
+}
+
+// TestApplyPlan applies the plan of a rendered bundle, and then a plan deleting what it created.
+func TestApplyPlan(t *testing.T) {
+	ctx := context.Background()
+	store, s, sourceUrl := newTestServices(t)
+
+	rendered := renderEvent(t, s, sourceUrl, time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC))
+	live, err := s.LiveState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := services.PlanReconciliation([]*gamedata.DesiredBundle{{File: "act1side.json", RenderedObjects: rendered}}, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ApplyPlan(ctx, &gamedata.Plan{Changes: changes}); err != nil {
+		t.Fatal(err)
+	}
+
+	var deletes []*gamedata.PlanChange
+	store.Read(func(d *types.Snapshot) {
+		if len(d.Zones) != 1 || len(d.Stages) != 1 || len(d.TimeRanges) != 1 || len(d.DropInfos) != 5 {
+			t.Fatalf("the mock holds %d zones, %d stages, %d time ranges and %d drop infos; want 1, 1, 1 and 5",
+				len(d.Zones), len(d.Stages), len(d.TimeRanges), len(d.DropInfos))
+		}
+		for _, dropInfo := range d.DropInfos {
+			deletes = append(deletes, &gamedata.PlanChange{Action: gamedata.PlanActionDelete, Kind: gamedata.PlanKindDropInfo, ID: dropInfo.DropID})
+		}
+		deletes = append(deletes,
+			&gamedata.PlanChange{Action: gamedata.PlanActionDelete, Kind: gamedata.PlanKindStage, ID: d.Stages[0].StageID},
+			&gamedata.PlanChange{Action: gamedata.PlanActionDelete, Kind: gamedata.PlanKindTimeRange, ID: d.TimeRanges[0].RangeID},
+			&gamedata.PlanChange{Action: gamedata.PlanActionDelete, Kind: gamedata.PlanKindZone, ID: d.Zones[0].ZoneID},
+		)
+	})
+
+	live, err = s.LiveState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes, err := services.PlanReconciliation([]*gamedata.DesiredBundle{{File: "act1side.json", RenderedObjects: rendered}}, live); err != nil {
+		t.Fatal(err)
+	} else if len(changes) != 0 {
+		t.Errorf("planned %d changes after applying the plan, want none", len(changes))
+	}
+
+	if err := s.ApplyPlan(ctx, &gamedata.Plan{Changes: deletes}); err != nil {
+		t.Fatal(err)
+	}
+	store.Read(func(d *types.Snapshot) {
+		if len(d.Zones)+len(d.Stages)+len(d.TimeRanges)+len(d.DropInfos) != 0 {
+			t.Errorf("the mock holds %+v after deleting everything, want nothing", d)
+		}
+	})
+}
+
+// TestCheckRenderedTimeRanges checks the time ranges of bundles saved together against each other.
+func TestCheckRenderedTimeRanges(t *testing.T) {
+	ctx := context.Background()
+	_, s, sourceUrl := newTestServices(t)
+
+	first := time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+	bundles := []*gamedata.RenderedObjects{
+		renderEvent(t, s, sourceUrl, first),
+		// starts a week before the first ends
+		renderEvent(t, s, sourceUrl, first.AddDate(0, 0, 7)),
+	}
+	findings, err := s.CheckRenderedTimeRanges(ctx, bundles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, bundleFindings := range findings {
+		if len(bundleFindings) != 1 || !strings.Contains(bundleFindings[0].Message, "overlaps") {
+			t.Errorf("bundle %d has findings %v, want the overlap of the two", i, bundleFindings)
+		}
+	}
+
+	if findings, err := s.CheckRenderedTimeRanges(ctx, bundles[:1]); err != nil {
+		t.Fatal(err)
+	} else if len(findings[0]) != 0 {
+		t.Errorf("a single bundle has findings %v, want none", findings[0])
+	}
+}
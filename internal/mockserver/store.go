@@ -0,0 +1,316 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+	"github.com/penguin-statistics/soracli/internal/pkg/export"
+)
+
+var (
+	errNotFound = errors.New("entity not found")
+	errConflict = errors.New("entity already exists")
+	errInvalid  = errors.New("invalid request")
+	errInUse    = errors.New("entity in use")
+)
+
+// Store keeps the state of the mock server in memory, and optionally mirrors it
+// to a JSON file after every mutation so that the state survives restarts.
+type Store struct {
+	m sync.RWMutex
+
+	path string
+	data *types.Snapshot
+}
+
+// NewStore creates a store. If statePath points to an existing file, the state is
+// loaded from it; otherwise the store is seeded from snapshotPath, if given.
+// Either path may be empty.
+func NewStore(statePath, snapshotPath string) (*Store, error) {
+	s := &Store{
+		path: statePath,
+		data: &types.Snapshot{},
+	}
+
+	seed := snapshotPath
+	if statePath != "" {
+		if _, err := os.Stat(statePath); err == nil {
+			seed = statePath
+		}
+	}
+
+	if seed != "" {
+		log.Info().Str("file", seed).Msg("loading mock server state")
+		data, err := export.ReadSnapshot(seed)
+		if err != nil {
+			return nil, err
+		}
+		s.data = data
+	}
+
+	return s, nil
+}
+
+// Read calls fn with the current state under a read lock. fn must not retain
+// or modify the snapshot.
+func (s *Store) Read(fn func(data *types.Snapshot)) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	fn(s.data)
+}
+
+// Write calls fn with the current state under a write lock, and persists the
+// state afterwards if fn succeeded.
+func (s *Store) Write(fn func(data *types.Snapshot) error) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if err := fn(s.data); err != nil {
+		return err
+	}
+	return s.persist()
+}
+
+func (s *Store) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0o644)
+}
+
+// Save stores the rendered objects and assigns IDs to them the same way the
+// admin API does: zones and stages are matched by their ark IDs and updated in
+// place, while the time range, drop infos and activity are always created.
+// The rendered objects are updated in place with the assigned IDs.
+func (s *Store) Save(rendered *gamedata.RenderedObjects) error {
+	return s.Write(func(data *types.Snapshot) error {
+		if rendered.Zone != nil {
+			rendered.Zone.ZoneID = 0
+			for _, zone := range data.Zones {
+				if zone.ArkZoneID == rendered.Zone.ArkZoneID {
+					rendered.Zone.ZoneID = zone.ZoneID
+					*zone = *rendered.Zone
+				}
+			}
+			if rendered.Zone.ZoneID == 0 {
+				rendered.Zone.ZoneID = nextZoneID(data)
+				data.Zones = append(data.Zones, rendered.Zone)
+			}
+		}
+
+		if rendered.TimeRange != nil {
+			rendered.TimeRange.RangeID = nextTimeRangeID(data)
+			data.TimeRanges = append(data.TimeRanges, rendered.TimeRange)
+		}
+
+		for _, stage := range rendered.Stages {
+			if rendered.Zone != nil {
+				stage.ZoneID = rendered.Zone.ZoneID
+			}
+			stage.StageID = 0
+			for _, existing := range data.Stages {
+				if existing.ArkStageID == stage.ArkStageID {
+					stage.StageID = existing.StageID
+					*existing = *stage
+				}
+			}
+			if stage.StageID == 0 {
+				stage.StageID = nextStageID(data)
+				data.Stages = append(data.Stages, stage)
+			}
+
+			for _, dropInfo := range rendered.DropInfosMap[stage.ArkStageID] {
+				dropInfo.DropID = nextDropInfoID(data)
+				dropInfo.StageID = stage.StageID
+				if rendered.TimeRange != nil {
+					dropInfo.RangeID = rendered.TimeRange.RangeID
+				}
+				data.DropInfos = append(data.DropInfos, dropInfo)
+			}
+		}
+
+		if rendered.Activity != nil {
+			rendered.Activity.ActivityID = nextActivityID(data)
+			data.Activities = append(data.Activities, rendered.Activity)
+		}
+
+		return nil
+	})
+}
+
+// DeleteDropInfos deletes the drop infos of ids.
+func (s *Store) DeleteDropInfos(ids []int) error {
+	return s.Write(func(data *types.Snapshot) error {
+		var err error
+		data.DropInfos, err = deleteByID(data.DropInfos, ids, "drop info", func(v *models.DropInfo) int { return v.DropID })
+		return err
+	})
+}
+
+// DeleteStages deletes the stages of ids, none of which may still have drop infos.
+func (s *Store) DeleteStages(ids []int) error {
+	return s.Write(func(data *types.Snapshot) error {
+		for _, dropInfo := range data.DropInfos {
+			if containsID(ids, dropInfo.StageID) {
+				return errors.Wrapf(errInUse, "stage %d still has drop info %d", dropInfo.StageID, dropInfo.DropID)
+			}
+		}
+		var err error
+		data.Stages, err = deleteByID(data.Stages, ids, "stage", func(v *models.Stage) int { return v.StageID })
+		return err
+	})
+}
+
+// DeleteTimeRanges deletes the time ranges of ids, none of which may still have drop infos.
+func (s *Store) DeleteTimeRanges(ids []int) error {
+	return s.Write(func(data *types.Snapshot) error {
+		for _, dropInfo := range data.DropInfos {
+			if containsID(ids, dropInfo.RangeID) {
+				return errors.Wrapf(errInUse, "time range %d still has drop info %d", dropInfo.RangeID, dropInfo.DropID)
+			}
+		}
+		var err error
+		data.TimeRanges, err = deleteByID(data.TimeRanges, ids, "time range", func(v *models.TimeRange) int { return v.RangeID })
+		return err
+	})
+}
+
+// DeleteZones deletes the zones of ids, none of which may still have stages.
+func (s *Store) DeleteZones(ids []int) error {
+	return s.Write(func(data *types.Snapshot) error {
+		for _, stage := range data.Stages {
+			if containsID(ids, stage.ZoneID) {
+				return errors.Wrapf(errInUse, "zone %d still has stage %d", stage.ZoneID, stage.StageID)
+			}
+		}
+		var err error
+		data.Zones, err = deleteByID(data.Zones, ids, "zone", func(v *models.Zone) int { return v.ZoneID })
+		return err
+	})
+}
+
+// DeleteActivities deletes the activities of ids.
+func (s *Store) DeleteActivities(ids []int) error {
+	return s.Write(func(data *types.Snapshot) error {
+		var err error
+		data.Activities, err = deleteByID(data.Activities, ids, "activity", func(v *models.Activity) int { return v.ActivityID })
+		return err
+	})
+}
+
+// deleteByID returns entities without the ones of ids, or errNotFound if any of ids is missing.
+func deleteByID[T any](entities []*T, ids []int, kind string, idOf func(*T) int) ([]*T, error) {
+	found := make(map[int]bool, len(ids))
+	kept := make([]*T, 0, len(entities))
+	for _, entity := range entities {
+		if containsID(ids, idOf(entity)) {
+			found[idOf(entity)] = true
+			continue
+		}
+		kept = append(kept, entity)
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return entities, errors.Wrapf(errNotFound, "%s %d", kind, id)
+		}
+	}
+	return kept, nil
+}
+
+func containsID(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func findTimeRange(data *types.Snapshot, id int) *models.TimeRange {
+	for _, timeRange := range data.TimeRanges {
+		if timeRange.RangeID == id {
+			return timeRange
+		}
+	}
+	return nil
+}
+
+func nextZoneID(data *types.Snapshot) int {
+	max := 0
+	for _, v := range data.Zones {
+		if v.ZoneID > max {
+			max = v.ZoneID
+		}
+	}
+	return max + 1
+}
+
+func nextStageID(data *types.Snapshot) int {
+	max := 0
+	for _, v := range data.Stages {
+		if v.StageID > max {
+			max = v.StageID
+		}
+	}
+	return max + 1
+}
+
+func nextDropInfoID(data *types.Snapshot) int {
+	max := 0
+	for _, v := range data.DropInfos {
+		if v.DropID > max {
+			max = v.DropID
+		}
+	}
+	return max + 1
+}
+
+func nextTimeRangeID(data *types.Snapshot) int {
+	max := 0
+	for _, v := range data.TimeRanges {
+		if v.RangeID > max {
+			max = v.RangeID
+		}
+	}
+	return max + 1
+}
+
+func nextActivityID(data *types.Snapshot) int {
+	max := 0
+	for _, v := range data.Activities {
+		if v.ActivityID > max {
+			max = v.ActivityID
+		}
+	}
+	return max + 1
+}
+
+func nextItemID(data *types.Snapshot) int {
+	max := 0
+	for _, v := range data.Items {
+		if v.ItemID > max {
+			max = v.ItemID
+		}
+	}
+	return max + 1
+}
+
+func nextNoticeID(data *types.Snapshot) int {
+	max := 0
+	for _, v := range data.Notices {
+		if v.NoticeID > max {
+			max = v.NoticeID
+		}
+	}
+	return max + 1
+}
@@ -0,0 +1,187 @@
+package mockserver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+	"github.com/penguin-statistics/soracli/internal/services"
+)
+
+var (
+	may1  = time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+	may8  = may1.AddDate(0, 0, 7)
+	may15 = may1.AddDate(0, 0, 14)
+	may22 = may1.AddDate(0, 0, 21)
+)
+
+// newTimeRangeService seeds the mock with stage 1, the time ranges and the drop infos, returning a
+// time range service of it.
+func newTimeRangeService(t *testing.T, timeRanges []*models.TimeRange, dropInfos []*models.DropInfo) (*Store, *services.TimeRangeService) {
+	t.Helper()
+	store, pg := newTestClient(t)
+	err := store.Write(func(d *types.Snapshot) error {
+		d.Stages = []*models.Stage{{StageID: 1, ArkStageID: "act1side_01"}}
+		d.TimeRanges = timeRanges
+		d.DropInfos = dropInfos
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemService := services.NewItemService(pg)
+	stageService := services.NewStageService(pg)
+	return store, services.NewTimeRangeService(itemService, stageService, pg)
+}
+
+func timeRangeOf(id int, server string, start, end time.Time) *models.TimeRange {
+	return &models.TimeRange{RangeID: id, Server: server, StartTime: &start, EndTime: &end}
+}
+
+func dropInfoOf(id, rangeID int, itemID int64, dropType string) *models.DropInfo {
+	return &models.DropInfo{DropID: id, Server: "CN", StageID: 1, ItemID: null.IntFrom(itemID), DropType: dropType, RangeID: rangeID}
+}
+
+func TestSplitTimeRange(t *testing.T) {
+	store, s := newTimeRangeService(t,
+		[]*models.TimeRange{timeRangeOf(1, "CN", may1, may15)},
+		[]*models.DropInfo{dropInfoOf(1, 1, 1, "NORMAL_DROP")},
+	)
+
+	resp, err := s.SplitTimeRange(context.Background(), &types.SplitTimeRangeRequest{
+		RangeID:   1,
+		SplitTime: may8.UnixMilli(),
+		Comment:   null.StringFrom("changed drops"),
+		DropInfos: []*models.DropInfo{dropInfoOf(1, 1, 1, "NORMAL_DROP"), dropInfoOf(0, 0, 2, "SPECIAL_DROP")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Original.EndTime.Equal(may8) || !resp.TimeRange.StartTime.Equal(may8) || !resp.TimeRange.EndTime.Equal(may15) {
+		t.Errorf("split into %s and %s, want them to meet at %s", resp.Original, resp.TimeRange, may8)
+	}
+	if len(resp.DropInfos) != 2 {
+		t.Fatalf("created %d drop infos, want 2", len(resp.DropInfos))
+	}
+
+	store.Read(func(d *types.Snapshot) {
+		if len(d.TimeRanges) != 2 || !d.TimeRanges[0].EndTime.Equal(may8) || d.TimeRanges[1].Comment.String != "changed drops" {
+			t.Errorf("the mock holds time ranges %v, want the original ended at the split and the new one commented", d.TimeRanges)
+		}
+		ranges := make(map[int]int)
+		for _, dropInfo := range d.DropInfos {
+			ranges[dropInfo.RangeID]++
+		}
+		if len(d.DropInfos) != 3 || ranges[1] != 1 || ranges[resp.TimeRange.RangeID] != 2 {
+			t.Errorf("the mock holds drop infos per range %v, want the original one kept and two on the new range", ranges)
+		}
+	})
+}
+
+func TestSplitTimeRangeOutside(t *testing.T) {
+	store, s := newTimeRangeService(t, []*models.TimeRange{timeRangeOf(1, "CN", may1, may15)}, nil)
+
+	for _, splitTime := range []time.Time{may1, may15, may22} {
+		if _, err := s.SplitTimeRange(context.Background(), &types.SplitTimeRangeRequest{RangeID: 1, SplitTime: splitTime.UnixMilli()}); err == nil {
+			t.Errorf("split at %s succeeded, want an error", splitTime)
+		}
+	}
+	store.Read(func(d *types.Snapshot) {
+		if len(d.TimeRanges) != 1 || !d.TimeRanges[0].EndTime.Equal(may15) {
+			t.Errorf("the mock holds time ranges %v, want the original untouched", d.TimeRanges)
+		}
+	})
+}
+
+func TestMergeTimeRanges(t *testing.T) {
+	store, s := newTimeRangeService(t,
+		[]*models.TimeRange{timeRangeOf(1, "CN", may1, may8), timeRangeOf(2, "CN", may8, may15), timeRangeOf(3, "CN", may15, may22)},
+		[]*models.DropInfo{
+			dropInfoOf(1, 1, 1, "NORMAL_DROP"),
+			// the same drop as drop info 1, which is deleted
+			dropInfoOf(2, 2, 1, "NORMAL_DROP"),
+			dropInfoOf(3, 2, 2, "NORMAL_DROP"),
+			// the same drop as drop info 3, once it is moved
+			dropInfoOf(4, 3, 2, "NORMAL_DROP"),
+			dropInfoOf(5, 3, 1, "SPECIAL_DROP"),
+		},
+	)
+
+	// the order of the IDs does not matter
+	resp, err := s.MergeTimeRanges(context.Background(), &types.MergeTimeRangesRequest{RangeIDs: []int{3, 1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TimeRange.RangeID != 1 || !resp.TimeRange.EndTime.Equal(may22) {
+		t.Errorf("merged into %d %s, want 1 until %s", resp.TimeRange.RangeID, resp.TimeRange, may22)
+	}
+	if !reflect.DeepEqual(resp.DeletedRangeIDs, []int{2, 3}) || !reflect.DeepEqual(resp.MovedDropIDs, []int{3, 5}) || !reflect.DeepEqual(resp.DeletedDropIDs, []int{2, 4}) {
+		t.Errorf("deleted ranges %v, moved drops %v and deleted drops %v; want [2 3], [3 5] and [2 4]", resp.DeletedRangeIDs, resp.MovedDropIDs, resp.DeletedDropIDs)
+	}
+
+	store.Read(func(d *types.Snapshot) {
+		if len(d.TimeRanges) != 1 || !d.TimeRanges[0].EndTime.Equal(may22) {
+			t.Errorf("the mock holds time ranges %v, want the merged one", d.TimeRanges)
+		}
+		for _, dropInfo := range d.DropInfos {
+			if dropInfo.RangeID != 1 {
+				t.Errorf("drop info %d is on time range %d, want 1", dropInfo.DropID, dropInfo.RangeID)
+			}
+		}
+		if len(d.DropInfos) != 3 {
+			t.Errorf("the mock holds %d drop infos, want 3", len(d.DropInfos))
+		}
+	})
+}
+
+func TestMergeTimeRangesInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		rangeIDs []int
+	}{
+		{"a single range", []int{1}},
+		{"a gap", []int{1, 3}},
+		{"different servers", []int{1, 4}},
+		{"no end time", []int{1, 5}},
+		{"not found", []int{1, 6}},
+	}
+	noEnd := timeRangeOf(5, "CN", may8, may15)
+	noEnd.EndTime = nil
+	store, s := newTimeRangeService(t,
+		[]*models.TimeRange{timeRangeOf(1, "CN", may1, may8), timeRangeOf(3, "CN", may15, may22), timeRangeOf(4, "US", may8, may15), noEnd},
+		nil,
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.MergeTimeRanges(context.Background(), &types.MergeTimeRangesRequest{RangeIDs: tt.rangeIDs}); err == nil {
+				t.Errorf("merging %v succeeded, want an error", tt.rangeIDs)
+			}
+		})
+	}
+	store.Read(func(d *types.Snapshot) {
+		if len(d.TimeRanges) != 4 || !d.TimeRanges[0].EndTime.Equal(may8) {
+			t.Errorf("the mock holds time ranges %v, want them untouched", d.TimeRanges)
+		}
+	})
+}
+
+// TestGetTimeRangesWithoutStartTime sorts a range without a start time first.
+func TestGetTimeRangesWithoutStartTime(t *testing.T) {
+	noStart := timeRangeOf(2, "CN", may1, may8)
+	noStart.StartTime = nil
+	_, s := newTimeRangeService(t, []*models.TimeRange{timeRangeOf(1, "CN", may8, may15), noStart}, nil)
+
+	timeRanges, err := s.GetTimeRanges(context.Background(), "CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timeRanges) != 2 || timeRanges[0].RangeID != 2 || timeRanges[1].RangeID != 1 {
+		t.Errorf("got time ranges %v, want 2 and then 1", timeRanges)
+	}
+}
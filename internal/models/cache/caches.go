@@ -3,6 +3,7 @@ package cache
 import (
 	"sync"
 
+	"github.com/rs/zerolog/log"
 	"gopkg.in/guregu/null.v3"
 
 	"github.com/penguin-statistics/soracli/internal/models"
@@ -13,6 +14,9 @@ import (
 
 type Flusher func() error
 
+// KeyDeleter deletes a single key from a cache set.
+type KeyDeleter func(key string) error
+
 var (
 	ItemDropSetByStageIDAndRangeID   *cache.Set[[]int]
 	ItemDropSetByStageIdAndTimeRange *cache.Set[[]int]
@@ -41,6 +45,9 @@ var (
 	TimeRangesMap            *cache.Set[map[int]*models.TimeRange]
 	MaxAccumulableTimeRanges *cache.Set[map[int]map[int][]*models.TimeRange]
 
+	// StageTableZoneIDs are the IDs of the zones with stages to render of stage tables, keyed by their URLs.
+	StageTableZoneIDs *cache.Set[[]string]
+
 	Zones           *cache.Singular[[]*models.Zone]
 	ZoneByArkID     *cache.Set[models.Zone]
 	ShimZones       *cache.Singular[[]*shims.Zone]
@@ -51,6 +58,7 @@ var (
 	once sync.Once
 
 	CacheSetMap             map[string]Flusher
+	CacheSetKeyDeleterMap   map[string]KeyDeleter
 	CacheSingularFlusherMap map[string]Flusher
 )
 
@@ -60,10 +68,12 @@ func Initialize() {
 	})
 }
 
+// Delete deletes key from the named cache set, or flushes the named cache set or deletes the
+// named cache singular when key is not given.
 func Delete(name string, key null.String) error {
 	if key.Valid {
-		if _, ok := CacheSetMap[name]; ok {
-			if err := CacheSetMap[name](); err != nil {
+		if _, ok := CacheSetKeyDeleterMap[name]; ok {
+			if err := CacheSetKeyDeleterMap[name](key.String); err != nil {
 				return err
 			}
 		}
@@ -81,78 +91,104 @@ func Delete(name string, key null.String) error {
 	return nil
 }
 
+// InvalidateTag deletes every entry of every cache in the registry that is tagged with tag,
+// including the derived caches depending on every entity of the kind of tag.
+func InvalidateTag(tag string) error {
+	n, err := cache.InvalidateTag(tag)
+	log.Debug().Str("tag", tag).Int("deleted", n).Msg("invalidated cache tag")
+	return err
+}
+
+func registerSet[T any](name string, set *cache.Set[T]) {
+	CacheSetMap[name] = set.Flush
+	CacheSetKeyDeleterMap[name] = set.Delete
+}
+
 func initializeCaches() {
 	CacheSetMap = make(map[string]Flusher)
+	CacheSetKeyDeleterMap = make(map[string]KeyDeleter)
 	CacheSingularFlusherMap = make(map[string]Flusher)
 
 	// drop_info
-	ItemDropSetByStageIDAndRangeID = cache.NewSet[[]int]("itemDropSet#server|stageId|rangeId")
-	ItemDropSetByStageIdAndTimeRange = cache.NewSet[[]int]("itemDropSet#server|stageId|startTime|endTime")
+	ItemDropSetByStageIDAndRangeID = cache.NewSet[[]int]("itemDropSet#server|stageId|rangeId",
+		keyTags("itemDropSet#server|stageId|rangeId"), dependsOn(TagKindStage, TagKindDropInfo))
+	ItemDropSetByStageIdAndTimeRange = cache.NewSet[[]int]("itemDropSet#server|stageId|startTime|endTime",
+		keyTags("itemDropSet#server|stageId|startTime|endTime"), dependsOn(TagKindStage, TagKindDropInfo, TagKindTimeRange))
 
-	CacheSetMap["itemDropSet#server|stageId|rangeId"] = ItemDropSetByStageIDAndRangeID.Flush
-	CacheSetMap["itemDropSet#server|stageId|startTime|endTime"] = ItemDropSetByStageIdAndTimeRange.Flush
+	registerSet("itemDropSet#server|stageId|rangeId", ItemDropSetByStageIDAndRangeID)
+	registerSet("itemDropSet#server|stageId|startTime|endTime", ItemDropSetByStageIdAndTimeRange)
 
 	// item
-	CliGameDataSeed = cache.NewSingular[types.CliGameDataSeedResponse]("cliGameDataSeed")
-	ItemByArkID = cache.NewSet[models.Item]("item#arkItemId")
-	ShimItems = cache.NewSingular[[]*shims.Item]("shimItems")
-	ShimItemByArkID = cache.NewSet[shims.Item]("shimItem#arkItemId")
-	ItemsMapById = cache.NewSingular[map[int]*models.Item]("itemsMapById")
-	ItemsMapByArkID = cache.NewSingular[map[string]*models.Item]("itemsMapByArkId")
+	CliGameDataSeed = cache.NewSingular[types.CliGameDataSeedResponse]("cliGameDataSeed", dependsOn(TagKindItem))
+	ItemByArkID = cache.NewSet[models.Item]("item#arkItemId", keyTags("item#arkItemId"))
+	ShimItems = cache.NewSingular[[]*shims.Item]("shimItems", dependsOn(TagKindItem))
+	ShimItemByArkID = cache.NewSet[shims.Item]("shimItem#arkItemId", keyTags("shimItem#arkItemId"))
+	ItemsMapById = cache.NewSingular[map[int]*models.Item]("itemsMapById", dependsOn(TagKindItem))
+	ItemsMapByArkID = cache.NewSingular[map[string]*models.Item]("itemsMapByArkId", dependsOn(TagKindItem))
 
 	CacheSingularFlusherMap["items"] = CliGameDataSeed.Delete
-	CacheSetMap["item#arkItemId"] = ItemByArkID.Flush
+	registerSet("item#arkItemId", ItemByArkID)
 	CacheSingularFlusherMap["shimItems"] = ShimItems.Delete
-	CacheSetMap["shimItem#arkItemId"] = ShimItemByArkID.Flush
+	registerSet("shimItem#arkItemId", ShimItemByArkID)
 	CacheSingularFlusherMap["itemsMapById"] = ItemsMapById.Delete
 	CacheSingularFlusherMap["itemsMapByArkId"] = ItemsMapByArkID.Delete
 
 	// notice
-	Notices = cache.NewSingular[[]*models.Notice]("notices")
+	Notices = cache.NewSingular[[]*models.Notice]("notices", dependsOn(TagKindNotice))
 
 	CacheSingularFlusherMap["notices"] = Notices.Delete
 
 	// activity
-	Activities = cache.NewSingular[[]*models.Activity]("activities")
-	ShimActivities = cache.NewSingular[[]*shims.Activity]("shimActivities")
+	Activities = cache.NewSingular[[]*models.Activity]("activities", dependsOn(TagKindActivity))
+	ShimActivities = cache.NewSingular[[]*shims.Activity]("shimActivities", dependsOn(TagKindActivity))
 
 	CacheSingularFlusherMap["activities"] = Activities.Delete
 	CacheSingularFlusherMap["shimActivities"] = ShimActivities.Delete
 
 	// stage
-	Stages = cache.NewSingular[[]*models.Stage]("stages")
-	StageByArkID = cache.NewSet[models.Stage]("stage#arkStageId")
-	ShimStages = cache.NewSet[[]*shims.Stage]("shimStages#server")
-	ShimStageByArkID = cache.NewSet[shims.Stage]("shimStage#server|arkStageId")
-	StagesMapByID = cache.NewSingular[map[int]*models.Stage]("stagesMapById")
-	StagesMapByArkID = cache.NewSingular[map[string]*models.Stage]("stagesMapByArkId")
+	Stages = cache.NewSingular[[]*models.Stage]("stages", dependsOn(TagKindStage))
+	StageByArkID = cache.NewSet[models.Stage]("stage#arkStageId", keyTags("stage#arkStageId"))
+	ShimStages = cache.NewSet[[]*shims.Stage]("shimStages#server",
+		keyTags("shimStages#server"), dependsOn(TagKindStage, TagKindDropInfo, TagKindTimeRange))
+	ShimStageByArkID = cache.NewSet[shims.Stage]("shimStage#server|arkStageId",
+		keyTags("shimStage#server|arkStageId"), dependsOn(TagKindDropInfo, TagKindTimeRange))
+	StagesMapByID = cache.NewSingular[map[int]*models.Stage]("stagesMapById", dependsOn(TagKindStage))
+	StagesMapByArkID = cache.NewSingular[map[string]*models.Stage]("stagesMapByArkId", dependsOn(TagKindStage))
 
 	CacheSingularFlusherMap["stages"] = Stages.Delete
-	CacheSetMap["stage#arkStageId"] = StageByArkID.Flush
-	CacheSetMap["shimStages#server"] = ShimStages.Flush
-	CacheSetMap["shimStage#server|arkStageId"] = ShimStageByArkID.Flush
+	registerSet("stage#arkStageId", StageByArkID)
+	registerSet("shimStages#server", ShimStages)
+	registerSet("shimStage#server|arkStageId", ShimStageByArkID)
 	CacheSingularFlusherMap["stagesMapById"] = StagesMapByID.Delete
 	CacheSingularFlusherMap["stagesMapByArkId"] = StagesMapByArkID.Delete
 
 	// time_range
-	TimeRanges = cache.NewSet[[]*models.TimeRange]("timeRanges#server")
-	TimeRangeByID = cache.NewSet[models.TimeRange]("timeRange#rangeId")
-	TimeRangesMap = cache.NewSet[map[int]*models.TimeRange]("timeRangesMap#server")
-	MaxAccumulableTimeRanges = cache.NewSet[map[int]map[int][]*models.TimeRange]("maxAccumulableTimeRanges#server")
-
-	CacheSetMap["timeRanges#server"] = TimeRanges.Flush
-	CacheSetMap["timeRange#rangeId"] = TimeRangeByID.Flush
-	CacheSetMap["timeRangesMap#server"] = TimeRangesMap.Flush
-	CacheSetMap["maxAccumulableTimeRanges#server"] = MaxAccumulableTimeRanges.Flush
+	TimeRanges = cache.NewSet[[]*models.TimeRange]("timeRanges#server",
+		keyTags("timeRanges#server"), dependsOn(TagKindTimeRange))
+	TimeRangeByID = cache.NewSet[models.TimeRange]("timeRange#rangeId", keyTags("timeRange#rangeId"))
+	TimeRangesMap = cache.NewSet[map[int]*models.TimeRange]("timeRangesMap#server",
+		keyTags("timeRangesMap#server"), dependsOn(TagKindTimeRange))
+	MaxAccumulableTimeRanges = cache.NewSet[map[int]map[int][]*models.TimeRange]("maxAccumulableTimeRanges#server",
+		keyTags("maxAccumulableTimeRanges#server"), dependsOn(TagKindTimeRange, TagKindDropInfo))
+
+	registerSet("timeRanges#server", TimeRanges)
+	registerSet("timeRange#rangeId", TimeRangeByID)
+	registerSet("timeRangesMap#server", TimeRangesMap)
+	registerSet("maxAccumulableTimeRanges#server", MaxAccumulableTimeRanges)
 
 	// zone
-	Zones = cache.NewSingular[[]*models.Zone]("zones")
-	ZoneByArkID = cache.NewSet[models.Zone]("zone#arkZoneId")
-	ShimZones = cache.NewSingular[[]*shims.Zone]("shimZones")
-	ShimZoneByArkID = cache.NewSet[shims.Zone]("shimZone#arkZoneId")
+	Zones = cache.NewSingular[[]*models.Zone]("zones", dependsOn(TagKindZone))
+	ZoneByArkID = cache.NewSet[models.Zone]("zone#arkZoneId", keyTags("zone#arkZoneId"))
+	ShimZones = cache.NewSingular[[]*shims.Zone]("shimZones", dependsOn(TagKindZone, TagKindStage))
+	ShimZoneByArkID = cache.NewSet[shims.Zone]("shimZone#arkZoneId", keyTags("shimZone#arkZoneId"), dependsOn(TagKindStage))
 
 	CacheSingularFlusherMap["zones"] = Zones.Delete
-	CacheSetMap["zone#arkZoneId"] = ZoneByArkID.Flush
+	registerSet("zone#arkZoneId", ZoneByArkID)
 	CacheSingularFlusherMap["shimZones"] = ShimZones.Delete
-	CacheSetMap["shimZone#arkZoneId"] = ShimZoneByArkID.Flush
+	registerSet("shimZone#arkZoneId", ShimZoneByArkID)
+
+	// stage table
+	StageTableZoneIDs = cache.NewSet[[]string]("stageTableZoneIds#sourceUrl")
+
+	registerSet("stageTableZoneIds#sourceUrl", StageTableZoneIDs)
 }
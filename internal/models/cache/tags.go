@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/pkg/cache"
+)
+
+// Tag kinds of the cache entries in the registry. Stages, zones and items are tagged by their
+// ark IDs; time ranges, drop infos, activities and notices by their IDs.
+const (
+	TagKindServer    = "server"
+	TagKindStage     = "stage"
+	TagKindZone      = "zone"
+	TagKindItem      = "item"
+	TagKindTimeRange = "timeRange"
+	TagKindDropInfo  = "dropInfo"
+	TagKindActivity  = "activity"
+	TagKindNotice    = "notice"
+)
+
+// keyFieldTagKinds maps the key fields used in the names of cache sets to the tag kinds they identify.
+var keyFieldTagKinds = map[string]string{
+	"server":     TagKindServer,
+	"arkStageId": TagKindStage,
+	"arkZoneId":  TagKindZone,
+	"arkItemId":  TagKindItem,
+	"rangeId":    TagKindTimeRange,
+}
+
+func tag(kind, value string) string {
+	return kind + ":" + value
+}
+
+// TagAny returns the tag matching every entity of kind.
+func TagAny(kind string) string {
+	return tag(kind, cache.TagWildcard)
+}
+
+func TagServer(server string) string {
+	return tag(TagKindServer, server)
+}
+
+func TagStage(arkStageId string) string {
+	return tag(TagKindStage, arkStageId)
+}
+
+func TagZone(arkZoneId string) string {
+	return tag(TagKindZone, arkZoneId)
+}
+
+func TagItem(arkItemId string) string {
+	return tag(TagKindItem, arkItemId)
+}
+
+func TagTimeRange(rangeId int) string {
+	return tag(TagKindTimeRange, strconv.Itoa(rangeId))
+}
+
+func TagDropInfo(dropId int) string {
+	return tag(TagKindDropInfo, strconv.Itoa(dropId))
+}
+
+func TagActivity(activityId int) string {
+	return tag(TagKindActivity, strconv.Itoa(activityId))
+}
+
+func TagNotice(noticeId int) string {
+	return tag(TagKindNotice, strconv.Itoa(noticeId))
+}
+
+// keyTags tags each entry of a cache set from its key, following the key fields listed after
+// `#` in the name of the set, e.g. `shimStage#server|arkStageId` tags key `CN|main_01-07` with
+// `server:CN` and `stage:main_01-07`.
+func keyTags(name string) cache.Option {
+	_, spec, _ := strings.Cut(name, "#")
+	fields := strings.Split(spec, consts.CacheSep)
+	return cache.WithKeyTags(func(key string) []string {
+		values := strings.Split(key, consts.CacheSep)
+		tags := make([]string, 0, len(fields))
+		for i, field := range fields {
+			kind, ok := keyFieldTagKinds[field]
+			if !ok || i >= len(values) {
+				continue
+			}
+			tags = append(tags, tag(kind, values[i]))
+		}
+		return tags
+	})
+}
+
+// dependsOn tags every entry of a cache with the wildcard tags of kinds, as the cached values are
+// derived from every entity of those kinds.
+func dependsOn(kinds ...string) cache.Option {
+	tags := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		tags = append(tags, TagAny(kind))
+	}
+	return cache.WithTags(tags...)
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type DropPattern struct {
+	PatternID int    `bun:",pk,autoincrement" json:"id"`
+	Hash      string `json:"hash"`
+
+	Elements []*DropPatternElement `bun:"rel:has-many,join:pattern_id=drop_pattern_id" json:"elements,omitempty"`
+}
+
+type DropPatternElement struct {
+	ElementID     int `bun:",pk,autoincrement" json:"id"`
+	DropPatternID int `json:"dropPatternId"`
+	ItemID        int `json:"itemId"`
+	Quantity      int `json:"quantity"`
+}
+
+// Quantities returns the quantity of each item in the pattern, keyed by item ID. Without Elements,
+// they are parsed from Hash, which is in the form of `itemId:quantity|itemId:quantity`.
+func (p *DropPattern) Quantities() (map[int]int, error) {
+	quantities := make(map[int]int)
+	if len(p.Elements) > 0 {
+		for _, element := range p.Elements {
+			quantities[element.ItemID] += element.Quantity
+		}
+		return quantities, nil
+	}
+
+	if p.Hash == "" {
+		return quantities, nil
+	}
+	for _, part := range strings.Split(p.Hash, "|") {
+		itemID, quantity, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, errors.Errorf("invalid hash %q of drop pattern %d", p.Hash, p.PatternID)
+		}
+		id, err := strconv.Atoi(itemID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid item ID in hash %q of drop pattern %d", p.Hash, p.PatternID)
+		}
+		q, err := strconv.Atoi(quantity)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid quantity in hash %q of drop pattern %d", p.Hash, p.PatternID)
+		}
+		quantities[id] += q
+	}
+	return quantities, nil
+}
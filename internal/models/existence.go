@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Existence is the typed form of the `existence` JSON column found on zones, stages,
+// items, activities and notices: a map with server code as key.
+type Existence map[string]*ServerExistence
+
+// ServerExistence describes whether an entity exists in a server, and optionally the
+// time window, in milliseconds since epoch, in which it does.
+type ServerExistence struct {
+	Exist     bool   `json:"exist"`
+	OpenTime  *int64 `json:"openTime,omitempty"`
+	CloseTime *int64 `json:"closeTime,omitempty"`
+}
+
+func ParseExistence(raw json.RawMessage) (Existence, error) {
+	existence := make(Existence)
+	if len(raw) == 0 || string(raw) == "null" {
+		return existence, nil
+	}
+	if err := json.Unmarshal(raw, &existence); err != nil {
+		return nil, err
+	}
+	return existence, nil
+}
+
+func (e Existence) Marshal() (json.RawMessage, error) {
+	return json.Marshal(e)
+}
+
+// ExistsAt reports whether the entity exists in server at t. A missing open or close
+// time is treated as unbounded.
+func (e Existence) ExistsAt(server string, t time.Time) bool {
+	se, ok := e[server]
+	if !ok || se == nil || !se.Exist {
+		return false
+	}
+	if se.OpenTime != nil && t.UnixMilli() < *se.OpenTime {
+		return false
+	}
+	if se.CloseTime != nil && t.UnixMilli() >= *se.CloseTime {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package gamedata
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AppliedBundle records a rendered bundle saved through the admin api, and the IDs of the entities it
+// created or updated, so that it can be undone.
+type AppliedBundle struct {
+	Session   string    `json:"session"`
+	BaseURL   string    `json:"baseUrl"`
+	Server    string    `json:"server"`
+	AppliedAt time.Time `json:"appliedAt"`
+
+	// Created are the entities created by the bundle.
+	Created *AppliedIDs `json:"created"`
+	// Updated are the zone and stages that existed before and were overwritten by the bundle.
+	Updated *AppliedIDs `json:"updated"`
+	// Prior are the existences of the updated zone and stages before the bundle was saved, which undoing
+	// it restores.
+	Prior *PriorExistences `json:"prior,omitempty"`
+
+	Rendered *RenderedObjects `json:"rendered"`
+
+	// UndoneAt is when the bundle was undone, if it was.
+	UndoneAt *time.Time `json:"undoneAt,omitempty"`
+}
+
+type AppliedIDs struct {
+	ZoneIDs     []int `json:"zoneIds,omitempty"`
+	StageIDs    []int `json:"stageIds,omitempty"`
+	DropIDs     []int `json:"dropIds,omitempty"`
+	RangeIDs    []int `json:"rangeIds,omitempty"`
+	ActivityIDs []int `json:"activityIds,omitempty"`
+}
+
+// PriorExistences are existences of zones and stages, by ID.
+type PriorExistences struct {
+	Zones  map[int]json.RawMessage `json:"zones,omitempty"`
+	Stages map[int]json.RawMessage `json:"stages,omitempty"`
+}
+
+const (
+	UndoActionDelete           = "delete"
+	UndoActionRestoreExistence = "restore existence"
+)
+
+// UndoStep is a request undoing part of an applied bundle. Steps are run in order, so that no entity is
+// deleted before the ones depending on it.
+type UndoStep struct {
+	Action string
+	// Kind is the kind of the entities, e.g. "dropInfo"
+	Kind     string
+	IDs      []int
+	Endpoint string
+	// Existence is the existence restored by an UndoActionRestoreExistence step.
+	Existence json.RawMessage
+}
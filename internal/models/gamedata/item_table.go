@@ -0,0 +1,81 @@
+package gamedata
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+)
+
+type ItemTable struct {
+	Items map[string]*Item `json:"items"`
+}
+
+type Item struct {
+	ItemID       string     `json:"itemId"`
+	Name         string     `json:"name"`
+	Rarity       ItemRarity `json:"rarity"`
+	SortID       int        `json:"sortId"`
+	IconID       string     `json:"iconId"`
+	ClassifyType string     `json:"classifyType"`
+	ItemType     string     `json:"itemType"`
+}
+
+// ItemRarity is the zero-based rarity of an item. Older game data uses plain integers,
+// while newer game data uses strings like "TIER_3", which is the same as 2.
+type ItemRarity int
+
+func (r *ItemRarity) UnmarshalJSON(b []byte) error {
+	var i int
+	if err := json.Unmarshal(b, &i); err == nil {
+		*r = ItemRarity(i)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	tier, err := strconv.Atoi(strings.TrimPrefix(s, "TIER_"))
+	if err != nil {
+		return err
+	}
+	*r = ItemRarity(tier - 1)
+	return nil
+}
+
+// ItemSyncReport describes how the items known to Penguin differ from the item tables of the game.
+type ItemSyncReport struct {
+	Missing             []*MissingItem
+	NameMismatches      []*ItemNameMismatch
+	ExistenceMismatches []*ItemExistenceMismatch
+
+	Payload *ItemSyncPayload
+}
+
+type MissingItem struct {
+	ArkItemID string
+	Name      string
+	Servers   []string
+}
+
+type ItemNameMismatch struct {
+	ItemID       int
+	ArkItemID    string
+	Lang         string
+	PenguinName  string
+	GameDataName string
+}
+
+type ItemExistenceMismatch struct {
+	ItemID    int
+	ArkItemID string
+	Server    string
+}
+
+// ItemSyncPayload holds the items to create and the updated items to submit.
+type ItemSyncPayload struct {
+	Create []*models.Item `json:"create"`
+	Update []*models.Item `json:"update"`
+}
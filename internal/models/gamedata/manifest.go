@@ -0,0 +1,68 @@
+package gamedata
+
+import (
+	"github.com/penguin-statistics/soracli/internal/models"
+)
+
+// Manifest is a batch of new events to render and apply at once, in order. Its defaults apply to every
+// entry not overriding them.
+type Manifest struct {
+	// SourceURL is the default source url of the stage table of the entries, which may contain
+	// consts.GameDataRegionPlaceholder in place of the game data region.
+	SourceURL string           `yaml:"sourceUrl"`
+	Rules     *RenderRules     `yaml:"rules"`
+	Entries   []*ManifestEntry `yaml:"entries"`
+}
+
+// ManifestEntry is the NewEventBasicInfo of a new event in a Manifest, with its source, region and rule
+// overrides. Times are in the format of the render flags, in the local time of Server unless suffixed
+// with `@SERVER`.
+type ManifestEntry struct {
+	ArkZoneID    string `yaml:"arkZoneId"`
+	ZoneName     string `yaml:"zoneName"`
+	ZoneCategory string `yaml:"zoneCategory"`
+	ZoneType     string `yaml:"zoneType"`
+	Server       string `yaml:"server"`
+	StartTime    string `yaml:"startTime"`
+	StartDate    string `yaml:"startDate"`
+	EndTime      string `yaml:"endTime"`
+	EndDate      string `yaml:"endDate"`
+
+	// SourceURL overrides the source url of the manifest.
+	SourceURL string `yaml:"sourceUrl"`
+	// Region replaces consts.GameDataRegionPlaceholder in the source url, instead of the region of Server.
+	Region string `yaml:"region"`
+	// Rules are merged over the rules of the manifest.
+	Rules *RenderRules `yaml:"rules"`
+}
+
+// RenderRules override how the drop infos of a new event are rendered.
+type RenderRules struct {
+	// ItemBounds are the bounds of the drop infos of items, keyed by ark item ID.
+	ItemBounds map[string]*models.Bounds `yaml:"itemBounds"`
+	// DropTypeBounds are the bounds of the drop infos of drop types, keyed by drop type, e.g. REGULAR.
+	DropTypeBounds map[string]*models.Bounds `yaml:"dropTypeBounds"`
+	// ExcludeStages are the ark stage IDs of stages not to render.
+	ExcludeStages []string `yaml:"excludeStages"`
+}
+
+// Merge returns the rules of r overridden by override, either of which may be nil.
+func (r *RenderRules) Merge(override *RenderRules) *RenderRules {
+	merged := &RenderRules{
+		ItemBounds:     make(map[string]*models.Bounds),
+		DropTypeBounds: make(map[string]*models.Bounds),
+	}
+	for _, rules := range []*RenderRules{r, override} {
+		if rules == nil {
+			continue
+		}
+		for arkItemID, bounds := range rules.ItemBounds {
+			merged.ItemBounds[arkItemID] = bounds
+		}
+		for dropType, bounds := range rules.DropTypeBounds {
+			merged.DropTypeBounds[dropType] = bounds
+		}
+		merged.ExcludeStages = append(merged.ExcludeStages, rules.ExcludeStages...)
+	}
+	return merged
+}
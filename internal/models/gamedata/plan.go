@@ -0,0 +1,74 @@
+package gamedata
+
+import (
+	"time"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+)
+
+const (
+	PlanActionCreate = "create"
+	PlanActionUpdate = "update"
+	PlanActionDelete = "delete"
+)
+
+const (
+	PlanKindZone      = "zone"
+	PlanKindStage     = "stage"
+	PlanKindTimeRange = "timeRange"
+	PlanKindDropInfo  = "dropInfo"
+)
+
+// Plan is the changes reconciling the live admin data with a desired-state directory of rendered bundles.
+// A saved plan is only applied if the live data is still the one it was planned against.
+type Plan struct {
+	BaseURL   string    `json:"baseUrl"`
+	Dir       string    `json:"dir"`
+	PlannedAt time.Time `json:"plannedAt"`
+	// LiveDigest is the digest of the live zones, stages, time ranges and drop infos planned against.
+	LiveDigest string        `json:"liveDigest"`
+	Changes    []*PlanChange `json:"changes"`
+}
+
+// Count returns the number of changes of action.
+func (p *Plan) Count(action string) int {
+	count := 0
+	for _, change := range p.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// PlanChange creates, updates or deletes an entity. Changes are run in order, so that entities are created
+// before the ones referencing them and deleted after.
+type PlanChange struct {
+	Action string `json:"action"`
+	Kind   string `json:"kind"`
+	// Key identifies the entity regardless of its ID: the ark ID of a zone or stage, the server and times of
+	// a time range, or the stage, time range, drop type and item of a drop info.
+	Key string `json:"key"`
+	// ID is the live ID of the entity updated or deleted.
+	ID int `json:"id,omitempty"`
+	// Fields are the JSON fields changed by an update.
+	Fields []string `json:"fields,omitempty"`
+
+	// ZoneKey, StageKey and RangeKey are the keys of the zone of a stage, and of the stage and time range of a
+	// drop info, whose IDs are filled in once they are created.
+	ZoneKey  string `json:"zoneKey,omitempty"`
+	StageKey string `json:"stageKey,omitempty"`
+	RangeKey string `json:"rangeKey,omitempty"`
+
+	// the entity created or updated, as desired
+	Zone      *models.Zone      `json:"zone,omitempty"`
+	Stage     *models.Stage     `json:"stage,omitempty"`
+	TimeRange *models.TimeRange `json:"timeRange,omitempty"`
+	DropInfo  *models.DropInfo  `json:"dropInfo,omitempty"`
+}
+
+// DesiredBundle is a rendered bundle of a desired-state directory, along with the file it is read from.
+type DesiredBundle struct {
+	File string
+	*RenderedObjects
+}
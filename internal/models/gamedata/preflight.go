@@ -0,0 +1,43 @@
+package gamedata
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	PreflightSeverityError   = "ERROR"
+	PreflightSeverityWarning = "WARNING"
+)
+
+// PreflightFinding is a problem found in the source data before rendering.
+type PreflightFinding struct {
+	Severity string
+	// Subject is what the finding is about, e.g. a stage ID or an item ID.
+	Subject    string
+	Message    string
+	Suggestion string
+}
+
+// PreflightError is returned by rendering when the preflight found any finding of
+// PreflightSeverityError. Its message is the full, human-readable report.
+type PreflightError struct {
+	Findings []*PreflightFinding
+}
+
+func (e *PreflightError) Error() string {
+	return "render preflight failed:\n" + FormatFindings(e.Findings)
+}
+
+// FormatFindings formats findings as a human-readable report, one finding per line followed
+// by its suggested fix.
+func FormatFindings(findings []*PreflightFinding) string {
+	var b strings.Builder
+	for _, f := range findings {
+		fmt.Fprintf(&b, "  [%s] %s: %s\n", f.Severity, f.Subject, f.Message)
+		if f.Suggestion != "" {
+			fmt.Fprintf(&b, "      suggested fix: %s\n", f.Suggestion)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,12 @@
+package gamedata
+
+import "github.com/penguin-statistics/soracli/internal/models/shims"
+
+// ShimBundle is a RenderedObjects bundle in the v2 shim shapes. Each field holds the part of
+// the response of the /PenguinStats/api/v2 endpoint of the same name that the bundle contributes.
+type ShimBundle struct {
+	Zones  []*shims.Zone     `json:"zones"`
+	Stages []*shims.Stage    `json:"stages"`
+	Items  []*shims.Item     `json:"items"`
+	Period []*shims.Activity `json:"period"`
+}
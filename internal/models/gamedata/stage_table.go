@@ -27,3 +27,9 @@ type DisplayDetailReward struct {
 	DropType string `json:"dropType"`
 	Type     string `json:"type"`
 }
+
+// ZoneStages is a zone of a stage table, along with the stages of it that render imports.
+type ZoneStages struct {
+	ArkZoneID string
+	Stages    []*Stage
+}
@@ -15,6 +15,8 @@ type Item struct {
 	Name json.RawMessage `json:"name" swaggertype:"object"`
 	// Existence is a map with server code as key and the existence of the item in that server as value.
 	Existence json.RawMessage `json:"existence" swaggertype:"object"`
+	// Type is the type of the item, e.g. "MATERIAL", "CARD_EXP" and "FURN".
+	Type string `bun:"type" json:"itemType,omitempty"`
 	// SortID is the sort position of the item.
 	SortID int `json:"sortId"`
 	Rarity int `json:"rarity"`
@@ -0,0 +1,18 @@
+package shims
+
+import (
+	"gopkg.in/guregu/null.v3"
+)
+
+type DropMatrixQueryResult struct {
+	Matrix []*OneDropMatrixElement `json:"matrix"`
+}
+
+type OneDropMatrixElement struct {
+	StageID   string   `json:"stageId"`
+	ItemID    string   `json:"itemId"`
+	Quantity  int      `json:"quantity"`
+	Times     int      `json:"times"`
+	StartTime int64    `json:"start"`
+	EndTime   null.Int `json:"end" swaggertype:"integer"`
+}
@@ -34,12 +34,12 @@ func TimeRangeFromString(s string) *TimeRange {
 		if err != nil {
 			return nil
 		}
-		startTime = time.Unix(startTimestamp/1000, 0)
+		startTime = time.UnixMilli(startTimestamp)
 		endTimestamp, err := strconv.ParseInt(times[1], 10, 64)
 		if err != nil {
 			return nil
 		}
-		endTime = time.Unix(endTimestamp/1000, 0)
+		endTime = time.UnixMilli(endTimestamp)
 	}
 
 	return &TimeRange{
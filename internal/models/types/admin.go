@@ -36,3 +36,35 @@ type PurgeCacheRequest struct {
 type CliGameDataSeedResponse struct {
 	Items []*models.Item `json:"items"`
 }
+
+type SplitTimeRangeRequest struct {
+	RangeID int `json:"rangeId"`
+	// SplitTime is the time, in milliseconds, the original range ends and the new range starts at.
+	SplitTime int64       `json:"splitTime"`
+	Name      null.String `json:"name,omitempty" swaggertype:"string"`
+	Comment   null.String `json:"comment" swaggertype:"string"`
+	// DropInfos are created on the new range; their rangeId is ignored.
+	DropInfos []*models.DropInfo `json:"dropInfos"`
+}
+
+type SplitTimeRangeResponse struct {
+	Original  *models.TimeRange  `json:"original"`
+	TimeRange *models.TimeRange  `json:"timeRange"`
+	DropInfos []*models.DropInfo `json:"dropInfos"`
+}
+
+type MergeTimeRangesRequest struct {
+	RangeIDs []int `json:"rangeIds"`
+}
+
+type MergeTimeRangesResponse struct {
+	TimeRange       *models.TimeRange `json:"timeRange"`
+	DeletedRangeIDs []int             `json:"deletedRangeIds"`
+	MovedDropIDs    []int             `json:"movedDropIds"`
+	DeletedDropIDs  []int             `json:"deletedDropIds"`
+}
+
+// DeleteEntitiesRequest deletes the entities of the IDs, of the kind of the endpoint it is posted to.
+type DeleteEntitiesRequest struct {
+	IDs []int `json:"ids"`
+}
@@ -0,0 +1,49 @@
+package types
+
+import (
+	"github.com/penguin-statistics/soracli/internal/models"
+)
+
+const (
+	// BoundsViolationItemQuantity is an item quantity outside the bounds of its drop info, or one of its exceptions.
+	BoundsViolationItemQuantity = "ITEM_QUANTITY"
+	// BoundsViolationUnknownItem is an item without a drop info on the stage within the time range.
+	BoundsViolationUnknownItem = "UNKNOWN_ITEM"
+	// BoundsViolationTypeCount is a count of item kinds of a drop type outside the bounds of the drop info of the type.
+	BoundsViolationTypeCount = "TYPE_COUNT"
+)
+
+// BoundsAudit is the result of replaying drop reports against the bounds of the drop infos in force.
+type BoundsAudit struct {
+	Violations  []*BoundsViolation
+	Suggestions []*BoundsSuggestion
+	Summary     *DropMatrixSummary
+
+	// WronglyAccepted counts the reliable reports that violate the bounds.
+	WronglyAccepted int
+	// WronglyRejected counts the unreliable reports that satisfy the bounds.
+	WronglyRejected int
+}
+
+// BoundsViolation is a report violating the bounds of a drop info.
+type BoundsViolation struct {
+	Report    *models.DropReport
+	DropInfo  *models.DropInfo
+	TimeRange *models.TimeRange
+	Kind      string
+	// Subject is the ark item ID, or the drop type for BoundsViolationTypeCount.
+	Subject string
+	Value   int
+}
+
+// BoundsSuggestion suggests bounds for a drop info from the quantities observed in the reports.
+type BoundsSuggestion struct {
+	DropInfo  *models.DropInfo
+	TimeRange *models.TimeRange
+	// Subject is the ark item ID, or the drop type of a drop info without an item.
+	Subject   string
+	Observed  *models.Bounds
+	Suggested *models.Bounds
+	Samples   int
+	Reason    string
+}
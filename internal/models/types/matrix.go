@@ -0,0 +1,32 @@
+package types
+
+import (
+	"github.com/penguin-statistics/soracli/internal/models"
+)
+
+// DropMatrixElement is the drop rate of an item on a stage within a time range, computed from drop reports.
+type DropMatrixElement struct {
+	Server     string
+	StageID    int
+	ItemID     int
+	ArkStageID string
+	ArkItemID  string
+	TimeRange  *models.TimeRange
+
+	// Quantity is the total quantity of the item dropped in Times runs.
+	Quantity int
+	Times    int
+
+	// Rate is the mean quantity per run, and [Lower, Upper] its confidence interval.
+	Rate  float64
+	Lower float64
+	Upper float64
+}
+
+// DropMatrixSummary counts the drop reports that were not taken into the drop matrix.
+type DropMatrixSummary struct {
+	Reports     int
+	Unreliable  int
+	NoTimeRange int
+	NoPattern   int
+}
@@ -0,0 +1,17 @@
+package types
+
+import (
+	"github.com/penguin-statistics/soracli/internal/models"
+)
+
+// Snapshot is a point-in-time copy of the admin-managed entities, as served by the
+// entity listing endpoints of the admin API.
+type Snapshot struct {
+	Items      []*models.Item      `json:"items"`
+	Zones      []*models.Zone      `json:"zones"`
+	Stages     []*models.Stage     `json:"stages"`
+	DropInfos  []*models.DropInfo  `json:"dropInfos"`
+	TimeRanges []*models.TimeRange `json:"timeRanges"`
+	Activities []*models.Activity  `json:"activities"`
+	Notices    []*models.Notice    `json:"notices"`
+}
@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Entry records a mutating request to the admin api.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Session identifies the soracli run the request was made in.
+	Session  string `json:"session"`
+	Profile  string `json:"profile"`
+	Operator string `json:"operator"`
+
+	Method    string `json:"method"`
+	BaseURL   string `json:"baseUrl"`
+	Endpoint  string `json:"endpoint"`
+	RequestID string `json:"requestId"`
+
+	PayloadHash string          `json:"payloadHash"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+
+	// Status is the status code of the response, or zero if none was received.
+	Status int `json:"status"`
+	// Response is the response body, if it is JSON.
+	Response      json.RawMessage `json:"response,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	DurationMilli int64           `json:"durationMs"`
+}
+
+// Succeeded reports whether the request was received and succeeded.
+func (e *Entry) Succeeded() bool {
+	return e.Status >= 200 && e.Status < 300 && e.Error == ""
+}
+
+// Log is an append-only JSON lines file of entries.
+type Log struct {
+	filename string
+	mu       sync.Mutex
+}
+
+func NewLog(filename string) *Log {
+	return &Log{filename: filename}
+}
+
+func (l *Log) Filename() string {
+	return l.filename
+}
+
+// Append writes entry as a single line at the end of the log.
+func (l *Log) Append(entry *Entry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// Entries reads all entries of the log, oldest first. A missing log has no entries.
+func (l *Log) Entries() ([]*Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.filename)
+	if os.IsNotExist(err) {
+		return []*Entry{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := make([]*Entry, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, errors.Wrapf(err, "%s:%d", l.filename, line)
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, scanner.Err()
+}
+
+// HashPayload returns the SHA-256 of payload, as `sha256:<hex>`.
+func HashPayload(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// NewID returns a random ID for a request or a session.
+func NewID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate a random ID")
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewSessionID returns an ID for a soracli run starting at t, readable enough to be typed back.
+func NewSessionID(t time.Time) (string, error) {
+	id, err := NewID()
+	if err != nil {
+		return "", err
+	}
+	return t.Format("20060102-150405") + "-" + id[:4], nil
+}
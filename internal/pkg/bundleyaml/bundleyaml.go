@@ -0,0 +1,353 @@
+// Package bundleyaml converts rendered bundles to and from an annotated YAML form for editing by hand.
+//
+// The YAML form has the fields of the canonical JSON, in the same order, with comments naming the items and
+// stages behind numeric IDs, and times in the local time of their servers. Decode converts it back
+// to the canonical form; comments are ignored, so that a bundle encoded and decoded unchanged is identical.
+package bundleyaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/pkg/gdutils"
+)
+
+// localTimeLayout is the layout of local times, with milliseconds only if there are any.
+const localTimeLayout = "2006-01-02T15:04:05.999Z07:00"
+
+const header = `Rendered bundle of zone %s, in the annotated YAML editing format.
+
+Comments are ignored when the file is read back, so edit values only. Times are in the local time of their
+servers, that of the time range for the activity, and may be written as RFC3339 or 2006-01-02 15:04.
+Existence openTime and closeTime are saved as epoch milliseconds.`
+
+var sectionComments = map[string]string{
+	"zone":   "the zone; penguinZoneId 0 is assigned when saved",
+	"stages": "the stages of the zone; penguinStageId 0 is assigned when saved",
+	"dropInfosMap": "the drop infos of each stage, by ark stage ID. itemId null is the total of the items of its drop type;\n" +
+		"bounds are the least and most of it dropped per run, except for the counts in exceptions",
+	"timeRange": "the time range the drop infos are in",
+	"activity":  "the activity shown on the site",
+}
+
+// Encode converts rendered to the annotated YAML form, naming items by items, keyed by their penguin item IDs.
+func Encode(rendered *gamedata.RenderedObjects, items map[int]*models.Item) ([]byte, error) {
+	b, err := json.Marshal(rendered)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, so parsing it keeps the fields in their canonical order
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	root := doc.Content[0]
+	blockStyle(root)
+
+	stageCodes := make(map[string]string)
+	for _, stage := range rendered.Stages {
+		stageCodes[stage.ArkStageID] = stageCode(stage)
+	}
+
+	server := ""
+	if rendered.TimeRange != nil {
+		server = rendered.TimeRange.Server
+	}
+	arkZoneId := ""
+	if rendered.Zone != nil {
+		arkZoneId = rendered.Zone.ArkZoneID
+	}
+	doc.HeadComment = fmt.Sprintf(header, arkZoneId)
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		key.HeadComment = sectionComments[key.Value]
+		switch key.Value {
+		case "zone":
+			if err := annotateEntity(value, stageCodes); err != nil {
+				return nil, err
+			}
+		case "activity":
+			if err := annotateEntity(value, stageCodes); err != nil {
+				return nil, err
+			}
+			if err := annotateTimes(value, server); err != nil {
+				return nil, err
+			}
+		case "stages":
+			for _, stage := range value.Content {
+				if err := annotateEntity(stage, stageCodes); err != nil {
+					return nil, err
+				}
+			}
+		case "dropInfosMap":
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				if code, ok := stageCodes[value.Content[j].Value]; ok {
+					value.Content[j].LineComment = code
+				}
+				for _, dropInfo := range value.Content[j+1].Content {
+					annotateDropInfo(dropInfo, items)
+				}
+			}
+		case "timeRange":
+			if err := annotateTimes(value, server); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode converts the annotated YAML form back to a rendered bundle.
+func Decode(b []byte) (*gamedata.RenderedObjects, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("not a rendered bundle")
+	}
+	root := doc.Content[0]
+	server := ""
+	if s := field(field(root, "timeRange"), "server"); s != nil {
+		server = s.Value
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		var err error
+		switch key.Value {
+		case "zone":
+			err = decodeExistence(key.Value, value)
+		case "activity":
+			if err = decodeExistence(key.Value, value); err == nil {
+				err = decodeTimes(key.Value, value, server)
+			}
+		case "timeRange":
+			err = decodeTimes(key.Value, value, server)
+		case "stages":
+			for j, stage := range value.Content {
+				if err = decodeExistence(fmt.Sprintf("stages[%d]", j), stage); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	// times are kept as they are written, rather than resolved as YAML timestamps
+	stringTimes(root)
+
+	var v any
+	if err := root.Decode(&v); err != nil {
+		return nil, err
+	}
+	j, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var rendered gamedata.RenderedObjects
+	if err := json.Unmarshal(j, &rendered); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+// blockStyle makes node and its children use the block style, rather than the JSON flow style.
+func blockStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle | yaml.DoubleQuotedStyle
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func stringTimes(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!timestamp" {
+		node.Tag = "!!str"
+	}
+	for _, child := range node.Content {
+		stringTimes(child)
+	}
+}
+
+// field returns the value of the field of a mapping node, or nil.
+func field(node *yaml.Node, name string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == name {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// annotateEntity annotates the stage code of a stage and converts the existence times of an entity to local times.
+func annotateEntity(node *yaml.Node, stageCodes map[string]string) error {
+	if stageId := field(node, "stageId"); stageId != nil {
+		stageId.LineComment = stageCodes[stageId.Value]
+	}
+
+	existence := field(node, "existence")
+	if existence == nil || existence.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(existence.Content); i += 2 {
+		server, state := existence.Content[i].Value, existence.Content[i+1]
+		loc, err := gdutils.ServerLocation(server)
+		if err != nil {
+			continue
+		}
+		for _, name := range []string{"openTime", "closeTime"} {
+			t := field(state, name)
+			if t == nil || t.Tag != "!!int" {
+				continue
+			}
+			millis, err := strconv.ParseInt(t.Value, 10, 64)
+			if err != nil {
+				return err
+			}
+			if millis == consts.FakeEndTimeMilli {
+				t.LineComment = "never"
+				continue
+			}
+			t.Tag, t.Value = "!!timestamp", time.UnixMilli(millis).In(loc).Format(localTimeLayout)
+			t.LineComment = consts.LocZoneMap[server]
+		}
+	}
+	return nil
+}
+
+// decodeExistence converts the existence times of an entity back to epoch milliseconds.
+func decodeExistence(path string, node *yaml.Node) error {
+	existence := field(node, "existence")
+	if existence == nil || existence.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(existence.Content); i += 2 {
+		server, state := existence.Content[i].Value, existence.Content[i+1]
+		for _, name := range []string{"openTime", "closeTime"} {
+			t := field(state, name)
+			if t == nil || t.Kind != yaml.ScalarNode || t.Tag == "!!int" || t.Tag == "!!null" {
+				continue
+			}
+			parsed, err := gdutils.ParseServerTime(t.Value, server)
+			if err != nil {
+				return errors.Wrapf(err, "line %d: %s.existence.%s.%s", t.Line, path, server, name)
+			}
+			t.Tag, t.Style, t.Value = "!!int", 0, strconv.FormatInt(parsed.UnixMilli(), 10)
+		}
+	}
+	return nil
+}
+
+// annotateTimes converts the start and end times of a time range or activity to local times of server, and
+// notes an end time that never comes.
+func annotateTimes(node *yaml.Node, server string) error {
+	loc, locErr := gdutils.ServerLocation(server)
+	for _, name := range []string{"startTime", "endTime"} {
+		t := field(node, name)
+		if t == nil || t.Kind != yaml.ScalarNode || t.Tag == "!!null" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, t.Value)
+		if err != nil {
+			return err
+		}
+		if parsed.UnixMilli() == consts.FakeEndTimeMilli {
+			t.LineComment = "never"
+			continue
+		}
+		if locErr != nil {
+			continue
+		}
+		t.Tag, t.Value = "!!timestamp", parsed.In(loc).Format(localTimeLayout)
+		t.LineComment = consts.LocZoneMap[server]
+	}
+	return nil
+}
+
+// decodeTimes converts the start and end times of a time range or activity back to RFC3339.
+func decodeTimes(path string, node *yaml.Node, server string) error {
+	for _, name := range []string{"startTime", "endTime"} {
+		t := field(node, name)
+		if t == nil || t.Kind != yaml.ScalarNode || t.Tag == "!!null" {
+			continue
+		}
+		parsed, err := gdutils.ParseServerTime(t.Value, server)
+		if err != nil {
+			return errors.Wrapf(err, "line %d: %s.%s", t.Line, path, name)
+		}
+		t.Tag, t.Style, t.Value = "!!str", 0, parsed.Format(time.RFC3339Nano)
+	}
+	return nil
+}
+
+// annotateDropInfo names the item of a drop info.
+func annotateDropInfo(node *yaml.Node, items map[int]*models.Item) {
+	itemId := field(node, "itemId")
+	if itemId == nil {
+		return
+	}
+	if itemId.Tag == "!!null" {
+		if dropType := field(node, "dropType"); dropType != nil {
+			itemId.LineComment = "total of " + dropType.Value + " drops"
+		}
+		return
+	}
+	id, err := strconv.Atoi(itemId.Value)
+	if err != nil {
+		return
+	}
+	if item, ok := items[id]; ok {
+		itemId.LineComment = strings.TrimSpace(item.ArkItemID + " " + itemName(item))
+	} else {
+		itemId.LineComment = "unknown item"
+	}
+}
+
+// itemName returns the English name of item, or else the Chinese one.
+func itemName(item *models.Item) string {
+	var names map[string]string
+	if err := json.Unmarshal(item.Name, &names); err != nil {
+		return ""
+	}
+	if name := names["en"]; name != "" {
+		return name
+	}
+	return names["zh"]
+}
+
+// stageCode returns the English code of stage, or else the Chinese one.
+func stageCode(stage *models.Stage) string {
+	var codes map[string]string
+	if err := json.Unmarshal(stage.Code, &codes); err != nil {
+		return ""
+	}
+	if code := codes["en"]; code != "" {
+		return code
+	}
+	return codes["zh"]
+}
@@ -0,0 +1,168 @@
+package bundleyaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+)
+
+func testBundle(server string, start, end, activityEnd time.Time) *gamedata.RenderedObjects {
+	// keys in the order of the rendered JSON, which marshals existences from maps
+	existence := `{"` + server + `": {"closeTime": ` + jsonMillis(end) + `, "exist": true, "openTime": ` + jsonMillis(start) + `}}`
+	return &gamedata.RenderedObjects{
+		Zone: &models.Zone{
+			ArkZoneID: "act1side_zone1",
+			Name:      json.RawMessage(`{"en": "Side Story"}`),
+			Existence: json.RawMessage(existence),
+		},
+		Stages: []*models.Stage{{
+			ArkStageID: "act1side_01",
+			Code:       json.RawMessage(`{"en": "SS-1"}`),
+			Existence:  json.RawMessage(existence),
+		}},
+		DropInfosMap: map[string][]*models.DropInfo{
+			"act1side_01": {{Server: server, DropType: "NORMAL_DROP"}},
+		},
+		TimeRange: &models.TimeRange{Server: server, StartTime: &start, EndTime: &end},
+		Activity: &models.Activity{
+			StartTime: &start,
+			EndTime:   &activityEnd,
+			Name:      json.RawMessage(`{"en": "Side Story"}`),
+			Existence: json.RawMessage(`{"` + server + `": {"exist": true}}`),
+		},
+	}
+}
+
+func jsonMillis(t time.Time) string {
+	b, _ := json.Marshal(t.UnixMilli())
+	return string(b)
+}
+
+// canonical returns the JSON of rendered with its times in UTC, so that bundles of the same instants compare equal.
+func canonical(t *testing.T, rendered *gamedata.RenderedObjects) string {
+	t.Helper()
+	utc := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		u := t.UTC()
+		return &u
+	}
+	copied := *rendered
+	timeRange, activity := *rendered.TimeRange, *rendered.Activity
+	timeRange.StartTime, timeRange.EndTime = utc(timeRange.StartTime), utc(timeRange.EndTime)
+	activity.StartTime, activity.EndTime = utc(activity.StartTime), utc(activity.EndTime)
+	copied.TimeRange, copied.Activity = &timeRange, &activity
+
+	b, err := json.Marshal(&copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, b); err != nil {
+		t.Fatal(err)
+	}
+	return compact.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	never := time.UnixMilli(consts.FakeEndTimeMilli).UTC()
+	tests := []struct {
+		name                    string
+		server                  string
+		start, end, activityEnd time.Time
+		// local times expected in the YAML form
+		want []string
+	}{
+		{
+			name:   "spring forward",
+			server: "US",
+			// 03:00 PDT, right after 02:00 PST jumped to 03:00 PDT
+			start:       time.Date(2022, 3, 13, 10, 0, 0, 0, time.UTC),
+			end:         time.Date(2022, 3, 27, 11, 0, 0, 0, time.UTC),
+			activityEnd: time.Date(2022, 3, 27, 11, 0, 0, 0, time.UTC),
+			want:        []string{"startTime: 2022-03-13T03:00:00-07:00 # America/Los_Angeles", "endTime: 2022-03-27T04:00:00-07:00 # America/Los_Angeles"},
+		},
+		{
+			name:   "fall back",
+			server: "US",
+			// the second 01:30 of the day, in PST
+			start:       time.Date(2022, 11, 6, 9, 30, 0, 0, time.UTC),
+			end:         time.Date(2022, 11, 20, 12, 0, 0, 0, time.UTC),
+			activityEnd: time.Date(2022, 11, 20, 12, 0, 0, 0, time.UTC),
+			want:        []string{"startTime: 2022-11-06T01:30:00-08:00 # America/Los_Angeles", "endTime: 2022-11-20T04:00:00-08:00 # America/Los_Angeles"},
+		},
+		{
+			name:        "never ends",
+			server:      "CN",
+			start:       time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC),
+			end:         never,
+			activityEnd: never,
+			want:        []string{"startTime: 2022-05-01T16:00:00+08:00 # Asia/Shanghai", "endTime: \"3939-03-08T19:09:39Z\" # never", "closeTime: 62141368179000 # never"},
+		},
+		{
+			name:        "milliseconds",
+			server:      "JP",
+			start:       time.Date(2022, 5, 1, 7, 0, 0, 0, time.UTC),
+			end:         time.Date(2022, 5, 15, 18, 59, 59, 999e6, time.UTC),
+			activityEnd: time.Date(2022, 5, 15, 18, 59, 59, 999e6, time.UTC),
+			want:        []string{"endTime: 2022-05-16T03:59:59.999+09:00 # Asia/Tokyo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered := testBundle(tt.server, tt.start, tt.end, tt.activityEnd)
+
+			b, err := Encode(rendered, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(b), want) {
+					t.Errorf("YAML has no %q:\n%s", want, b)
+				}
+			}
+
+			decoded, err := Decode(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := canonical(t, decoded), canonical(t, rendered); got != want {
+				t.Errorf("decoded bundle differs:\n got %s\nwant %s", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeEditedTimes(t *testing.T) {
+	start := time.Date(2022, 11, 1, 11, 0, 0, 0, time.UTC)
+	end := time.Date(2022, 11, 15, 11, 0, 0, 0, time.UTC)
+	b, err := Encode(testBundle("US", start, end, end), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the end time is moved across the end of DST, written as a local time
+	edited := strings.Replace(string(b), "endTime: 2022-11-15T03:00:00-08:00", "endTime: 2022-11-20 04:00", 1)
+	if edited == string(b) {
+		t.Fatalf("no time range end time to edit in:\n%s", b)
+	}
+	decoded, err := Decode([]byte(edited))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2022, 11, 20, 12, 0, 0, 0, time.UTC); !decoded.TimeRange.EndTime.Equal(want) {
+		t.Errorf("time range ends at %v, want %v", decoded.TimeRange.EndTime, want)
+	}
+
+	invalid := strings.Replace(string(b), "endTime: 2022-11-15T03:00:00-08:00", "endTime: soon", 1)
+	if _, err := Decode([]byte(invalid)); err == nil || !strings.Contains(err.Error(), "timeRange.endTime") {
+		t.Errorf("err = %v, want one naming timeRange.endTime", err)
+	}
+}
@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+}
+
+// renderLoadTime simulates a value computation during render, e.g. building a map from the item seed.
+const renderLoadTime = 200 * time.Microsecond
+
+type benchValue struct {
+	ID     int
+	Name   string
+	Bounds []int
+}
+
+// globalMutexSet is the previous design of Set: one mutex guards every computation
+// of the set, and values are copied out through reflect. It is kept here as the
+// baseline of the benchmarks.
+type globalMutexSet[T any] struct {
+	m sync.Mutex
+	c *cache.Cache
+}
+
+func newGlobalMutexSet[T any]() *globalMutexSet[T] {
+	return &globalMutexSet[T]{c: cache.New(cache.NoExpiration, time.Minute*10)}
+}
+
+func (c *globalMutexSet[T]) Get(key string, dest *T) error {
+	result, ok := c.c.Get("bench:" + key)
+	if !ok {
+		return ErrNotFound
+	}
+	var r reflect.Value
+	if reflect.ValueOf(result).Kind() == reflect.Ptr {
+		r = reflect.ValueOf(result).Elem()
+	} else {
+		r = reflect.ValueOf(result)
+	}
+	reflect.ValueOf(dest).Elem().Set(r)
+	return nil
+}
+
+func (c *globalMutexSet[T]) MutexGetSet(key string, dest *T, valueFunc func() (*T, error), expire time.Duration, _ ...string) (bool, error) {
+	if err := c.Get(key, dest); err == nil {
+		return false, nil
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	if err := c.Get(key, dest); err == nil {
+		return true, nil
+	}
+	value, err := valueFunc()
+	if err != nil {
+		return true, err
+	}
+	c.c.Set("bench:"+key, *value, expire)
+	*dest = *value
+	return true, nil
+}
+
+type mutexGetSetter interface {
+	MutexGetSet(key string, dest *benchValue, valueFunc func() (*benchValue, error), expire time.Duration, tags ...string) (bool, error)
+}
+
+// benchmarkColdKeys has every goroutine ask for a fresh key per iteration, shared with a few
+// other goroutines, the way concurrent stage renders ask for per-stage derived values.
+func benchmarkColdKeys(b *testing.B, s mutexGetSetter) {
+	var next int64
+	b.SetParallelism(8)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			key := strconv.FormatInt(atomic.AddInt64(&next, 1)/4, 10)
+			var dest benchValue
+			_, err := s.MutexGetSet(key, &dest, func() (*benchValue, error) {
+				time.Sleep(renderLoadTime)
+				return &benchValue{ID: 1, Name: key}, nil
+			}, time.Hour)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMutexGetSetColdKeys(b *testing.B) {
+	UsePersistentStore(nil)
+	b.Run("global-mutex", func(b *testing.B) {
+		benchmarkColdKeys(b, newGlobalMutexSet[benchValue]())
+	})
+	b.Run("per-key", func(b *testing.B) {
+		benchmarkColdKeys(b, NewSet[benchValue]("bench"))
+	})
+}
+
+func benchmarkWarmGet(b *testing.B, get func(key string, dest *benchValue) error) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var dest benchValue
+		i := 0
+		for pb.Next() {
+			if err := get(strconv.Itoa(i%64), &dest); err != nil {
+				b.Fatal(err)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkGetWarm(b *testing.B) {
+	UsePersistentStore(nil)
+	value := benchValue{ID: 1, Name: "30012", Bounds: []int{0, 1}}
+
+	b.Run("reflect", func(b *testing.B) {
+		s := newGlobalMutexSet[benchValue]()
+		for i := 0; i < 64; i++ {
+			s.c.Set("bench:"+strconv.Itoa(i), value, time.Hour)
+		}
+		benchmarkWarmGet(b, s.Get)
+	})
+	b.Run("typed", func(b *testing.B) {
+		s := NewSet[benchValue]("bench")
+		for i := 0; i < 64; i++ {
+			s.Set(strconv.Itoa(i), value, time.Hour)
+		}
+		benchmarkWarmGet(b, func(key string, dest *benchValue) error {
+			return s.Get(key, dest)
+		})
+	})
+}
@@ -0,0 +1,288 @@
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/rs/zerolog/log"
+)
+
+// persistent is the disk store backing every Set and Singular, in addition to their
+// in-memory caches. It is nil unless UsePersistentStore has been called.
+var persistent *DiskStore
+
+// UsePersistentStore makes every Set and Singular read through to and write through
+// to store, so that cached values survive across CLI invocations. Passing nil disables
+// the persistent store.
+func UsePersistentStore(store *DiskStore) {
+	persistent = store
+}
+
+// PersistentStore returns the disk store set by UsePersistentStore, or nil.
+func PersistentStore() *DiskStore {
+	return persistent
+}
+
+// memoryExpiration converts a remaining time to live as returned by DiskStore.Load to the
+// expiration of the in-memory cache.
+func memoryExpiration(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return cache.NoExpiration
+	}
+	return ttl
+}
+
+// DiskStore stores cache entries as one JSON file per entry under a directory.
+// Entries written with a different version are treated as missing, which allows
+// invalidating everything cached by older builds by bumping the version.
+type DiskStore struct {
+	dir     string
+	version string
+}
+
+// DiskEntry is the on-disk representation of a cache entry.
+type DiskEntry struct {
+	Name      string          `json:"name"`
+	Version   string          `json:"version"`
+	CreatedAt time.Time       `json:"createdAt"`
+	ExpiresAt *time.Time      `json:"expiresAt,omitempty"`
+	Tags      []string        `json:"tags,omitempty"`
+	Value     json.RawMessage `json:"value"`
+
+	// Size is the size of the entry file in bytes; it is not stored.
+	Size int64 `json:"-"`
+}
+
+// Expired reports whether the entry has expired at t.
+func (e *DiskEntry) Expired(t time.Time) bool {
+	return e.ExpiresAt != nil && !t.Before(*e.ExpiresAt)
+}
+
+func NewDiskStore(dir, version string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskStore{
+		dir:     dir,
+		version: version,
+	}, nil
+}
+
+func (d *DiskStore) Dir() string {
+	return d.dir
+}
+
+func (d *DiskStore) Version() string {
+	return d.version
+}
+
+func (d *DiskStore) path(name string) string {
+	sum := sha1.Sum([]byte(name))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Load decodes the value of the named entry into dest, and returns the remaining
+// time to live of the entry, which is zero for entries that never expire, and its tags.
+// Missing, expired and version-mismatched entries are reported as ErrNotFound,
+// the latter two being removed from disk.
+func (d *DiskStore) Load(name string, dest any) (time.Duration, []string, error) {
+	entry, err := d.readEntry(d.path(name))
+	if err != nil {
+		return 0, nil, ErrNotFound
+	}
+	if entry.Name != name || entry.Version != d.version || entry.Expired(time.Now()) {
+		d.Delete(name)
+		return 0, nil, ErrNotFound
+	}
+	if err := json.Unmarshal(entry.Value, dest); err != nil {
+		log.Warn().Err(err).Str("name", name).Msg("failed to decode persistent cache entry; discarding")
+		d.Delete(name)
+		return 0, nil, ErrNotFound
+	}
+
+	if entry.ExpiresAt == nil {
+		return 0, entry.Tags, nil
+	}
+	return time.Until(*entry.ExpiresAt), entry.Tags, nil
+}
+
+// Store writes value as the named entry. An expire of zero or less means the entry never expires.
+func (d *DiskStore) Store(name string, value any, expire time.Duration, tags []string) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	entry := DiskEntry{
+		Name:      name,
+		Version:   d.version,
+		CreatedAt: now,
+		Tags:      tags,
+		Value:     b,
+	}
+	if expire > 0 {
+		expiresAt := now.Add(expire)
+		entry.ExpiresAt = &expiresAt
+	}
+
+	b, err = json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that concurrent readers never see a partial entry
+	tmp, err := os.CreateTemp(d.dir, ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), d.path(name))
+}
+
+func (d *DiskStore) Delete(name string) error {
+	err := os.Remove(d.path(name))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeletePrefix deletes every entry whose name starts with prefix, and returns the number
+// of deleted entries. An empty prefix deletes every entry.
+func (d *DiskStore) DeletePrefix(prefix string) (int, error) {
+	entries, err := d.Entries()
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name, prefix) {
+			continue
+		}
+		if err := d.Delete(entry.Name); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// DeleteTagged deletes every entry with a tag matched by tag, as described by InvalidateTag,
+// and returns the number of deleted entries.
+func (d *DiskStore) DeleteTagged(tag string) (int, error) {
+	entries, err := d.Entries()
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, entry := range entries {
+		for _, entryTag := range entry.Tags {
+			if !tagMatches(tag, entryTag) {
+				continue
+			}
+			if err := d.Delete(entry.Name); err != nil {
+				return deleted, err
+			}
+			deleted++
+			break
+		}
+	}
+	return deleted, nil
+}
+
+// Entries lists every entry in the store, including expired and version-mismatched ones.
+// Values are not decoded.
+func (d *DiskStore) Entries() ([]*DiskEntry, error) {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*DiskEntry, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		entry, err := d.readEntry(filepath.Join(d.dir, file.Name()))
+		if err != nil {
+			log.Warn().Err(err).Str("file", file.Name()).Msg("skipping unreadable persistent cache entry")
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (d *DiskStore) readEntry(filename string) (*DiskEntry, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var entry DiskEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, err
+	}
+	entry.Size = int64(len(b))
+	return &entry, nil
+}
+
+// Prune removes the entries of the store that Load would discard as of now, i.e. expired, version-mismatched
+// and unreadable ones, and temporary files left behind by interrupted writes. It returns the number of files
+// removed and their total size; a dry run only counts them.
+func (d *DiskStore) Prune(now time.Time, dryRun bool) (int, int64, error) {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	removed, size := 0, int64(0)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		filename := filepath.Join(d.dir, file.Name())
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(file.Name(), ".entry-"):
+			// a write in progress renames its file within moments
+			if now.Sub(info.ModTime()) < time.Hour {
+				continue
+			}
+		case strings.HasSuffix(file.Name(), ".json"):
+			entry, err := d.readEntry(filename)
+			if err == nil && entry.Version == d.version && !entry.Expired(now) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if !dryRun {
+			if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+				return removed, size, err
+			}
+		}
+		removed++
+		size += info.Size()
+	}
+	return removed, size, nil
+}
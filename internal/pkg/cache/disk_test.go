@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDiskStore(t *testing.T, version string) *DiskStore {
+	t.Helper()
+	store, err := NewDiskStore(t.TempDir(), version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestDiskStoreLoad(t *testing.T) {
+	store := newTestDiskStore(t, "v1")
+	if err := store.Store("forever", []int{1, 2}, 0, []string{"kind:a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Store("hour", "value", time.Hour, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Store("expired", "value", time.Nanosecond, nil); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	var ints []int
+	ttl, tags, err := store.Load("forever", &ints)
+	if err != nil || ttl != 0 || len(ints) != 2 || len(tags) != 1 || tags[0] != "kind:a" {
+		t.Errorf("forever: %v, ttl %v, tags %v, err %v", ints, ttl, tags, err)
+	}
+	var s string
+	if ttl, _, err := store.Load("hour", &s); err != nil || s != "value" || ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("hour: %q, ttl %v, err %v", s, ttl, err)
+	}
+	if _, _, err := store.Load("expired", &s); err != ErrNotFound {
+		t.Errorf("expired: err = %v, want ErrNotFound", err)
+	}
+	if _, _, err := store.Load("missing", &s); err != ErrNotFound {
+		t.Errorf("missing: err = %v, want ErrNotFound", err)
+	}
+	// a value of another type is discarded
+	if _, _, err := store.Load("hour", &ints); err != ErrNotFound {
+		t.Errorf("mistyped: err = %v, want ErrNotFound", err)
+	}
+	if _, _, err := store.Load("hour", &s); err != ErrNotFound {
+		t.Errorf("the mistyped entry is kept: err = %v", err)
+	}
+
+	// entries of another version are treated as missing
+	other := &DiskStore{dir: store.Dir(), version: "v2"}
+	if _, _, err := other.Load("forever", &ints); err != ErrNotFound {
+		t.Errorf("other version: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDiskStoreDelete(t *testing.T) {
+	store := newTestDiskStore(t, "v1")
+	entries := map[string][]string{
+		"stage:a":  {"stage:a"},
+		"stage:b":  {"stage:b"},
+		"stages":   {"stage:*"},
+		"zone:a":   {"zone:a"},
+		"untagged": nil,
+	}
+	for name, tags := range entries {
+		if err := store.Store(name, name, 0, tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := store.DeleteTagged("stage:a"); err != nil || n != 2 {
+		t.Errorf("DeleteTagged(stage:a) = %d, %v; want stage:a and stages", n, err)
+	}
+	if n, err := store.DeletePrefix("stage:"); err != nil || n != 1 {
+		t.Errorf("DeletePrefix(stage:) = %d, %v; want stage:b", n, err)
+	}
+	if err := store.Delete("zone:a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("zone:a"); err != nil {
+		t.Errorf("deleting a missing entry: %v", err)
+	}
+
+	left, err := store.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0].Name != "untagged" {
+		t.Errorf("entries left: %v, want untagged only", left)
+	}
+}
+
+func TestDiskStorePrune(t *testing.T) {
+	store := newTestDiskStore(t, "v2")
+	now := time.Now()
+	write := func(name string, content string, modTime time.Time) {
+		filename := filepath.Join(store.Dir(), name)
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filename, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Store("valid", 1, time.Hour, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Store("expiring", 1, time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+	write("old-version.json", `{"name": "old", "version": "v1", "value": 1}`, now)
+	write("unreadable.json", `{`, now)
+	write(".entry-recent", `{}`, now)
+	write(".entry-stale", `{}`, now.Add(-2*time.Hour))
+	write("other.txt", `kept`, now.Add(-2*time.Hour))
+
+	// an hour on, the expiring entry has expired too
+	later := now.Add(time.Hour - time.Second)
+	removed, _, err := store.Prune(later, true)
+	if err != nil || removed != 4 {
+		t.Errorf("dry run counts %d, %v; want 4", removed, err)
+	}
+	if files, _ := os.ReadDir(store.Dir()); len(files) != 7 {
+		t.Errorf("a dry run left %d files, want all 7", len(files))
+	}
+
+	removed, size, err := store.Prune(later, false)
+	if err != nil || removed != 4 || size <= 0 {
+		t.Errorf("Prune = %d, %d bytes, %v; want 4", removed, size, err)
+	}
+	files, _ := os.ReadDir(store.Dir())
+	names := make(map[string]bool)
+	for _, file := range files {
+		names[file.Name()] = true
+	}
+	if len(names) != 3 || !names[".entry-recent"] || !names["other.txt"] {
+		t.Errorf("files left: %v, want the valid entry, .entry-recent and other.txt", names)
+	}
+	var v int
+	if _, _, err := store.Load("valid", &v); err != nil {
+		t.Errorf("the valid entry is pruned: %v", err)
+	}
+}
+
+// TestPersistentStore reads entries written by an earlier invocation, which is simulated by new sets and
+// singulars of the same keys, back from disk.
+func TestPersistentStore(t *testing.T) {
+	dir := t.TempDir()
+	use := func(version string) {
+		store, err := NewDiskStore(dir, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		UsePersistentStore(store)
+	}
+	use("v1")
+	t.Cleanup(func() { UsePersistentStore(nil) })
+
+	stages := NewSet[[]int]("test-persisted-stages", WithKeyTags(func(key string) []string { return []string{"test-persisted-stage:" + key} }))
+	all := NewSingular[string]("test-persisted-all", WithTags("test-persisted-stage:*"))
+	if err := stages.Set("a", []int{1, 2}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := stages.Set("b", []int{3}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := all.Set("all", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	stages = NewSet[[]int]("test-persisted-stages")
+	all = NewSingular[string]("test-persisted-all")
+	var ints []int
+	if err := stages.Get("a", &ints); err != nil || len(ints) != 2 {
+		t.Errorf("stage a = %v, %v; want it read from disk", ints, err)
+	}
+	var s string
+	if err := all.Get(&s); err != nil || s != "all" {
+		t.Errorf("all = %q, %v; want it read from disk", s, err)
+	}
+	if stats := stages.Stats(); stats.Hits != 1 || stats.Misses != 0 {
+		t.Errorf("stats %+v, want a read from disk counted as a hit", stats)
+	}
+
+	// the tags of entries read from disk are indexed again
+	if _, err := InvalidateTag("test-persisted-stage:a"); err != nil {
+		t.Fatal(err)
+	}
+	stages = NewSet[[]int]("test-persisted-stages")
+	all = NewSingular[string]("test-persisted-all")
+	if err := stages.Get("a", &ints); err != ErrNotFound {
+		t.Errorf("stage a is still cached: %v, %v", ints, err)
+	}
+	if err := all.Get(&s); err != ErrNotFound {
+		t.Errorf("all is still cached: %q, %v", s, err)
+	}
+	if err := stages.Get("b", &ints); err != nil || len(ints) != 1 {
+		t.Errorf("stage b = %v, %v; want it kept", ints, err)
+	}
+
+	// a new version misses every entry of the previous one
+	use("v2")
+	stages = NewSet[[]int]("test-persisted-stages")
+	if err := stages.Get("b", &ints); err != ErrNotFound {
+		t.Errorf("stage b of v1 is read by v2: %v, %v", ints, err)
+	}
+	if entries, err := PersistentStore().Entries(); err != nil || len(entries) != 0 {
+		t.Errorf("entries left: %d, %v; want the v1 entry removed once missed", len(entries), err)
+	}
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// call is an in-flight or completed computation of a flight.
+type call[T any] struct {
+	wg sync.WaitGroup
+
+	val T
+	err error
+}
+
+// flight deduplicates concurrent computations per key, in the style of singleflight:
+// while a computation for a key is in flight, callers asking for the same key wait
+// for it and share its result, while callers for other keys proceed independently.
+type flight[T any] struct {
+	m     sync.Mutex
+	calls map[string]*call[T]
+}
+
+// do executes fn for key, unless a computation for key is already in flight, in which
+// case it waits for that computation and returns its result. shared reports whether
+// the result came from another caller's computation. A panic of fn is returned as an
+// error to the caller and to every waiter alike.
+func (f *flight[T]) do(key string, fn func() (T, error)) (val T, err error, shared bool) {
+	f.m.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]*call[T])
+	}
+	if c, ok := f.calls[key]; ok {
+		f.m.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := &call[T]{}
+	c.wg.Add(1)
+	f.calls[key] = c
+	f.m.Unlock()
+
+	defer func() {
+		f.m.Lock()
+		delete(f.calls, key)
+		f.m.Unlock()
+		c.wg.Done()
+	}()
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				var zero T
+				c.val, c.err = zero, errors.Errorf("computing %s panicked: %v", key, r)
+			}
+		}()
+		c.val, c.err = fn()
+	}()
+
+	return c.val, c.err, false
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestFlightSharesComputation(t *testing.T) {
+	var f flight[int]
+	var calls int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	const callers = 8
+	var wg sync.WaitGroup
+	results := make([]int, callers)
+	shared := make([]bool, callers)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0], _, shared[0] = f.do("key", func() (int, error) {
+			atomic.AddInt32(&calls, 1)
+			close(started)
+			<-release
+			return 42, nil
+		})
+	}()
+	<-started
+	for i := 1; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, shared[i] = f.do("key", func() (int, error) {
+				atomic.AddInt32(&calls, 1)
+				return -1, nil
+			})
+		}(i)
+	}
+	// the waiters are blocked on the computation in flight
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("computed %d times, want once", calls)
+	}
+	for i, result := range results {
+		if result != 42 {
+			t.Errorf("caller %d got %d, want 42", i, result)
+		}
+	}
+	if shared[0] {
+		t.Error("the computing caller reports a shared result")
+	}
+
+	// once done, the key is computed anew
+	v, err, _ := f.do("key", func() (int, error) { return 7, nil })
+	if v != 7 || err != nil {
+		t.Errorf("recomputed %d, %v; want 7", v, err)
+	}
+}
+
+func TestFlightKeysAreIndependent(t *testing.T) {
+	var f flight[string]
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go f.do("slow", func() (string, error) {
+		close(started)
+		<-release
+		return "slow", nil
+	})
+	<-started
+	defer close(release)
+
+	done := make(chan string)
+	go func() {
+		v, _, _ := f.do("fast", func() (string, error) { return "fast", nil })
+		done <- v
+	}()
+	select {
+	case v := <-done:
+		if v != "fast" {
+			t.Errorf("got %q, want fast", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a computation of another key blocks")
+	}
+}
+
+func TestFlightError(t *testing.T) {
+	var f flight[int]
+	want := errors.New("unavailable")
+	if _, err, _ := f.do("key", func() (int, error) { return 0, want }); err != want {
+		t.Errorf("err = %v, want %v", err, want)
+	}
+}
+
+func TestFlightPanic(t *testing.T) {
+	var f flight[*int]
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	errs := make(chan error, 2)
+	vals := make(chan *int, 2)
+	go func() {
+		v, err, _ := f.do("key", func() (*int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+		vals <- v
+		errs <- err
+	}()
+	<-started
+	go func() {
+		v, err, _ := f.do("key", func() (*int, error) { return new(int), nil })
+		vals <- v
+		errs <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		if v := <-vals; v != nil {
+			t.Errorf("got value %v of a panicking computation", v)
+		}
+		if err := <-errs; err == nil || !strings.Contains(err.Error(), "computing key panicked: boom") {
+			t.Errorf("err = %v, want the panic", err)
+		}
+	}
+
+	// a panic leaves no call in flight behind
+	v, err, _ := f.do("key", func() (*int, error) { n := 1; return &n, nil })
+	if err != nil || v == nil || *v != 1 {
+		t.Errorf("recomputed %v, %v; want 1", v, err)
+	}
+}
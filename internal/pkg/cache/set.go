@@ -1,27 +1,31 @@
 package cache
 
 import (
-	"reflect"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/patrickmn/go-cache"
 	"github.com/rs/zerolog/log"
 )
 
-func NewSet[T any](prefix string) *Set[T] {
+func NewSet[T any](prefix string, opts ...Option) *Set[T] {
 	return &Set[T]{
-		prefix: prefix + ":",
-		c:      cache.New(cache.NoExpiration, time.Minute*10),
+		prefix:  prefix + ":",
+		options: newOptions(opts),
+		c:       cache.New(cache.NoExpiration, time.Minute*10),
 	}
 }
 
 type Set[T any] struct {
-	// m is a mutex for MutexGetSet for concurrent prevention
-	m sync.Mutex
+	// flight deduplicates concurrent MutexGetSet computations of the same key
+	flight flight[T]
 
-	prefix string
+	counters counters
 
+	prefix  string
+	options options
+
+	// c holds values of type T, never *T, so that they can be type-asserted back
 	c *cache.Cache
 }
 
@@ -30,80 +34,145 @@ func (c *Set[T]) key(key string) string {
 }
 
 func (c *Set[T]) Get(key string, dest *T) error {
-	key = c.key(key)
-	result, ok := c.c.Get(key)
-	if !ok {
-		if l := log.Trace(); l.Enabled() {
-			l.Str("key", key).Msg("cache entry not found")
-		}
-		return ErrNotFound
+	if value, ok := c.getFromMemory(key); ok {
+		*dest = value
+		return nil
 	}
 
-	// copy value to dest
-	var r reflect.Value
-	if reflect.ValueOf(result).Kind() == reflect.Ptr {
-		r = reflect.ValueOf(result).Elem()
-	} else {
-		r = reflect.ValueOf(result)
+	value, err := c.get(c.key(key))
+	if err != nil {
+		return err
 	}
-	reflect.ValueOf(dest).Elem().Set(r)
+	*dest = value
 	return nil
 }
 
-func (c *Set[T]) Set(key string, value T, expire time.Duration) error {
+// getFromMemory is the allocation-free fast path of lookups that hit the in-memory cache.
+// It takes the key without the prefix.
+func (c *Set[T]) getFromMemory(key string) (T, bool) {
+	if result, ok := c.c.Get(c.prefix + key); ok {
+		c.counters.hit()
+		return result.(T), true
+	}
+	var zero T
+	return zero, false
+}
+
+func (c *Set[T]) get(key string) (T, error) {
+	if value, ok := c.lookup(key); ok {
+		c.counters.hit()
+		return value, nil
+	}
+
+	c.counters.miss()
+	if l := log.Trace(); l.Enabled() {
+		l.Str("key", key).Msg("cache entry not found")
+	}
+	var zero T
+	return zero, ErrNotFound
+}
+
+// lookup finds key in memory, then in the persistent store, without touching the counters.
+func (c *Set[T]) lookup(key string) (T, bool) {
+	if result, ok := c.c.Get(key); ok {
+		return result.(T), true
+	}
+
+	if persistent != nil {
+		var value T
+		if ttl, tags, err := persistent.Load(key, &value); err == nil {
+			c.c.Set(key, value, memoryExpiration(ttl))
+			indexTags(key, tags, c.deleteFromMemory(key))
+			return value, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// Set sets value to cache. The entry is tagged with tags, in addition to the tags configured
+// for the set.
+func (c *Set[T]) Set(key string, value T, expire time.Duration, tags ...string) error {
+	return c.set(key, value, expire, tags)
+}
+
+// set takes the key without the prefix.
+func (c *Set[T]) set(key string, value T, expire time.Duration, extraTags []string) error {
+	tags := c.tags(key, extraTags)
 	key = c.key(key)
 	if l := log.Trace(); l.Enabled() {
-		l.Str("key", key).Msg("setting value to cache")
+		l.Str("key", key).Strs("tags", tags).Msg("setting value to cache")
 	}
 	c.c.Set(key, value, expire)
+	indexTags(key, tags, c.deleteFromMemory(key))
+	if persistent != nil {
+		if err := persistent.Store(key, value, expire, tags); err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("failed to write value to persistent cache")
+		}
+	}
 	return nil
 }
 
-// MutexGetSet gets value from cache and writes to dest, or if the key does not exists, it executes valueFunc
-// to get cache value if the key still not exists when serially dispatched, sets value to cache and
-// writes value to dest.
-// The first return value means whether the value is got from cache or not. True means calculated; False means got from cache.
-func (c *Set[T]) MutexGetSet(key string, dest *T, valueFunc func() (*T, error), expire time.Duration) (bool, error) {
-	err := c.Get(key, dest)
-	if err == nil {
-		return false, nil
+func (c *Set[T]) tags(key string, extraTags []string) []string {
+	tags := make([]string, 0, len(c.options.tags)+len(extraTags))
+	tags = append(tags, c.options.tags...)
+	if c.options.keyTags != nil {
+		tags = append(tags, c.options.keyTags(key)...)
 	}
-	// onwards, cache key does not exist
-
-	return true, c.slowMutexGetSet(key, dest, valueFunc, expire)
+	return append(tags, extraTags...)
 }
 
-func (c *Set[T]) slowMutexGetSet(key string, dest *T, valueFunc func() (*T, error), expire time.Duration) error {
-	c.m.Lock()
-	defer c.m.Unlock()
-	err := c.Get(key, dest)
-
-	if err == nil {
-		return nil
+func (c *Set[T]) deleteFromMemory(key string) func() {
+	return func() {
+		c.c.Delete(key)
 	}
+}
 
-	value, err := valueFunc()
-	if err != nil {
-		log.Error().Err(err).Str("key", key).Msg("failed to get value from valueFunc() in MutexGetSet")
-		return err
+// MutexGetSet gets value from cache and writes to dest, or if the key does not exists, it executes valueFunc
+// to get cache value, sets value to cache and writes value to dest. Concurrent calls for the same key share
+// a single execution of valueFunc, while calls for different keys do not block each other.
+// The first return value means whether the value is got from cache or not. True means calculated; False means got from cache.
+func (c *Set[T]) MutexGetSet(key string, dest *T, valueFunc func() (*T, error), expire time.Duration, tags ...string) (bool, error) {
+	if value, ok := c.getFromMemory(key); ok {
+		*dest = value
+		return false, nil
 	}
 
-	err = c.Set(key, *value, expire)
-	if err != nil {
-		log.Error().Err(err).Str("key", key).Msg("failed to set value to cache in MutexGetSet")
-		return err
+	fullKey := c.key(key)
+	if value, err := c.get(fullKey); err == nil {
+		*dest = value
+		return false, nil
 	}
+	// onwards, cache key does not exist
+
+	value, err, _ := c.flight.do(fullKey, func() (T, error) {
+		// the value might have been set by a computation that finished since our lookup
+		if value, ok := c.lookup(fullKey); ok {
+			return value, nil
+		}
 
-	// copy value to dest
-	var r reflect.Value
-	if reflect.ValueOf(value).Kind() == reflect.Ptr {
-		r = reflect.ValueOf(value).Elem()
-	} else {
-		r = reflect.ValueOf(value)
+		start := time.Now()
+		value, err := valueFunc()
+		c.counters.load(time.Since(start))
+		if err != nil {
+			log.Error().Err(err).Str("key", fullKey).Msg("failed to get value from valueFunc() in MutexGetSet")
+			var zero T
+			return zero, err
+		}
+
+		if err := c.set(key, *value, expire, tags); err != nil {
+			log.Error().Err(err).Str("key", fullKey).Msg("failed to set value to cache in MutexGetSet")
+			var zero T
+			return zero, err
+		}
+		return *value, nil
+	})
+	if err != nil {
+		return true, err
 	}
-	reflect.ValueOf(dest).Elem().Set(r)
 
-	return nil
+	*dest = value
+	return true, nil
 }
 
 func (c *Set[T]) Delete(key string) error {
@@ -112,11 +181,25 @@ func (c *Set[T]) Delete(key string) error {
 		l.Str("key", key).Msg("deleting value from cache")
 	}
 	c.c.Delete(key)
+	unindexKey(key)
+	if persistent != nil {
+		return persistent.Delete(key)
+	}
 
 	return nil
 }
 
 func (c *Set[T]) Flush() error {
 	c.c.Flush()
+	unindex(func(key string) bool { return strings.HasPrefix(key, c.prefix) })
+	if persistent != nil {
+		_, err := persistent.DeletePrefix(c.prefix)
+		return err
+	}
 	return nil
 }
+
+// Stats returns the hit, miss and load counters of the set.
+func (c *Set[T]) Stats() Stats {
+	return c.counters.snapshot()
+}
@@ -1,101 +1,137 @@
 package cache
 
 import (
-	"reflect"
-	"sync"
 	"time"
 
 	"github.com/patrickmn/go-cache"
 	"github.com/rs/zerolog/log"
 )
 
-func NewSingular[T any](key string) *Singular[T] {
+func NewSingular[T any](key string, opts ...Option) *Singular[T] {
 	return &Singular[T]{
-		key: key,
-		c:   cache.New(cache.NoExpiration, time.Minute*10),
+		key:     key,
+		options: newOptions(opts),
+		c:       cache.New(cache.NoExpiration, time.Minute*10),
 	}
 }
 
 type Singular[T any] struct {
-	// m is a mutex for MutexGetSet for concurrent prevention
-	m sync.Mutex
+	// flight deduplicates concurrent MutexGetSet computations
+	flight flight[T]
 
-	key string
+	counters counters
 
+	key     string
+	options options
+
+	// c holds a value of type T, never *T, so that it can be type-asserted back
 	c *cache.Cache
 }
 
 func (c *Singular[T]) Get(dest *T) error {
-	result, ok := c.c.Get(c.key)
-	if !ok {
-		return ErrNotFound
-	}
-	// copy value to dest
-	var r reflect.Value
-	if reflect.ValueOf(result).Kind() == reflect.Ptr {
-		r = reflect.ValueOf(result).Elem()
-	} else {
-		r = reflect.ValueOf(result)
+	value, err := c.get()
+	if err != nil {
+		return err
 	}
-	reflect.ValueOf(dest).Elem().Set(r)
-
+	*dest = value
 	return nil
 }
 
-func (c *Singular[T]) Set(value T, expire time.Duration) error {
-	c.c.Set(c.key, value, expire)
-	return nil
+func (c *Singular[T]) get() (T, error) {
+	if value, ok := c.lookup(); ok {
+		c.counters.hit()
+		return value, nil
+	}
+
+	c.counters.miss()
+	var zero T
+	return zero, ErrNotFound
 }
 
-// MutexGetSet gets value from cache and writes to dest, or if the key does not exists, it executes valueFunc
-// to get cache value if the key still not exists when serially dispatched, sets value to cache and
-// writes value to dest.
-// The first return value means whether the value is got from cache or not. True means calculated; False means got from cache.
-func (c *Singular[T]) MutexGetSet(dest *T, valueFunc func() (T, error), expire time.Duration) error {
-	err := c.Get(dest)
-	if err == nil {
-		return nil
+// lookup finds the value in memory, then in the persistent store, without touching the counters.
+func (c *Singular[T]) lookup() (T, bool) {
+	if result, ok := c.c.Get(c.key); ok {
+		return result.(T), true
 	}
-	// onwards, cache key does not exist
 
-	return c.slowMutexGetSet(dest, valueFunc, expire)
+	if persistent != nil {
+		var value T
+		if ttl, tags, err := persistent.Load(c.key, &value); err == nil {
+			c.c.Set(c.key, value, memoryExpiration(ttl))
+			indexTags(c.key, tags, c.deleteFromMemory)
+			return value, true
+		}
+	}
+	var zero T
+	return zero, false
 }
 
-func (c *Singular[T]) slowMutexGetSet(dest *T, valueFunc func() (T, error), expire time.Duration) error {
-	c.m.Lock()
-	defer c.m.Unlock()
-	err := c.Get(dest)
-
-	if err == nil {
-		return nil
+// Set sets value to cache. The entry is tagged with tags, in addition to the tags configured
+// for the singular.
+func (c *Singular[T]) Set(value T, expire time.Duration, tags ...string) error {
+	tags = append(append([]string{}, c.options.tags...), tags...)
+	c.c.Set(c.key, value, expire)
+	indexTags(c.key, tags, c.deleteFromMemory)
+	if persistent != nil {
+		if err := persistent.Store(c.key, value, expire, tags); err != nil {
+			log.Warn().Err(err).Str("key", c.key).Msg("failed to write value to persistent cache")
+		}
 	}
+	return nil
+}
 
-	value, err := valueFunc()
-	if err != nil {
-		log.Error().Err(err).Str("key", c.key).Msg("failed to get value from valueFunc() in MutexGetSet")
-		return err
+// MutexGetSet gets value from cache and writes to dest, or if the key does not exists, it executes valueFunc
+// to get cache value, sets value to cache and writes value to dest. Concurrent calls share a single
+// execution of valueFunc.
+func (c *Singular[T]) MutexGetSet(dest *T, valueFunc func() (T, error), expire time.Duration, tags ...string) error {
+	if value, err := c.get(); err == nil {
+		*dest = value
+		return nil
 	}
+	// onwards, cache key does not exist
 
-	err = c.Set(value, expire)
+	value, err, _ := c.flight.do(c.key, func() (T, error) {
+		// the value might have been set by a computation that finished since our lookup
+		if value, ok := c.lookup(); ok {
+			return value, nil
+		}
+
+		start := time.Now()
+		value, err := valueFunc()
+		c.counters.load(time.Since(start))
+		if err != nil {
+			log.Error().Err(err).Str("key", c.key).Msg("failed to get value from valueFunc() in MutexGetSet")
+			return value, err
+		}
+
+		if err := c.Set(value, expire, tags...); err != nil {
+			log.Error().Err(err).Str("key", c.key).Msg("failed to set value to cache in MutexGetSet")
+			return value, err
+		}
+		return value, nil
+	})
 	if err != nil {
-		log.Error().Err(err).Str("key", c.key).Msg("failed to set value to cache in MutexGetSet")
 		return err
 	}
 
-	// copy value to dest
-	// copy value to dest
-	var r reflect.Value
-	if reflect.ValueOf(value).Kind() == reflect.Ptr {
-		r = reflect.ValueOf(value).Elem()
-	} else {
-		r = reflect.ValueOf(value)
-	}
-	reflect.ValueOf(dest).Elem().Set(r)
-
+	*dest = value
 	return nil
 }
 
+func (c *Singular[T]) deleteFromMemory() {
+	c.c.Delete(c.key)
+}
+
 func (c *Singular[T]) Delete() error {
-	c.c.Flush()
+	c.deleteFromMemory()
+	unindexKey(c.key)
+	if persistent != nil {
+		return persistent.Delete(c.key)
+	}
 	return nil
 }
+
+// Stats returns the hit, miss and load counters of the singular.
+func (c *Singular[T]) Stats() Stats {
+	return c.counters.snapshot()
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the counters of a Set or a Singular since it was created.
+type Stats struct {
+	// Hits is the number of lookups served from the cache.
+	Hits uint64
+	// Misses is the number of lookups that found no value.
+	Misses uint64
+	// Loads is the number of values computed by MutexGetSet. Callers that shared a
+	// computation in flight are not counted again.
+	Loads uint64
+	// LoadTime is the total time spent computing values in MutexGetSet.
+	LoadTime time.Duration
+}
+
+type counters struct {
+	hits     uint64
+	misses   uint64
+	loads    uint64
+	loadTime int64
+}
+
+func (c *counters) hit() {
+	atomic.AddUint64(&c.hits, 1)
+}
+
+func (c *counters) miss() {
+	atomic.AddUint64(&c.misses, 1)
+}
+
+func (c *counters) load(took time.Duration) {
+	atomic.AddUint64(&c.loads, 1)
+	atomic.AddInt64(&c.loadTime, int64(took))
+}
+
+func (c *counters) snapshot() Stats {
+	return Stats{
+		Hits:     atomic.LoadUint64(&c.hits),
+		Misses:   atomic.LoadUint64(&c.misses),
+		Loads:    atomic.LoadUint64(&c.loads),
+		LoadTime: time.Duration(atomic.LoadInt64(&c.loadTime)),
+	}
+}
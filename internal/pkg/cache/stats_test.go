@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetStats(t *testing.T) {
+	set := NewSet[int]("test-stats")
+	var v int
+
+	if err := set.Get("a", &v); err != ErrNotFound {
+		t.Fatalf("Get() before Set() error = %v, want ErrNotFound", err)
+	}
+	set.Set("a", 1, time.Hour)
+	// each in-memory hit is counted once
+	for i := 0; i < 3; i++ {
+		if err := set.Get("a", &v); err != nil || v != 1 {
+			t.Fatalf("Get() = %d, %v; want 1", v, err)
+		}
+	}
+	if stats := set.Stats(); stats.Hits != 3 || stats.Misses != 1 || stats.Loads != 0 {
+		t.Errorf("stats %+v, want 3 hits and 1 miss", stats)
+	}
+
+	load := func() (*int, error) {
+		time.Sleep(10 * time.Millisecond)
+		v := 2
+		return &v, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var v int
+			set.MutexGetSet("b", &v, load, time.Hour)
+		}()
+	}
+	wg.Wait()
+	if calculated, err := set.MutexGetSet("b", &v, load, time.Hour); err != nil || calculated || v != 2 {
+		t.Errorf("MutexGetSet() = %d, calculated %v, %v; want 2 from the cache", v, calculated, err)
+	}
+
+	// the concurrent computations of b are shared, and each lookup counts once, as a hit or a miss
+	stats := set.Stats()
+	if stats.Hits+stats.Misses != 10 || stats.Misses < 2 || stats.Loads != 1 || stats.LoadTime < 10*time.Millisecond {
+		t.Errorf("stats %+v, want 10 lookups and 1 load of at least 10ms", stats)
+	}
+}
+
+func TestSingularStats(t *testing.T) {
+	singular := NewSingular[string]("test-stats-singular")
+	var v string
+
+	if err := singular.Get(&v); err != ErrNotFound {
+		t.Fatalf("Get() before Set() error = %v, want ErrNotFound", err)
+	}
+	if err := singular.MutexGetSet(&v, func() (string, error) { return "a", nil }, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := singular.Get(&v); err != nil || v != "a" {
+		t.Fatalf("Get() = %q, %v; want a", v, err)
+	}
+
+	if stats := singular.Stats(); stats.Hits != 1 || stats.Misses != 2 || stats.Loads != 1 {
+		t.Errorf("stats %+v, want 1 hit, 2 misses and 1 load", stats)
+	}
+}
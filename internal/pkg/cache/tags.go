@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+)
+
+// TagWildcard, as the value part of a tag like `stage:*`, marks an entry as depending on every
+// entity of a kind. Invalidating any tag of that kind invalidates the entry too.
+const TagWildcard = "*"
+
+// Option configures a Set or a Singular.
+type Option func(o *options)
+
+type options struct {
+	tags    []string
+	keyTags func(key string) []string
+}
+
+// WithTags attaches tags to every entry of the cache.
+func WithTags(tags ...string) Option {
+	return func(o *options) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// WithKeyTags derives tags for each entry of a Set from its key, which is given without the prefix.
+func WithKeyTags(fn func(key string) []string) Option {
+	return func(o *options) {
+		o.keyTags = fn
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// tagIndex maps a tag to the entries tagged with it, keyed by their full keys, with a
+// function deleting the entry from its cache.
+var tagIndex = struct {
+	sync.Mutex
+	entries map[string]map[string]func()
+}{
+	entries: make(map[string]map[string]func()),
+}
+
+func indexTags(fullKey string, tags []string, deleteFn func()) {
+	if len(tags) == 0 {
+		return
+	}
+	tagIndex.Lock()
+	defer tagIndex.Unlock()
+	for _, tag := range tags {
+		if tagIndex.entries[tag] == nil {
+			tagIndex.entries[tag] = make(map[string]func())
+		}
+		tagIndex.entries[tag][fullKey] = deleteFn
+	}
+}
+
+// unindex removes the entries whose full keys match from the index, along with the tags left without entries.
+func unindex(match func(fullKey string) bool) {
+	tagIndex.Lock()
+	defer tagIndex.Unlock()
+	for tag, entries := range tagIndex.entries {
+		for fullKey := range entries {
+			if match(fullKey) {
+				delete(entries, fullKey)
+			}
+		}
+		if len(entries) == 0 {
+			delete(tagIndex.entries, tag)
+		}
+	}
+}
+
+func unindexKey(fullKey string) {
+	unindex(func(key string) bool { return key == fullKey })
+}
+
+// tagMatches reports whether invalidating tag invalidates entries tagged with entryTag.
+func tagMatches(tag, entryTag string) bool {
+	if tag == entryTag {
+		return true
+	}
+	kind, value, ok := strings.Cut(tag, ":")
+	if !ok {
+		return false
+	}
+	entryKind, entryValue, ok := strings.Cut(entryTag, ":")
+	if !ok || kind != entryKind {
+		return false
+	}
+	return value == TagWildcard || entryValue == TagWildcard
+}
+
+// InvalidateTag deletes every entry of every Set and Singular that is tagged with tag, from
+// memory and from the persistent store. Tags have the form `kind:value`, e.g. `server:CN`;
+// a value of TagWildcard matches every value of the kind, on either side.
+// It returns the number of in-memory entries deleted.
+func InvalidateTag(tag string) (int, error) {
+	deleteFns := make(map[string]func())
+
+	tagIndex.Lock()
+	for entryTag, entries := range tagIndex.entries {
+		if !tagMatches(tag, entryTag) {
+			continue
+		}
+		for fullKey, deleteFn := range entries {
+			deleteFns[fullKey] = deleteFn
+		}
+		delete(tagIndex.entries, entryTag)
+	}
+	tagIndex.Unlock()
+
+	for _, deleteFn := range deleteFns {
+		deleteFn()
+	}
+
+	if persistent != nil {
+		if _, err := persistent.DeleteTagged(tag); err != nil {
+			return len(deleteFns), err
+		}
+	}
+	return len(deleteFns), nil
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// indexed reports whether fullKey is in the tag index under tag.
+func indexed(tag, fullKey string) bool {
+	tagIndex.Lock()
+	defer tagIndex.Unlock()
+	_, ok := tagIndex.entries[tag][fullKey]
+	return ok
+}
+
+func TestTagMatches(t *testing.T) {
+	tests := []struct {
+		tag, entryTag string
+		want          bool
+	}{
+		{"stage:main_01-07", "stage:main_01-07", true},
+		{"stage:main_01-07", "stage:main_01-08", false},
+		{"stage:*", "stage:main_01-07", true},
+		{"stage:main_01-07", "stage:*", true},
+		{"stage:*", "zone:*", false},
+		{"stage:main_01-07", "zone:main_01-07", false},
+		{"items", "items", true},
+		{"items", "items:*", false},
+	}
+	for _, tt := range tests {
+		if got := tagMatches(tt.tag, tt.entryTag); got != tt.want {
+			t.Errorf("tagMatches(%q, %q) = %v, want %v", tt.tag, tt.entryTag, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidateTag(t *testing.T) {
+	stages := NewSet[string]("test-stages", WithKeyTags(func(key string) []string { return []string{"test-stage:" + key} }))
+	all := NewSingular[[]string]("test-all-stages", WithTags("test-stage:*"))
+	stages.Set("a", "A", time.Hour)
+	stages.Set("b", "B", time.Hour)
+	all.Set([]string{"A", "B"}, time.Hour)
+
+	n, err := InvalidateTag("test-stage:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("invalidated %d entries, want stage a and the list of all stages", n)
+	}
+	var v string
+	if err := stages.Get("a", &v); err != ErrNotFound {
+		t.Errorf("stage a is still cached: %q, %v", v, err)
+	}
+	if err := stages.Get("b", &v); err != nil || v != "B" {
+		t.Errorf("stage b = %q, %v; want it kept", v, err)
+	}
+	var list []string
+	if err := all.Get(&list); err != ErrNotFound {
+		t.Errorf("the list of all stages is still cached: %v, %v", list, err)
+	}
+
+	if n, _ := InvalidateTag("test-stage:*"); n != 1 {
+		t.Errorf("invalidated %d entries by the wildcard, want stage b", n)
+	}
+}
+
+func TestDeleteUnindexesTags(t *testing.T) {
+	set := NewSet[int]("test-unindex", WithTags("test-kind:x"))
+	singular := NewSingular[int]("test-unindex-singular", WithTags("test-kind:x"))
+	set.Set("a", 1, time.Hour)
+	set.Set("b", 2, time.Hour)
+	singular.Set(3, time.Hour)
+
+	if err := set.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if indexed("test-kind:x", "test-unindex:a") {
+		t.Error("a deleted entry of a set is left in the tag index")
+	}
+	if !indexed("test-kind:x", "test-unindex:b") {
+		t.Error("deleting an entry of a set unindexed another")
+	}
+
+	if err := singular.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if indexed("test-kind:x", "test-unindex-singular") {
+		t.Error("a deleted singular is left in the tag index")
+	}
+
+	if err := set.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	tagIndex.Lock()
+	_, ok := tagIndex.entries["test-kind:x"]
+	tagIndex.Unlock()
+	if ok {
+		t.Error("a tag is left in the index after flushing its entries")
+	}
+
+	// a flushed entry set again is invalidated by its tags, once
+	set.Set("b", 2, time.Hour)
+	if n, _ := InvalidateTag("test-kind:x"); n != 1 {
+		t.Errorf("invalidated %d entries, want 1", n)
+	}
+}
@@ -10,12 +10,32 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/pkg/audit"
+	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
 )
 
+// KnownEndpoints are the endpoints soracli posts to that the admin api is known to serve. The others are
+// only served by soracli mock-server so far, and are posted to only once allowed with
+// AllowUnverifiedEndpoints.
+var KnownEndpoints = map[string]bool{
+	"/save":  true,
+	"/purge": true,
+}
+
+var ErrUnverifiedEndpoint = errors.New("the admin api is not known to serve this endpoint; pass --unverified-endpoints to post to it anyway, e.g. to soracli mock-server")
+
 type Penguin struct {
 	baseUrl string
 	token   string
 	client  *http.Client
+	// unverified allows posting to endpoints other than KnownEndpoints
+	unverified bool
+
+	auditLog *audit.Log
+	// auditInfo holds the fields shared by the audit entries of the requests made
+	auditInfo audit.Entry
 }
 
 func NewHTTP(baseUrl, token string) *Penguin {
@@ -28,12 +48,67 @@ func NewHTTP(baseUrl, token string) *Penguin {
 	}
 }
 
-func NewHTTPFromCliContext(ctx *cli.Context) *Penguin {
-	log.Debug().Str("baseUrl", ctx.String("baseUrl")).Str("token", ctx.String("token")).Msg("creating http client")
-	return NewHTTP(ctx.String("baseUrl"), ctx.String("token"))
+func NewHTTPFromCliContext(ctx *cli.Context) (*Penguin, error) {
+	// the token is a credential, so only whether it is set is logged
+	log.Debug().Str("baseUrl", ctx.String("baseUrl")).Bool("tokenSet", ctx.String("token") != "").Msg("creating http client")
+	auditLogFile, err := filepath.UnderDataDir(consts.AuditLogFile)
+	if err != nil {
+		return nil, err
+	}
+	session, err := audit.NewSessionID(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	h := NewHTTP(ctx.String("baseUrl"), ctx.String("token"))
+	if ctx.Bool("unverified-endpoints") {
+		h.AllowUnverifiedEndpoints()
+	}
+	h.UseAuditLog(audit.NewLog(auditLogFile), audit.Entry{
+		Session:  session,
+		Profile:  ctx.String("profile"),
+		Operator: ctx.String("operator"),
+	})
+	return h, nil
+}
+
+// AllowUnverifiedEndpoints allows posting to endpoints other than KnownEndpoints.
+func (h *Penguin) AllowUnverifiedEndpoints() {
+	h.unverified = true
+}
+
+// UseAuditLog records every mutating request in auditLog, with the session, profile and operator of info.
+func (h *Penguin) UseAuditLog(auditLog *audit.Log, info audit.Entry) {
+	h.auditLog = auditLog
+	h.auditInfo = info
+}
+
+// AuditLog returns the audit log mutating requests are recorded in, or nil.
+func (h *Penguin) AuditLog() *audit.Log {
+	return h.auditLog
+}
+
+func (h *Penguin) BaseURL() string {
+	return h.baseUrl
+}
+
+// Session returns the session the requests are recorded under in the audit log.
+func (h *Penguin) Session() string {
+	return h.auditInfo.Session
 }
 
 func (h *Penguin) NewRequest(method, url string, body any) (*http.Request, error) {
+	var b []byte
+	if body != nil {
+		var err error
+		b, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return h.newRequest(method, url, b)
+}
+
+func (h *Penguin) newRequest(method, url string, body []byte) (*http.Request, error) {
 	req, err := http.NewRequest(method, h.baseUrl+url, nil)
 	if err != nil {
 		return nil, err
@@ -41,11 +116,7 @@ func (h *Penguin) NewRequest(method, url string, body any) (*http.Request, error
 	req.Header.Set("Authorization", "Bearer "+h.token)
 
 	if body != nil {
-		b, err := json.Marshal(body)
-		if err != nil {
-			return nil, err
-		}
-		req.Body = io.NopCloser(bytes.NewReader(b))
+		req.Body = io.NopCloser(bytes.NewReader(body))
 	}
 
 	return req, nil
@@ -62,41 +133,96 @@ func (h *Penguin) GetJSON(url string, v any) error {
 	resp, err := h.client.Do(req)
 	if err != nil {
 		return err
-	} else if resp.StatusCode != http.StatusOK {
-		return errors.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
-
 	defer resp.Body.Close()
 
-	json.NewDecoder(resp.Body).Decode(v)
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
 
+	// a malformed or truncated response must not read as no entities
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errors.Wrapf(err, "failed to decode the response of %s", url)
+	}
 	return nil
 }
 
 func (h *Penguin) PostJSON(url string, v any) error {
-	req, err := h.NewRequest("POST", url, v)
+	return h.PostJSONWithResponse(url, v, nil)
+}
+
+// PostJSONWithResponse posts v as JSON to url, and decodes the response body into dest
+// if dest is not nil. The request is recorded in the audit log.
+func (h *Penguin) PostJSONWithResponse(url string, v any, dest any) error {
+	if !h.unverified && !KnownEndpoints[url] {
+		return errors.Wrapf(ErrUnverifiedEndpoint, "POST %s", url)
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := h.newRequest("POST", url, payload)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	requestID, err := audit.NewID()
+	if err != nil {
+		return err
+	}
+	req.Header.Set(consts.RequestIDHeader, requestID)
 
-	log.Debug().Str("method", req.Method).Str("url", req.URL.String()).Msg("making request")
+	log.Debug().Str("method", req.Method).Str("url", req.URL.String()).Str("requestId", requestID).Msg("making request")
+
+	entry := h.auditInfo
+	entry.Time = time.Now()
+	entry.Method = req.Method
+	entry.BaseURL = h.baseUrl
+	entry.Endpoint = url
+	entry.RequestID = requestID
+	entry.PayloadHash = audit.HashPayload(payload)
+	entry.Payload = payload
+	defer h.audit(&entry)
 
 	resp, err := h.client.Do(req)
 	if err != nil {
+		entry.Error = err.Error()
 		return err
-	} else if resp.StatusCode != http.StatusOK {
+	}
+	defer resp.Body.Close()
+	entry.Status = resp.StatusCode
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		entry.Error = err.Error()
+		log.Error().Err(err).Msg("failed to read response body")
+		return err
+	}
+	if json.Valid(body) {
+		entry.Response = body
+	}
+
+	if resp.StatusCode != http.StatusOK {
 		// log response
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Error().Err(err).Msg("failed to read response body")
-		} else {
-			log.Error().Str("body", string(body)).Msg("request failed")
-		}
+		log.Error().Str("body", string(body)).Msg("request failed")
+		entry.Error = string(body)
 		return errors.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	defer resp.Body.Close()
+	// an empty body leaves dest as is
+	if dest != nil && len(bytes.TrimSpace(body)) > 0 {
+		return json.Unmarshal(body, dest)
+	}
 
 	return nil
 }
+
+func (h *Penguin) audit(entry *audit.Entry) {
+	if h.auditLog == nil {
+		return
+	}
+	entry.DurationMilli = time.Since(entry.Time).Milliseconds()
+	if err := h.auditLog.Append(entry); err != nil {
+		log.Warn().Err(err).Str("requestId", entry.RequestID).Msgf("failed to record the request in the audit log %s", h.auditLog.Filename())
+	}
+}
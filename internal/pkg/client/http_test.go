@@ -0,0 +1,121 @@
+package client
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
+)
+
+func TestGetJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr bool
+		want    int
+	}{
+		{name: "entities", status: http.StatusOK, body: `[{"id":1},{"id":2}]`, want: 2},
+		{name: "no entities", status: http.StatusOK, body: `[]`, want: 0},
+		{name: "truncated", status: http.StatusOK, body: `[{"id":1},{"id"`, wantErr: true},
+		{name: "not json", status: http.StatusOK, body: `<html>bad gateway</html>`, wantErr: true},
+		{name: "empty", status: http.StatusOK, body: ``, wantErr: true},
+		{name: "error status", status: http.StatusNotFound, body: `[]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			var entities []struct {
+				ID int `json:"id"`
+			}
+			err := NewHTTP(server.URL, "token").GetJSON("/cli/zones", &entities)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(entities) != tt.want {
+				t.Errorf("GetJSON() decoded %d entities, want %d", len(entities), tt.want)
+			}
+		})
+	}
+}
+
+func TestPostJSONUnverifiedEndpoint(t *testing.T) {
+	tests := []struct {
+		url        string
+		unverified bool
+		wantErr    bool
+	}{
+		{url: "/save"},
+		{url: "/purge"},
+		{url: "/zones", wantErr: true},
+		{url: "/notices/1", wantErr: true},
+		{url: "/zones", unverified: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			posted := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				posted++
+			}))
+			defer server.Close()
+
+			h := NewHTTP(server.URL, "token")
+			if tt.unverified {
+				h.AllowUnverifiedEndpoints()
+			}
+			err := h.PostJSON(tt.url, struct{}{})
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnverifiedEndpoint) {
+					t.Errorf("PostJSON() error = %v, want ErrUnverifiedEndpoint", err)
+				}
+				if posted != 0 {
+					t.Errorf("posted %d requests, want none", posted)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if posted != 1 {
+				t.Errorf("posted %d requests, want 1", posted)
+			}
+		})
+	}
+}
+
+func TestNewHTTPFromCliContextRedactsToken(t *testing.T) {
+	var out bytes.Buffer
+	logger, level := log.Logger, zerolog.GlobalLevel()
+	log.Logger = zerolog.New(&out)
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	filepath.SetDataDir(t.TempDir())
+	t.Cleanup(func() {
+		log.Logger = logger
+		zerolog.SetGlobalLevel(level)
+		filepath.SetDataDir("")
+	})
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.String("baseUrl", "https://example.com", "")
+	set.String("token", "secret-token", "")
+	if _, err := NewHTTPFromCliContext(cli.NewContext(cli.NewApp(), set, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "creating http client") || strings.Contains(out.String(), "secret-token") {
+		t.Errorf("debug log %q, want it without the token", out.String())
+	}
+}
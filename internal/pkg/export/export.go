@@ -0,0 +1,187 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/penguin-statistics/soracli/internal/models/types"
+)
+
+// FormatVersion is the version of the layout of export directories. It is increased on changes that
+// readers of older exports cannot handle, such as a renamed file or field.
+const FormatVersion = 1
+
+// ErrSQLiteUnsupported is returned by WriteSQLite when soracli is built without SQLite.
+var ErrSQLiteUnsupported = errors.New("soracli is built without SQLite; rebuild it with `go build -tags sqlite`, which needs cgo")
+
+// ManifestFile is the name of the manifest of an export directory.
+const ManifestFile = "export.json"
+
+// Manifest describes an export directory: when and where it was exported from, and the file of each kind
+// of entity along with its digest.
+type Manifest struct {
+	FormatVersion int       `json:"formatVersion"`
+	ExportedAt    time.Time `json:"exportedAt"`
+	BaseURL       string    `json:"baseUrl"`
+	Files         []*File   `json:"files"`
+}
+
+// File is a JSON array of the entities of a kind, sorted by their IDs.
+type File struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Count  int    `json:"count"`
+	SHA256 string `json:"sha256"`
+}
+
+// kinds are the kinds of entities exported, each with its file and the slice of the snapshot holding it.
+var kinds = []struct {
+	kind, name string
+	entities   func(*types.Snapshot) any
+}{
+	{"zone", "zones.json", func(s *types.Snapshot) any { return &s.Zones }},
+	{"stage", "stages.json", func(s *types.Snapshot) any { return &s.Stages }},
+	{"timeRange", "timeRanges.json", func(s *types.Snapshot) any { return &s.TimeRanges }},
+	{"dropInfo", "dropInfos.json", func(s *types.Snapshot) any { return &s.DropInfos }},
+	{"activity", "activities.json", func(s *types.Snapshot) any { return &s.Activities }},
+	{"item", "items.json", func(s *types.Snapshot) any { return &s.Items }},
+	{"notice", "notices.json", func(s *types.Snapshot) any { return &s.Notices }},
+}
+
+// SortByID sorts every kind of entity of snapshot by their IDs, so that exports of the same data are
+// identical.
+func SortByID(snapshot *types.Snapshot) {
+	sort.Slice(snapshot.Zones, func(i, j int) bool { return snapshot.Zones[i].ZoneID < snapshot.Zones[j].ZoneID })
+	sort.Slice(snapshot.Stages, func(i, j int) bool { return snapshot.Stages[i].StageID < snapshot.Stages[j].StageID })
+	sort.Slice(snapshot.TimeRanges, func(i, j int) bool { return snapshot.TimeRanges[i].RangeID < snapshot.TimeRanges[j].RangeID })
+	sort.Slice(snapshot.DropInfos, func(i, j int) bool { return snapshot.DropInfos[i].DropID < snapshot.DropInfos[j].DropID })
+	sort.Slice(snapshot.Activities, func(i, j int) bool {
+		return snapshot.Activities[i].ActivityID < snapshot.Activities[j].ActivityID
+	})
+	sort.Slice(snapshot.Items, func(i, j int) bool { return snapshot.Items[i].ItemID < snapshot.Items[j].ItemID })
+	sort.Slice(snapshot.Notices, func(i, j int) bool { return snapshot.Notices[i].NoticeID < snapshot.Notices[j].NoticeID })
+}
+
+// WriteDir writes every kind of entity of snapshot to its file under dir, creating dir if needed, and then
+// the manifest. snapshot is sorted by SortByID.
+func WriteDir(dir string, snapshot *types.Snapshot, baseURL string) (*Manifest, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	SortByID(snapshot)
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		ExportedAt:    time.Now().UTC(),
+		BaseURL:       baseURL,
+		Files:         make([]*File, 0, len(kinds)),
+	}
+	for _, kind := range kinds {
+		// kinds without entities are written as empty arrays, not null
+		entities := reflect.ValueOf(kind.entities(snapshot)).Elem()
+		if entities.IsNil() {
+			entities.Set(reflect.MakeSlice(entities.Type(), 0, 0))
+		}
+		b, err := json.MarshalIndent(entities.Interface(), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, kind.name), b, 0o644); err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, &File{
+			Kind:   kind.kind,
+			Name:   kind.name,
+			Count:  entities.Len(),
+			SHA256: digest(b),
+		})
+	}
+
+	// the manifest is written last, so that a directory with one has every file complete
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), b, 0o644); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ReadDir reads an export directory written by WriteDir, checking its format version and the digests of
+// its files.
+func ReadDir(dir string) (*types.Snapshot, *Manifest, error) {
+	manifest := &Manifest{}
+	b, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "%s is not an export directory", dir)
+	}
+	if err := json.Unmarshal(b, manifest); err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid export manifest in %s", dir)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, nil, errors.Errorf("export %s is of format version %d, but only version %d is supported",
+			dir, manifest.FormatVersion, FormatVersion)
+	}
+
+	files := make(map[string]*File, len(manifest.Files))
+	for _, file := range manifest.Files {
+		files[file.Kind] = file
+	}
+	snapshot := &types.Snapshot{}
+	for _, kind := range kinds {
+		file, ok := files[kind.kind]
+		if !ok {
+			return nil, nil, errors.Errorf("export %s has no %s file", dir, kind.kind)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, file.Name))
+		if err != nil {
+			return nil, nil, err
+		}
+		if digest(b) != file.SHA256 {
+			return nil, nil, errors.Errorf("%s of export %s does not match its digest; it was modified after the export", file.Name, dir)
+		}
+		if err := json.Unmarshal(b, kind.entities(snapshot)); err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid %s of export %s", file.Name, dir)
+		}
+	}
+	return snapshot, manifest, nil
+}
+
+// ReadSnapshot reads a snapshot from filename, which is either a JSON file of a types.Snapshot or an
+// export directory.
+func ReadSnapshot(filename string) (*types.Snapshot, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		snapshot, _, err := ReadDir(filename)
+		return snapshot, err
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	snapshot := &types.Snapshot{}
+	if err := json.NewDecoder(f).Decode(snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,169 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+)
+
+func testSnapshot() *types.Snapshot {
+	return &types.Snapshot{
+		Zones:  []*models.Zone{{ZoneID: 2, ArkZoneID: "act1side_zone1"}, {ZoneID: 1, ArkZoneID: "main_0"}},
+		Stages: []*models.Stage{{StageID: 1, ArkStageID: "main_01-07", ZoneID: 1}},
+		Items:  []*models.Item{{ItemID: 3, ArkItemID: "30013"}, {ItemID: 1, ArkItemID: "30011"}, {ItemID: 2, ArkItemID: "30012"}},
+	}
+}
+
+func TestWriteDirReadDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "export")
+	manifest, err := WriteDir(dir, testSnapshot(), "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.FormatVersion != FormatVersion || manifest.BaseURL != "https://example.com" || len(manifest.Files) != len(kinds) {
+		t.Errorf("manifest %+v, want format version %d with a file of each of the %d kinds", manifest, FormatVersion, len(kinds))
+	}
+
+	// kinds without entities are empty arrays
+	b, err := os.ReadFile(filepath.Join(dir, "notices.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "[]" {
+		t.Errorf("notices.json is %s, want []", b)
+	}
+
+	snapshot, read, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !read.ExportedAt.Equal(manifest.ExportedAt) {
+		t.Errorf("read the manifest of %s, want the one of %s", read.ExportedAt, manifest.ExportedAt)
+	}
+	itemIDs := make([]int, 0)
+	for _, item := range snapshot.Items {
+		itemIDs = append(itemIDs, item.ItemID)
+	}
+	if !reflect.DeepEqual(itemIDs, []int{1, 2, 3}) || snapshot.Zones[0].ZoneID != 1 || len(snapshot.Stages) != 1 {
+		t.Errorf("read items %v and zones %v, want them sorted by their IDs", itemIDs, snapshot.Zones)
+	}
+
+	// exports of the same data are identical, whatever order it is listed in
+	again := filepath.Join(t.TempDir(), "again")
+	shuffled := testSnapshot()
+	shuffled.Items[0], shuffled.Items[2] = shuffled.Items[2], shuffled.Items[0]
+	manifestAgain, err := WriteDir(again, shuffled, "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, file := range manifest.Files {
+		if file.SHA256 != manifestAgain.Files[i].SHA256 {
+			t.Errorf("%s differs between exports of the same data", file.Name)
+		}
+	}
+}
+
+func TestReadDirInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(t *testing.T, dir string)
+		wantErr string
+	}{
+		{
+			name: "modified file",
+			modify: func(t *testing.T, dir string) {
+				if err := os.WriteFile(filepath.Join(dir, "items.json"), []byte("[]"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "items.json of export",
+		},
+		{
+			name: "newer format version",
+			modify: func(t *testing.T, dir string) {
+				rewriteManifest(t, dir, func(m *Manifest) { m.FormatVersion = FormatVersion + 1 })
+			},
+			wantErr: "only version 1 is supported",
+		},
+		{
+			name: "missing kind",
+			modify: func(t *testing.T, dir string) {
+				rewriteManifest(t, dir, func(m *Manifest) { m.Files = m.Files[1:] })
+			},
+			wantErr: "has no zone file",
+		},
+		{
+			name: "no manifest",
+			modify: func(t *testing.T, dir string) {
+				if err := os.Remove(filepath.Join(dir, ManifestFile)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "is not an export directory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if _, err := WriteDir(dir, testSnapshot(), "https://example.com"); err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(t, dir)
+			if _, _, err := ReadDir(dir); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadDir() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func rewriteManifest(t *testing.T, dir string, modify func(m *Manifest)) {
+	t.Helper()
+	filename := filepath.Join(dir, ManifestFile)
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(b, manifest); err != nil {
+		t.Fatal(err)
+	}
+	modify(manifest)
+	if b, err = json.Marshal(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	exportDir := filepath.Join(dir, "export")
+	if _, err := WriteDir(exportDir, testSnapshot(), "https://example.com"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(testSnapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotFile := filepath.Join(dir, "snapshot.json")
+	if err := os.WriteFile(snapshotFile, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filename := range []string{exportDir, snapshotFile} {
+		snapshot, err := ReadSnapshot(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snapshot.Items) != 3 || len(snapshot.Zones) != 2 {
+			t.Errorf("%s has %d items and %d zones, want 3 and 2", filename, len(snapshot.Items), len(snapshot.Zones))
+		}
+	}
+}
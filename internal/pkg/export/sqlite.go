@@ -0,0 +1,195 @@
+//go:build sqlite
+
+package export
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+
+	"github.com/penguin-statistics/soracli/internal/models/types"
+)
+
+// SQLiteSupported tells whether soracli is built with SQLite, which needs cgo.
+const SQLiteSupported = true
+
+// sqliteSchema has a table for each kind of entity, with a column for each of their JSON fields. Fields
+// holding JSON objects, such as names and existences, are stored as JSON text, for use with the json_*
+// functions of SQLite; times are stored as RFC 3339 text in UTC.
+const sqliteSchema = `
+CREATE TABLE export (
+	format_version INTEGER NOT NULL,
+	exported_at    TEXT NOT NULL,
+	base_url       TEXT NOT NULL
+);
+CREATE TABLE zones (
+	zone_id     INTEGER PRIMARY KEY,
+	ark_zone_id TEXT NOT NULL,
+	"index"     INTEGER NOT NULL,
+	category    TEXT NOT NULL,
+	type        TEXT,
+	name        TEXT,
+	existence   TEXT,
+	background  TEXT
+);
+CREATE TABLE stages (
+	stage_id           INTEGER PRIMARY KEY,
+	ark_stage_id       TEXT NOT NULL,
+	zone_id            INTEGER NOT NULL,
+	stage_type         TEXT NOT NULL,
+	extra_process_type TEXT,
+	code               TEXT,
+	sanity             INTEGER,
+	existence          TEXT,
+	min_clear_time     INTEGER
+);
+CREATE TABLE time_ranges (
+	range_id   INTEGER PRIMARY KEY,
+	name       TEXT,
+	start_time TEXT,
+	end_time   TEXT,
+	comment    TEXT,
+	server     TEXT NOT NULL
+);
+CREATE TABLE drop_infos (
+	drop_id     INTEGER PRIMARY KEY,
+	server      TEXT NOT NULL,
+	stage_id    INTEGER NOT NULL,
+	item_id     INTEGER,
+	drop_type   TEXT NOT NULL,
+	range_id    INTEGER NOT NULL,
+	accumulable INTEGER NOT NULL,
+	bounds      TEXT,
+	extras      TEXT
+);
+CREATE TABLE activities (
+	activity_id INTEGER PRIMARY KEY,
+	start_time  TEXT,
+	end_time    TEXT,
+	name        TEXT,
+	existence   TEXT
+);
+CREATE TABLE items (
+	item_id     INTEGER PRIMARY KEY,
+	ark_item_id TEXT NOT NULL,
+	name        TEXT,
+	existence   TEXT,
+	type        TEXT,
+	sort_id     INTEGER NOT NULL,
+	rarity      INTEGER NOT NULL,
+	"group"     TEXT,
+	sprite      TEXT,
+	keywords    TEXT
+);
+CREATE TABLE notices (
+	notice_id INTEGER PRIMARY KEY,
+	existence TEXT,
+	severity  INTEGER,
+	content   TEXT
+);
+CREATE INDEX stages_zone_id ON stages (zone_id);
+CREATE INDEX drop_infos_stage_id ON drop_infos (stage_id, server);
+CREATE INDEX drop_infos_range_id ON drop_infos (range_id);
+`
+
+// WriteSQLite writes snapshot, exported as described by manifest, to a new SQLite database at filename.
+// An existing file is replaced.
+func WriteSQLite(filename string, snapshot *types.Snapshot, manifest *Manifest) error {
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	db, err := sql.Open("sqlite3", filename)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(sqliteSchema); err != nil {
+		return errors.Wrap(err, "failed to create the tables")
+	}
+	if _, err := tx.Exec("INSERT INTO export VALUES (?, ?, ?)",
+		manifest.FormatVersion, manifest.ExportedAt.Format(time.RFC3339), manifest.BaseURL); err != nil {
+		return err
+	}
+
+	for _, zone := range snapshot.Zones {
+		if _, err := tx.Exec("INSERT INTO zones VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			zone.ZoneID, zone.ArkZoneID, zone.Index, zone.Category, zone.Type, jsonText(zone.Name),
+			jsonText(zone.Existence), zone.Background); err != nil {
+			return errors.Wrapf(err, "failed to insert zone %d", zone.ZoneID)
+		}
+	}
+	for _, stage := range snapshot.Stages {
+		if _, err := tx.Exec("INSERT INTO stages VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			stage.StageID, stage.ArkStageID, stage.ZoneID, stage.StageType, stage.ExtraProcessType,
+			jsonText(stage.Code), stage.Sanity, jsonText(stage.Existence), stage.MinClearTime); err != nil {
+			return errors.Wrapf(err, "failed to insert stage %d", stage.StageID)
+		}
+	}
+	for _, timeRange := range snapshot.TimeRanges {
+		if _, err := tx.Exec("INSERT INTO time_ranges VALUES (?, ?, ?, ?, ?, ?)",
+			timeRange.RangeID, timeRange.Name, timeText(timeRange.StartTime), timeText(timeRange.EndTime),
+			timeRange.Comment, timeRange.Server); err != nil {
+			return errors.Wrapf(err, "failed to insert time range %d", timeRange.RangeID)
+		}
+	}
+	for _, dropInfo := range snapshot.DropInfos {
+		bounds, err := json.Marshal(dropInfo.Bounds)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO drop_infos VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			dropInfo.DropID, dropInfo.Server, dropInfo.StageID, dropInfo.ItemID, dropInfo.DropType,
+			dropInfo.RangeID, dropInfo.Accumulable, jsonText(bounds), jsonText(dropInfo.Extras)); err != nil {
+			return errors.Wrapf(err, "failed to insert drop info %d", dropInfo.DropID)
+		}
+	}
+	for _, activity := range snapshot.Activities {
+		if _, err := tx.Exec("INSERT INTO activities VALUES (?, ?, ?, ?, ?)",
+			activity.ActivityID, timeText(activity.StartTime), timeText(activity.EndTime),
+			jsonText(activity.Name), jsonText(activity.Existence)); err != nil {
+			return errors.Wrapf(err, "failed to insert activity %d", activity.ActivityID)
+		}
+	}
+	for _, item := range snapshot.Items {
+		if _, err := tx.Exec("INSERT INTO items VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			item.ItemID, item.ArkItemID, jsonText(item.Name), jsonText(item.Existence), item.Type, item.SortID,
+			item.Rarity, item.Group, item.Sprite, jsonText(item.Keywords)); err != nil {
+			return errors.Wrapf(err, "failed to insert item %d", item.ItemID)
+		}
+	}
+	for _, notice := range snapshot.Notices {
+		if _, err := tx.Exec("INSERT INTO notices VALUES (?, ?, ?, ?)",
+			notice.NoticeID, jsonText(notice.Existence), notice.Severity, jsonText(notice.Content)); err != nil {
+			return errors.Wrapf(err, "failed to insert notice %d", notice.NoticeID)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// jsonText returns raw as text, or NULL if it is empty or JSON null.
+func jsonText(raw []byte) any {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	return string(raw)
+}
+
+// timeText returns t as RFC 3339 text in UTC, or NULL if it is nil.
+func timeText(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,15 @@
+//go:build !sqlite
+
+package export
+
+import (
+	"github.com/penguin-statistics/soracli/internal/models/types"
+)
+
+// SQLiteSupported tells whether soracli is built with SQLite, which needs cgo.
+const SQLiteSupported = false
+
+// WriteSQLite fails with ErrSQLiteUnsupported; the SQLite export is only built with the sqlite tag.
+func WriteSQLite(filename string, snapshot *types.Snapshot, manifest *Manifest) error {
+	return ErrSQLiteUnsupported
+}
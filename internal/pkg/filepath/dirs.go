@@ -4,23 +4,98 @@ import (
 	"os"
 	"path"
 
+	"github.com/pkg/errors"
+
 	"github.com/penguin-statistics/soracli/internal/consts"
 )
 
-func UnderDataDir(p string) string {
-	userHomeDir, err := os.UserHomeDir()
-	if err != nil {
-		panic(err)
+// dataDir is the directory set by SetDataDir, or empty for the default locations.
+var dataDir string
+
+// SetDataDir makes every file of soracli live under dir, with caches and logs in its cache and logs
+// subdirectories, as under the legacy ~/.soracli. An empty dir restores the default locations.
+func SetDataDir(dir string) {
+	dataDir = dir
+}
+
+// Dirs are the directories soracli keeps its files in.
+type Dirs struct {
+	// Data holds the files worth keeping, such as sessions, rendered bundles and the audit log.
+	Data string
+	// Cache holds the on-disk caches of the admin api, which may be removed at any time.
+	Cache string
+	// Logs holds the log file of each run.
+	Logs string
+}
+
+// Locate returns the directories soracli keeps its files in. Unless SetDataDir overrides them, these are
+// the XDG base directories, i.e. $XDG_DATA_HOME/soracli, $XDG_CACHE_HOME/soracli and
+// $XDG_STATE_HOME/soracli/logs, with their defaults under the home directory. An existing ~/.soracli is
+// kept using as before, unless $XDG_DATA_HOME is set.
+func Locate() (*Dirs, error) {
+	if dataDir != "" {
+		return underOne(dataDir), nil
 	}
 
-	file := path.Join(userHomeDir, consts.DataDir, p)
-	dir := path.Dir(file)
-	// if dir does not exist, create it
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		if err := os.MkdirAll(dir, 0o755); err != nil {
-			panic(err)
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot locate the data directory; use --data-dir")
+	}
+	if os.Getenv("XDG_DATA_HOME") == "" {
+		legacy := path.Join(home, consts.DataDir)
+		if info, err := os.Stat(legacy); err == nil && info.IsDir() {
+			return underOne(legacy), nil
 		}
 	}
 
-	return file
+	return &Dirs{
+		Data:  path.Join(xdgDir("XDG_DATA_HOME", home, ".local/share"), consts.AppName),
+		Cache: path.Join(xdgDir("XDG_CACHE_HOME", home, ".cache"), consts.AppName),
+		Logs:  path.Join(xdgDir("XDG_STATE_HOME", home, ".local/state"), consts.AppName, "logs"),
+	}, nil
+}
+
+func underOne(dir string) *Dirs {
+	return &Dirs{
+		Data:  dir,
+		Cache: path.Join(dir, "cache"),
+		Logs:  path.Join(dir, "logs"),
+	}
+}
+
+// xdgDir returns the directory of the XDG environment variable env, or def under home if it is not set
+// to an absolute path, as the XDG base directory specification requires.
+func xdgDir(env, home, def string) string {
+	if dir := os.Getenv(env); path.IsAbs(dir) {
+		return dir
+	}
+	return path.Join(home, def)
+}
+
+// UnderDataDir returns the path of p under the data directory, creating the directories it is in.
+func UnderDataDir(p string) (string, error) {
+	return under(p, func(dirs *Dirs) string { return dirs.Data })
+}
+
+// UnderCacheDir returns the path of p under the cache directory, creating the directories it is in.
+func UnderCacheDir(p string) (string, error) {
+	return under(p, func(dirs *Dirs) string { return dirs.Cache })
+}
+
+// UnderLogDir returns the path of p under the log directory, creating the directories it is in.
+func UnderLogDir(p string) (string, error) {
+	return under(p, func(dirs *Dirs) string { return dirs.Logs })
+}
+
+func under(p string, dir func(dirs *Dirs) string) (string, error) {
+	dirs, err := Locate()
+	if err != nil {
+		return "", err
+	}
+
+	file := path.Join(dir(dirs), p)
+	if err := os.MkdirAll(path.Dir(file), 0o755); err != nil {
+		return "", errors.Wrapf(err, "cannot create the directory of %s", file)
+	}
+	return file, nil
 }
@@ -0,0 +1,110 @@
+package filepath
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestLocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		legacy  string // "dir" or "file" at ~/.soracli
+		dataDir string
+		// wanted directories, with ~ for the home directory
+		want Dirs
+	}{
+		{
+			name: "XDG defaults",
+			want: Dirs{Data: "~/.local/share/soracli", Cache: "~/.cache/soracli", Logs: "~/.local/state/soracli/logs"},
+		},
+		{
+			name: "XDG variables",
+			env:  map[string]string{"XDG_DATA_HOME": "/xdg/data", "XDG_CACHE_HOME": "/xdg/cache", "XDG_STATE_HOME": "/xdg/state"},
+			want: Dirs{Data: "/xdg/data/soracli", Cache: "/xdg/cache/soracli", Logs: "/xdg/state/soracli/logs"},
+		},
+		{
+			name: "relative XDG variables are ignored",
+			env:  map[string]string{"XDG_DATA_HOME": "data", "XDG_CACHE_HOME": "./cache", "XDG_STATE_HOME": "/xdg/state"},
+			want: Dirs{Data: "~/.local/share/soracli", Cache: "~/.cache/soracli", Logs: "/xdg/state/soracli/logs"},
+		},
+		{
+			name:   "legacy directory",
+			legacy: "dir",
+			env:    map[string]string{"XDG_CACHE_HOME": "/xdg/cache"},
+			want:   Dirs{Data: "~/.soracli", Cache: "~/.soracli/cache", Logs: "~/.soracli/logs"},
+		},
+		{
+			name:   "legacy directory with XDG_DATA_HOME",
+			legacy: "dir",
+			env:    map[string]string{"XDG_DATA_HOME": "/xdg/data"},
+			want:   Dirs{Data: "/xdg/data/soracli", Cache: "~/.cache/soracli", Logs: "~/.local/state/soracli/logs"},
+		},
+		{
+			name:   "legacy file",
+			legacy: "file",
+			want:   Dirs{Data: "~/.local/share/soracli", Cache: "~/.cache/soracli", Logs: "~/.local/state/soracli/logs"},
+		},
+		{
+			name:    "data dir",
+			legacy:  "dir",
+			env:     map[string]string{"XDG_DATA_HOME": "/xdg/data"},
+			dataDir: "/srv/soracli",
+			want:    Dirs{Data: "/srv/soracli", Cache: "/srv/soracli/cache", Logs: "/srv/soracli/logs"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			for _, env := range []string{"XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME"} {
+				t.Setenv(env, tt.env[env])
+			}
+			switch tt.legacy {
+			case "dir":
+				if err := os.Mkdir(path.Join(home, ".soracli"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			case "file":
+				if err := os.WriteFile(path.Join(home, ".soracli"), nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			SetDataDir(tt.dataDir)
+			t.Cleanup(func() { SetDataDir("") })
+
+			dirs, err := Locate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expand := func(p string) string {
+				if len(p) > 0 && p[0] == '~' {
+					return home + p[1:]
+				}
+				return p
+			}
+			want := Dirs{Data: expand(tt.want.Data), Cache: expand(tt.want.Cache), Logs: expand(tt.want.Logs)}
+			if *dirs != want {
+				t.Errorf("Locate() = %+v, want %+v", *dirs, want)
+			}
+		})
+	}
+}
+
+func TestUnderLogDir(t *testing.T) {
+	dir := t.TempDir()
+	SetDataDir(dir)
+	t.Cleanup(func() { SetDataDir("") })
+
+	file, err := UnderLogDir("sub/soracli.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := path.Join(dir, "logs/sub/soracli.log"); file != want {
+		t.Errorf("UnderLogDir = %s, want %s", file, want)
+	}
+	if info, err := os.Stat(path.Dir(file)); err != nil || !info.IsDir() {
+		t.Errorf("the directory of %s is not created: %v", file, err)
+	}
+}
@@ -1,6 +1,7 @@
 package gdutils
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/penguin-statistics/soracli/internal/consts"
@@ -81,3 +82,11 @@ func GetArkStageIDSecondPart(stage *gamedata.Stage) string {
 	}
 	return stage.StageID[len(zonePrefix)+1:]
 }
+
+// ArkIDOf returns the ark ID of the Penguin ID id in arkIDs, or `#id` if it has none.
+func ArkIDOf(arkIDs map[int]string, id int) string {
+	if arkID, ok := arkIDs[id]; ok {
+		return arkID
+	}
+	return "#" + strconv.Itoa(id)
+}
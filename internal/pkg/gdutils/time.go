@@ -0,0 +1,120 @@
+package gdutils
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+)
+
+// ServerTimeSep separates a server local time from its server, as in `2022-05-01 16:00 @CN`.
+const ServerTimeSep = "@"
+
+// serverTimeLayouts are the layouts of server local times accepted by ParseServerTime.
+var serverTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+const serverDateLayout = "2006-01-02"
+
+var ErrUnknownServer = errors.New("unknown server")
+
+// ServerLocation returns the time zone of server.
+func ServerLocation(server string) (*time.Location, error) {
+	loc, ok := consts.LocMap[server]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownServer, "%q; expected one of %v", server, consts.Servers)
+	}
+	return loc, nil
+}
+
+// GameDayStart returns the time the game day of date starts at on server, e.g. 04:00 of that date
+// in the time zone of server.
+func GameDayStart(year int, month time.Month, day int, server string) (time.Time, error) {
+	loc, err := ServerLocation(server)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(year, month, day, consts.GameDayStartHour, consts.GameDayStartMinute, consts.GameDayStartSecond, consts.GameDayStartNano, loc), nil
+}
+
+// ParseServerTime parses s either as RFC3339, or as a local time of a server like `2022-05-01 16:00 @CN`.
+// Without the `@SERVER` suffix, s is in the local time of server. A date without a time, like
+// `2022-05-01 @US`, is snapped to the start of that game day on the server. Local times skipped when
+// daylight saving time starts are rejected, and so are the ones repeated when it ends, which need an
+// RFC3339 offset to tell which of the two is meant.
+func ParseServerTime(s string, server string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	if value, suffix, ok := strings.Cut(s, ServerTimeSep); ok {
+		s = strings.TrimSpace(value)
+		server = strings.ToUpper(strings.TrimSpace(suffix))
+	}
+	if server == "" {
+		return time.Time{}, errors.Errorf("time %q has no time zone; append %sSERVER, e.g. `2022-05-01 16:00 @CN`, or use RFC3339", s, ServerTimeSep)
+	}
+	loc, err := ServerLocation(server)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if date, err := time.Parse(serverDateLayout, s); err == nil {
+		return GameDayStart(date.Year(), date.Month(), date.Day(), server)
+	}
+	for _, layout := range serverTimeLayouts {
+		if wall, err := time.Parse(layout, s); err == nil {
+			return inLocation(s, wall, loc)
+		}
+	}
+	return time.Time{}, errors.Errorf("invalid time %q; expected RFC3339, `2006-01-02 15:04 @SERVER` or `2006-01-02 @SERVER`", s)
+}
+
+// inLocation returns the time of loc showing the wall clock of wall, a time in UTC, unless loc skips or
+// repeats it for daylight saving time.
+func inLocation(s string, wall time.Time, loc *time.Location) (time.Time, error) {
+	// the offsets in effect around the time are the ones it may have, earliest first
+	var times []time.Time
+	for _, around := range []time.Duration{-24 * time.Hour, 0, 24 * time.Hour} {
+		_, offset := wall.Add(around).In(loc).Zone()
+		t := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if !sameWallClock(t, wall) || (len(times) > 0 && !t.After(times[len(times)-1])) {
+			continue
+		}
+		times = append(times, t)
+	}
+
+	switch len(times) {
+	case 0:
+		return time.Time{}, errors.Errorf("time %q does not exist in %s, as its clocks skip it when daylight saving time starts", s, loc)
+	case 1:
+		return times[0], nil
+	default:
+		return time.Time{}, errors.Errorf("time %q occurs twice in %s, as its clocks go back when daylight saving time ends; use RFC3339 to pick one, either %s or %s",
+			s, loc, times[0].Format(time.RFC3339), times[1].Format(time.RFC3339))
+	}
+}
+
+func sameWallClock(t, wall time.Time) bool {
+	y1, m1, d1 := t.Date()
+	y2, m2, d2 := wall.Date()
+	h1, min1, s1 := t.Clock()
+	h2, min2, s2 := wall.Clock()
+	return y1 == y2 && m1 == m2 && d1 == d2 && h1 == h2 && min1 == min2 && s1 == s2
+}
+
+// ParseServerDate parses a date like `2022-05-01` and snaps it to the start of that game day on server.
+func ParseServerDate(s string, server string) (time.Time, error) {
+	date, err := time.Parse(serverDateLayout, strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid date %q; expected 2006-01-02", s)
+	}
+	return GameDayStart(date.Year(), date.Month(), date.Day(), server)
+}
@@ -0,0 +1,62 @@
+package gdutils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseServerTime(t *testing.T) {
+	tests := []struct {
+		s, server string
+		want      time.Time
+		wantErr   string
+	}{
+		{s: "2022-05-01 16:00 @CN", want: time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)},
+		{s: "2022-05-01 16:00", server: "CN", want: time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)},
+		{s: "2022-05-01T16:00:30", server: "JP", want: time.Date(2022, 5, 1, 7, 0, 30, 0, time.UTC)},
+		{s: " 2022-05-01 16:00 @us ", server: "CN", want: time.Date(2022, 5, 1, 23, 0, 0, 0, time.UTC)},
+		{s: "2022-05-01T16:00:00+08:00", server: "US", want: time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)},
+		{s: "2022-05-01 @US", want: time.Date(2022, 5, 1, 11, 0, 0, 0, time.UTC)},
+
+		// US clocks go from 02:00 PST to 03:00 PDT on 2022-03-13
+		{s: "2022-03-13 01:59 @US", want: time.Date(2022, 3, 13, 9, 59, 0, 0, time.UTC)},
+		{s: "2022-03-13 02:00 @US", wantErr: "does not exist in America/Los_Angeles"},
+		{s: "2022-03-13 02:30 @US", wantErr: "does not exist in America/Los_Angeles"},
+		{s: "2022-03-13 03:00 @US", want: time.Date(2022, 3, 13, 10, 0, 0, 0, time.UTC)},
+
+		// and from 02:00 PDT back to 01:00 PST on 2022-11-06
+		{s: "2022-11-06 00:59 @US", want: time.Date(2022, 11, 6, 7, 59, 0, 0, time.UTC)},
+		{s: "2022-11-06 01:00 @US", wantErr: "either 2022-11-06T01:00:00-07:00 or 2022-11-06T01:00:00-08:00"},
+		{s: "2022-11-06 01:30 @US", wantErr: "occurs twice in America/Los_Angeles"},
+		{s: "2022-11-06T01:30:00-07:00", server: "US", want: time.Date(2022, 11, 6, 8, 30, 0, 0, time.UTC)},
+		{s: "2022-11-06T01:30:00-08:00", server: "US", want: time.Date(2022, 11, 6, 9, 30, 0, 0, time.UTC)},
+		{s: "2022-11-06 02:00 @US", want: time.Date(2022, 11, 6, 10, 0, 0, 0, time.UTC)},
+
+		// servers without daylight saving time
+		{s: "2022-03-13 02:30 @KR", want: time.Date(2022, 3, 12, 17, 30, 0, 0, time.UTC)},
+		{s: "2022-11-06 01:30 @JP", want: time.Date(2022, 11, 5, 16, 30, 0, 0, time.UTC)},
+
+		{s: "2022-05-01 16:00", wantErr: "has no time zone"},
+		{s: "2022-05-01 16:00 @EU", wantErr: "unknown server"},
+		{s: "May 1st", server: "CN", wantErr: "invalid time"},
+		{s: "2022-02-30 16:00", server: "CN", wantErr: "invalid time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseServerTime(tt.s, tt.server)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseServerTime(%q, %q) = %v, want %v", tt.s, tt.server, got.UTC(), tt.want)
+			}
+		})
+	}
+}
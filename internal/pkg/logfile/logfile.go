@@ -0,0 +1,48 @@
+// Package logfile manages the log file each run of soracli writes to, and the retention of older ones.
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/penguin-statistics/soracli/internal/pkg/filepath"
+	"github.com/penguin-statistics/soracli/internal/pkg/retention"
+)
+
+// current is the name of the log file opened by Open.
+var current string
+
+// Open creates the log file of this run under the log directory.
+func Open() (*os.File, error) {
+	filename, err := filepath.UnderLogDir(fmt.Sprintf("soracli-%s.log", time.Now().Format("20060102-150405")))
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	current = path.Base(filename)
+	return f, nil
+}
+
+// Prune removes the log files beyond the retention set by --log-max-age and --log-max-size, oldest first,
+// and returns them; a dry run only returns them. The log file of this run is always kept.
+func Prune(c *cli.Context, dryRun bool) ([]*retention.File, error) {
+	dirs, err := filepath.Locate()
+	if err != nil {
+		return nil, err
+	}
+	policy := retention.Policy{
+		MaxAge:  c.Duration("log-max-age"),
+		MaxSize: c.Int64("log-max-size") << 20,
+	}
+	return retention.Prune(dirs.Logs, func(name string) bool {
+		return name != current && strings.HasPrefix(name, "soracli-") && strings.HasSuffix(name, ".log")
+	}, policy, time.Now(), dryRun)
+}
@@ -0,0 +1,131 @@
+package logfile
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	soracliPath "github.com/penguin-statistics/soracli/internal/pkg/filepath"
+)
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxAge   time.Duration
+		maxSize  int64 // MiB
+		dryRun   bool
+		want     []string
+		wantLeft []string
+	}{
+		{
+			name:     "by age",
+			maxAge:   7 * 24 * time.Hour,
+			want:     []string{"soracli-20220401-120000.log", "soracli-20220501-120000.log"},
+			wantLeft: []string{"notes.log", "soracli-20220529-120000.log", "soracli.log"},
+		},
+		{
+			name:     "by size",
+			maxSize:  1,
+			want:     []string{"soracli-20220401-120000.log"},
+			wantLeft: []string{"notes.log", "soracli-20220501-120000.log", "soracli-20220529-120000.log", "soracli.log"},
+		},
+		{
+			name:     "dry run",
+			maxAge:   7 * 24 * time.Hour,
+			dryRun:   true,
+			want:     []string{"soracli-20220401-120000.log", "soracli-20220501-120000.log"},
+			wantLeft: []string{"notes.log", "soracli-20220401-120000.log", "soracli-20220501-120000.log", "soracli-20220529-120000.log", "soracli.log"},
+		},
+		{
+			name:     "no retention",
+			want:     []string{},
+			wantLeft: []string{"notes.log", "soracli-20220401-120000.log", "soracli-20220501-120000.log", "soracli-20220529-120000.log", "soracli.log"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			soracliPath.SetDataDir(dir)
+			t.Cleanup(func() { soracliPath.SetDataDir("") })
+			logs := filepath.Join(dir, "logs")
+			if err := os.MkdirAll(logs, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			now := time.Now()
+			// the log files of past runs, and files that are not
+			old := []struct {
+				name string
+				age  time.Duration
+				size int
+			}{
+				{"soracli-20220401-120000.log", 60 * 24 * time.Hour, 600 << 10},
+				{"soracli-20220501-120000.log", 30 * 24 * time.Hour, 300 << 10},
+				{"soracli-20220529-120000.log", 2 * 24 * time.Hour, 200 << 10},
+				{"soracli.log", 90 * 24 * time.Hour, 10},
+				{"notes.log", 90 * 24 * time.Hour, 10},
+			}
+			for _, file := range old {
+				filename := filepath.Join(logs, file.name)
+				if err := os.WriteFile(filename, make([]byte, file.size), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				modTime := now.Add(-file.age)
+				if err := os.Chtimes(filename, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// the log file of this run is kept, however large
+			f, err := Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.Write(make([]byte, 2<<20)); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+			t.Cleanup(func() { current = "" })
+
+			set := flag.NewFlagSet("test", flag.ContinueOnError)
+			set.Duration("log-max-age", tt.maxAge, "")
+			set.Int64("log-max-size", tt.maxSize, "")
+			c := cli.NewContext(cli.NewApp(), set, nil)
+
+			removed, err := Prune(c, tt.dryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(removed))
+			for _, file := range removed {
+				got = append(got, filepath.Base(file.Path))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("removed %v, want %v", got, tt.want)
+			}
+
+			entries, err := os.ReadDir(logs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			left := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				if entry.Name() != current {
+					left = append(left, entry.Name())
+				}
+			}
+			sort.Strings(left)
+			if !reflect.DeepEqual(left, tt.wantLeft) {
+				t.Errorf("left %v, want %v", left, tt.wantLeft)
+			}
+			if len(left) == len(entries) {
+				t.Errorf("the log file of this run %s is removed", current)
+			}
+		})
+	}
+}
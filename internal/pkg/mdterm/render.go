@@ -0,0 +1,165 @@
+// Package mdterm renders markdown for display in a terminal, approximating how
+// the site would present it.
+package mdterm
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiItalic    = "\x1b[3m"
+	ansiUnderline = "\x1b[4m"
+	ansiStrike    = "\x1b[9m"
+	ansiFaint     = "\x1b[2m"
+	ansiCyan      = "\x1b[36m"
+)
+
+// Render renders markdown source into ANSI-styled text.
+func Render(source string) string {
+	r := &renderer{}
+	root := blackfriday.New(blackfriday.WithExtensions(blackfriday.CommonExtensions)).Parse([]byte(source))
+	root.Walk(r.visit)
+	return strings.TrimRight(r.buf.String(), "\n") + "\n"
+}
+
+type renderer struct {
+	buf bytes.Buffer
+
+	// lists holds the next item number of each nesting ordered list, or 0 for bullet lists
+	lists []int
+	quote int
+}
+
+func (r *renderer) linePrefix() {
+	r.buf.WriteString(strings.Repeat(ansiFaint+"│ "+ansiReset, r.quote))
+}
+
+func (r *renderer) visit(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	switch node.Type {
+	case blackfriday.Heading:
+		if entering {
+			r.linePrefix()
+			r.buf.WriteString(ansiBold + ansiUnderline + strings.Repeat("#", node.Level) + " ")
+		} else {
+			r.buf.WriteString(ansiReset + "\n\n")
+		}
+	case blackfriday.Paragraph:
+		if entering {
+			if node.Parent == nil || node.Parent.Type != blackfriday.Item {
+				r.linePrefix()
+			}
+		} else {
+			r.buf.WriteString("\n")
+			if !inTightList(node) {
+				r.buf.WriteString("\n")
+			}
+		}
+	case blackfriday.BlockQuote:
+		if entering {
+			r.quote++
+		} else {
+			r.quote--
+		}
+	case blackfriday.List:
+		if entering {
+			next := 0
+			if node.ListFlags&blackfriday.ListTypeOrdered != 0 {
+				next = 1
+			}
+			r.lists = append(r.lists, next)
+		} else {
+			r.lists = r.lists[:len(r.lists)-1]
+			if len(r.lists) == 0 {
+				r.buf.WriteString("\n")
+			}
+		}
+	case blackfriday.Item:
+		if entering {
+			depth := len(r.lists) - 1
+			r.linePrefix()
+			r.buf.WriteString(strings.Repeat("  ", depth))
+			if n := r.lists[depth]; n > 0 {
+				r.buf.WriteString(strconv.Itoa(n) + ". ")
+				r.lists[depth]++
+			} else {
+				r.buf.WriteString("• ")
+			}
+		}
+	case blackfriday.HorizontalRule:
+		r.linePrefix()
+		r.buf.WriteString(ansiFaint + strings.Repeat("─", 40) + ansiReset + "\n\n")
+	case blackfriday.Emph:
+		r.style(entering, ansiItalic)
+	case blackfriday.Strong:
+		r.style(entering, ansiBold)
+	case blackfriday.Del:
+		r.style(entering, ansiStrike)
+	case blackfriday.Link, blackfriday.Image:
+		if entering {
+			r.buf.WriteString(ansiUnderline)
+		} else {
+			r.buf.WriteString(ansiReset + ansiFaint + " (" + string(node.LinkData.Destination) + ")" + ansiReset)
+		}
+	case blackfriday.Text:
+		r.buf.Write(node.Literal)
+	case blackfriday.Code:
+		r.buf.WriteString(ansiCyan + string(node.Literal) + ansiReset)
+	case blackfriday.CodeBlock:
+		for _, line := range strings.Split(strings.TrimRight(string(node.Literal), "\n"), "\n") {
+			r.linePrefix()
+			r.buf.WriteString("    " + ansiCyan + line + ansiReset + "\n")
+		}
+		r.buf.WriteString("\n")
+	case blackfriday.HTMLBlock, blackfriday.HTMLSpan:
+		r.buf.WriteString(ansiFaint + string(node.Literal) + ansiReset)
+		if node.Type == blackfriday.HTMLBlock {
+			r.buf.WriteString("\n\n")
+		}
+	case blackfriday.Softbreak:
+		r.buf.WriteString(" ")
+	case blackfriday.Hardbreak:
+		r.buf.WriteString("\n")
+		r.linePrefix()
+	case blackfriday.TableRow:
+		if !entering {
+			r.buf.WriteString("\n")
+		} else {
+			r.linePrefix()
+		}
+	case blackfriday.TableCell:
+		if entering {
+			if node.IsHeader {
+				r.buf.WriteString(ansiBold)
+			}
+		} else {
+			r.buf.WriteString(ansiReset + " │ ")
+		}
+	case blackfriday.Table:
+		if !entering {
+			r.buf.WriteString("\n")
+		}
+	}
+	return blackfriday.GoToNext
+}
+
+func inTightList(paragraph *blackfriday.Node) bool {
+	item := paragraph.Parent
+	if item == nil || item.Type != blackfriday.Item {
+		return false
+	}
+	return item.Tight || item.Parent != nil && item.Parent.Tight
+}
+
+func (r *renderer) style(entering bool, code string) {
+	if entering {
+		r.buf.WriteString(code)
+	} else {
+		r.buf.WriteString(ansiReset)
+	}
+}
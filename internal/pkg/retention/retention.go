@@ -0,0 +1,71 @@
+// Package retention prunes the files soracli accumulates, such as logs and sessions, by age and total size.
+package retention
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Policy is how much of the files of a directory is kept.
+type Policy struct {
+	// MaxAge removes the files last modified longer ago than it; zero keeps files of any age.
+	MaxAge time.Duration
+	// MaxSize removes the oldest files until the rest total at most it in bytes; zero keeps any size.
+	MaxSize int64
+}
+
+// File is a file removed, or to be removed, by Prune.
+type File struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Prune removes the files directly under dir whose names match beyond what policy keeps as of now,
+// oldest first, and returns them. A dry run only returns them. A missing dir has nothing to prune.
+func Prune(dir string, match func(name string) bool, policy Policy, now time.Time, dryRun bool) ([]*File, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*File, 0, len(entries))
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir() || !match(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// removed meanwhile
+			continue
+		}
+		files = append(files, &File{Path: filepath.Join(dir, entry.Name()), Size: info.Size(), ModTime: info.ModTime()})
+		total += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime.Before(files[j].ModTime)
+	})
+
+	removed := make([]*File, 0)
+	for _, file := range files {
+		tooOld := policy.MaxAge > 0 && now.Sub(file.ModTime) > policy.MaxAge
+		tooLarge := policy.MaxSize > 0 && total > policy.MaxSize
+		if !tooOld && !tooLarge {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+				return removed, err
+			}
+		}
+		total -= file.Size
+		removed = append(removed, file)
+	}
+	return removed, nil
+}
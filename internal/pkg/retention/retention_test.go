@@ -0,0 +1,90 @@
+package retention
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2022, 5, 31, 12, 0, 0, 0, time.UTC)
+	// files by name, with their ages in days and sizes in bytes
+	files := []struct {
+		name string
+		age  int
+		size int
+	}{
+		{"a.log", 40, 100},
+		{"b.log", 20, 300},
+		{"c.log", 10, 200},
+		{"d.log", 1, 100},
+		{"notes.txt", 60, 1000},
+	}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		want   []string
+	}{
+		{name: "keep all", policy: Policy{}, want: []string{}},
+		{name: "by age", policy: Policy{MaxAge: 30 * 24 * time.Hour}, want: []string{"a.log"}},
+		{name: "by size", policy: Policy{MaxSize: 350}, want: []string{"a.log", "b.log"}},
+		{name: "size at the limit", policy: Policy{MaxSize: 700}, want: []string{}},
+		{name: "by age and size", policy: Policy{MaxAge: 15 * 24 * time.Hour, MaxSize: 250}, want: []string{"a.log", "b.log", "c.log"}},
+	}
+	for _, tt := range tests {
+		for _, dryRun := range []bool{true, false} {
+			name := tt.name
+			if dryRun {
+				name += " dry run"
+			}
+			t.Run(name, func(t *testing.T) {
+				dir := t.TempDir()
+				for _, file := range files {
+					filename := filepath.Join(dir, file.name)
+					if err := os.WriteFile(filename, make([]byte, file.size), 0o644); err != nil {
+						t.Fatal(err)
+					}
+					modTime := now.Add(-time.Duration(file.age) * 24 * time.Hour)
+					if err := os.Chtimes(filename, modTime, modTime); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				removed, err := Prune(dir, func(name string) bool { return strings.HasSuffix(name, ".log") }, tt.policy, now, dryRun)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := make([]string, 0, len(removed))
+				for _, file := range removed {
+					got = append(got, filepath.Base(file.Path))
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("removed %v, want %v", got, tt.want)
+				}
+
+				entries, err := os.ReadDir(dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				wantLeft := len(files)
+				if !dryRun {
+					wantLeft -= len(tt.want)
+				}
+				if len(entries) != wantLeft {
+					t.Errorf("%d files left, want %d", len(entries), wantLeft)
+				}
+			})
+		}
+	}
+}
+
+func TestPruneMissingDir(t *testing.T) {
+	removed, err := Prune(filepath.Join(t.TempDir(), "missing"), func(string) bool { return true }, Policy{MaxSize: 1}, time.Now(), false)
+	if err != nil || len(removed) != 0 {
+		t.Errorf("Prune = %v, %v; want nothing", removed, err)
+	}
+}
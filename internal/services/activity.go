@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/cache"
+	"github.com/penguin-statistics/soracli/internal/pkg/client"
+)
+
+type ActivityService struct {
+	http *client.Penguin
+}
+
+func NewActivityService(http *client.Penguin) *ActivityService {
+	return &ActivityService{
+		http: http,
+	}
+}
+
+func (s *ActivityService) GetActivities(ctx context.Context) ([]*models.Activity, error) {
+	var activities []*models.Activity
+	err := cache.Activities.MutexGetSet(&activities, func() ([]*models.Activity, error) {
+		var resp []*models.Activity
+		if err := s.http.GetJSON("/cli/activities", &resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}, time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	return activities, nil
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+	"github.com/penguin-statistics/soracli/internal/pkg/gdutils"
+)
+
+// BoundsAuditOptions configures AuditDropReportBounds.
+type BoundsAuditOptions struct {
+	// MinSamples is the number of single-run reports needed to suggest tightening bounds wider than observed.
+	MinSamples int
+}
+
+// stageDropInfos are the drop infos of a stage within a time range.
+type stageDropInfos struct {
+	// items are the drop infos of items, keyed by item ID
+	items map[int]*models.DropInfo
+	// types are the drop infos without an item, bounding the kinds of items of a drop type, keyed by drop type
+	types map[string]*models.DropInfo
+}
+
+// observedBounds collects the quantities of a drop info observed in single-run reports.
+type observedBounds struct {
+	dropInfo   *models.DropInfo
+	timeRange  *models.TimeRange
+	subject    string
+	bounds     *models.Bounds
+	samples    int
+	violations int
+}
+
+func (o *observedBounds) observe(value int) {
+	if o.bounds == nil {
+		o.bounds = &models.Bounds{Lower: value, Upper: value}
+	}
+	if value < o.bounds.Lower {
+		o.bounds.Lower = value
+	}
+	if value > o.bounds.Upper {
+		o.bounds.Upper = value
+	}
+	if !containsInt(o.bounds.Exceptions, value) && containsInt(o.dropInfo.Bounds.Exceptions, value) {
+		o.bounds.Exceptions = append(o.bounds.Exceptions, value)
+	}
+	o.samples++
+}
+
+// AuditDropReportBounds replays the drop reports of server against the bounds of the drop infos in force for
+// the stage and time range of each report, as matched by ComputeDropMatrix, and suggests bounds from the
+// quantities observed. Item bounds are scaled by the Times of a report, while the exceptions and the bounds
+// of the kinds of items of a drop type are only checked for single-run reports.
+//
+// A reliable report violating the bounds is counted as wrongly accepted, and an unreliable report satisfying
+// them as wrongly rejected, though it may have been rejected for other reasons.
+func AuditDropReportBounds(snapshot *types.Snapshot, server string, reports []*models.DropReport, patterns []*models.DropPattern, opts BoundsAuditOptions) (*types.BoundsAudit, error) {
+	patternQuantities, err := patternQuantitiesOf(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	timeRangesMap := timeRangesMapOf(snapshot, server)
+	stageTimeRanges := stageTimeRangesOf(snapshot, server, timeRangesMap)
+
+	dropInfos := make(map[stageRangeKey]*stageDropInfos)
+	for _, dropInfo := range snapshot.DropInfos {
+		if dropInfo.Server != server || dropInfo.Bounds == nil {
+			continue
+		}
+		key := stageRangeKey{stageID: dropInfo.StageID, rangeID: dropInfo.RangeID}
+		if dropInfos[key] == nil {
+			dropInfos[key] = &stageDropInfos{items: make(map[int]*models.DropInfo), types: make(map[string]*models.DropInfo)}
+		}
+		if dropInfo.ItemID.Valid {
+			dropInfos[key].items[int(dropInfo.ItemID.Int64)] = dropInfo
+		} else {
+			dropInfos[key].types[dropInfo.DropType] = dropInfo
+		}
+	}
+
+	arkItemIDs := make(map[int]string, len(snapshot.Items))
+	for _, item := range snapshot.Items {
+		arkItemIDs[item.ItemID] = item.ArkItemID
+	}
+
+	audit := &types.BoundsAudit{
+		Violations:  make([]*types.BoundsViolation, 0),
+		Suggestions: make([]*types.BoundsSuggestion, 0),
+		Summary:     &types.DropMatrixSummary{},
+	}
+	observed := make(map[*models.DropInfo]*observedBounds)
+	observe := func(dropInfo *models.DropInfo, timeRange *models.TimeRange, subject string, value int, violated bool) {
+		o, ok := observed[dropInfo]
+		if !ok {
+			o = &observedBounds{dropInfo: dropInfo, timeRange: timeRange, subject: subject}
+			observed[dropInfo] = o
+		}
+		o.observe(value)
+		if violated {
+			o.violations++
+		}
+	}
+
+	for _, report := range reports {
+		if report.Server != server {
+			continue
+		}
+		audit.Summary.Reports++
+		if report.Reliability != 0 {
+			audit.Summary.Unreliable++
+		}
+		quantities, ok := patternQuantities[report.PatternID]
+		if !ok {
+			audit.Summary.NoPattern++
+			continue
+		}
+		timeRange := reportTimeRange(report, stageTimeRanges[report.StageID])
+		if timeRange == nil {
+			audit.Summary.NoTimeRange++
+			continue
+		}
+		inForce := dropInfos[stageRangeKey{stageID: report.StageID, rangeID: timeRange.RangeID}]
+		if inForce == nil {
+			audit.Summary.NoTimeRange++
+			continue
+		}
+
+		violations := make([]*types.BoundsViolation, 0)
+		violate := func(dropInfo *models.DropInfo, kind, subject string, value int) {
+			violations = append(violations, &types.BoundsViolation{
+				Report:    report,
+				DropInfo:  dropInfo,
+				TimeRange: timeRange,
+				Kind:      kind,
+				Subject:   subject,
+				Value:     value,
+			})
+		}
+		singleRun := report.Times == 1
+
+		for _, itemID := range sortedKeys(quantities) {
+			if _, ok := inForce.items[itemID]; !ok && quantities[itemID] > 0 {
+				violate(nil, types.BoundsViolationUnknownItem, gdutils.ArkIDOf(arkItemIDs, itemID), quantities[itemID])
+			}
+		}
+
+		typeCounts := make(map[string]int)
+		for _, itemID := range sortedKeys(inForce.items) {
+			dropInfo := inForce.items[itemID]
+			quantity := quantities[itemID]
+			if quantity > 0 {
+				typeCounts[dropInfo.DropType]++
+			}
+			violated := quantity < dropInfo.Bounds.Lower*report.Times || quantity > dropInfo.Bounds.Upper*report.Times ||
+				singleRun && containsInt(dropInfo.Bounds.Exceptions, quantity)
+			if violated {
+				violate(dropInfo, types.BoundsViolationItemQuantity, gdutils.ArkIDOf(arkItemIDs, itemID), quantity)
+			}
+			if singleRun {
+				observe(dropInfo, timeRange, gdutils.ArkIDOf(arkItemIDs, itemID), quantity, violated)
+			}
+		}
+
+		if singleRun {
+			for _, dropType := range sortedStringKeys(inForce.types) {
+				dropInfo := inForce.types[dropType]
+				count := typeCounts[dropType]
+				violated := count < dropInfo.Bounds.Lower || count > dropInfo.Bounds.Upper || containsInt(dropInfo.Bounds.Exceptions, count)
+				if violated {
+					violate(dropInfo, types.BoundsViolationTypeCount, dropType, count)
+				}
+				observe(dropInfo, timeRange, dropType, count, violated)
+			}
+		}
+
+		audit.Violations = append(audit.Violations, violations...)
+		if report.Reliability == 0 && len(violations) > 0 {
+			audit.WronglyAccepted++
+		} else if report.Reliability != 0 && len(violations) == 0 {
+			audit.WronglyRejected++
+		}
+	}
+
+	for _, o := range observed {
+		if suggestion := suggestBounds(o, opts.MinSamples); suggestion != nil {
+			audit.Suggestions = append(audit.Suggestions, suggestion)
+		}
+	}
+	sort.SliceStable(audit.Violations, func(i, j int) bool {
+		return audit.Violations[i].Report.ReportID < audit.Violations[j].Report.ReportID
+	})
+	sort.Slice(audit.Suggestions, func(i, j int) bool {
+		a, b := audit.Suggestions[i].DropInfo, audit.Suggestions[j].DropInfo
+		if a.StageID != b.StageID {
+			return a.StageID < b.StageID
+		}
+		if a.RangeID != b.RangeID {
+			return a.RangeID < b.RangeID
+		}
+		return a.DropID < b.DropID
+	})
+	return audit, nil
+}
+
+// suggestBounds widens the bounds of a drop info to cover the quantities observed outside them, or tightens
+// them to the observed quantities once there are minSamples reports.
+func suggestBounds(o *observedBounds, minSamples int) *types.BoundsSuggestion {
+	current := o.dropInfo.Bounds
+	suggestion := &types.BoundsSuggestion{
+		DropInfo:  o.dropInfo,
+		TimeRange: o.timeRange,
+		Subject:   o.subject,
+		Observed:  o.bounds,
+		Samples:   o.samples,
+	}
+
+	if o.violations > 0 {
+		suggested := &models.Bounds{Lower: current.Lower, Upper: current.Upper}
+		if o.bounds.Lower < suggested.Lower {
+			suggested.Lower = o.bounds.Lower
+		}
+		if o.bounds.Upper > suggested.Upper {
+			suggested.Upper = o.bounds.Upper
+		}
+		for _, exception := range current.Exceptions {
+			if !containsInt(o.bounds.Exceptions, exception) {
+				suggested.Exceptions = append(suggested.Exceptions, exception)
+			}
+		}
+		suggestion.Suggested = suggested
+		suggestion.Reason = fmt.Sprintf("%d of %d single-run reports violate the bounds; widen them if those drops are genuine", o.violations, o.samples)
+		return suggestion
+	}
+
+	if o.samples >= minSamples && (o.bounds.Lower > current.Lower || o.bounds.Upper < current.Upper) {
+		suggested := &models.Bounds{Lower: o.bounds.Lower, Upper: o.bounds.Upper}
+		for _, exception := range current.Exceptions {
+			if exception >= suggested.Lower && exception <= suggested.Upper {
+				suggested.Exceptions = append(suggested.Exceptions, exception)
+			}
+		}
+		suggestion.Suggested = suggested
+		suggestion.Reason = fmt.Sprintf("the bounds are wider than observed in %d single-run reports", o.samples)
+		return suggestion
+	}
+	return nil
+}
+
+func containsInt(ints []int, i int) bool {
+	for _, v := range ints {
+		if v == i {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedStringKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+)
+
+var auditStart = time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+
+// auditSnapshot has stage 1 dropping item 1 (30012) and item 2 (30013) as regular drops on CN from May 1 to 15,
+// of the item bounds and the bounds of the kinds of regular drops.
+func auditSnapshot(cubeBounds, clusterBounds, regularBounds *models.Bounds) *types.Snapshot {
+	end := auditStart.AddDate(0, 0, 14)
+	return &types.Snapshot{
+		Stages:     []*models.Stage{{StageID: 1, ArkStageID: "main_01-07"}},
+		Items:      []*models.Item{{ItemID: 1, ArkItemID: "30012"}, {ItemID: 2, ArkItemID: "30013"}},
+		TimeRanges: []*models.TimeRange{{RangeID: 1, Server: "CN", StartTime: &auditStart, EndTime: &end}},
+		DropInfos: []*models.DropInfo{
+			{DropID: 1, Server: "CN", StageID: 1, ItemID: null.IntFrom(1), DropType: "REGULAR", RangeID: 1, Bounds: cubeBounds},
+			{DropID: 2, Server: "CN", StageID: 1, ItemID: null.IntFrom(2), DropType: "REGULAR", RangeID: 1, Bounds: clusterBounds},
+			{DropID: 3, Server: "CN", StageID: 1, DropType: "REGULAR", RangeID: 1, Bounds: regularBounds},
+		},
+	}
+}
+
+// auditReports returns a report on stage 1 for each hash, made a day after the start of the time range, and the
+// patterns of the hashes.
+func auditReports(times int, hashes ...string) ([]*models.DropReport, []*models.DropPattern) {
+	createdAt := auditStart.AddDate(0, 0, 1)
+	reports := make([]*models.DropReport, 0, len(hashes))
+	patterns := make([]*models.DropPattern, 0, len(hashes))
+	for i, hash := range hashes {
+		reports = append(reports, &models.DropReport{ReportID: i + 1, StageID: 1, PatternID: i + 1, Times: times, CreatedAt: &createdAt, Server: "CN"})
+		patterns = append(patterns, &models.DropPattern{PatternID: i + 1, Hash: hash})
+	}
+	return reports, patterns
+}
+
+func TestAuditDropReportBounds(t *testing.T) {
+	snapshot := auditSnapshot(&models.Bounds{Lower: 1, Upper: 3, Exceptions: []int{2}}, &models.Bounds{Upper: 1}, &models.Bounds{Lower: 1, Upper: 2})
+	reports, patterns := auditReports(1,
+		"1:1",
+		// an exception
+		"1:2",
+		"1:1|2:1",
+		"1:4",
+		// an unknown item instead of the cube
+		"3:1",
+		"1:6",
+	)
+	// unreliable, but within the bounds
+	reports[2].Reliability = 1
+	// within the bounds of two runs
+	reports[5].Times = 2
+	before := auditStart.Add(-time.Hour)
+	reports = append(reports,
+		&models.DropReport{ReportID: 7, StageID: 1, PatternID: 99, Times: 1, CreatedAt: reports[0].CreatedAt, Server: "CN"},
+		&models.DropReport{ReportID: 8, StageID: 1, PatternID: 1, Times: 1, CreatedAt: &before, Server: "CN"},
+		&models.DropReport{ReportID: 9, StageID: 1, PatternID: 4, Times: 1, CreatedAt: reports[0].CreatedAt, Server: "US"},
+	)
+
+	audit, err := AuditDropReportBounds(snapshot, "CN", reports, patterns, BoundsAuditOptions{MinSamples: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	violations := make([]string, 0)
+	for _, violation := range audit.Violations {
+		violations = append(violations, fmt.Sprintf("%d %s %s %d", violation.Report.ReportID, violation.Kind, violation.Subject, violation.Value))
+	}
+	wantViolations := []string{
+		"2 ITEM_QUANTITY 30012 2",
+		"4 ITEM_QUANTITY 30012 4",
+		"5 UNKNOWN_ITEM #3 1",
+		"5 ITEM_QUANTITY 30012 0",
+		"5 TYPE_COUNT REGULAR 0",
+	}
+	if !reflect.DeepEqual(violations, wantViolations) {
+		t.Errorf("violations %q, want %q", violations, wantViolations)
+	}
+	if audit.WronglyAccepted != 3 || audit.WronglyRejected != 1 {
+		t.Errorf("wrongly accepted %d and rejected %d, want 3 and 1", audit.WronglyAccepted, audit.WronglyRejected)
+	}
+	wantSummary := &types.DropMatrixSummary{Reports: 8, Unreliable: 1, NoPattern: 1, NoTimeRange: 1}
+	if !reflect.DeepEqual(audit.Summary, wantSummary) {
+		t.Errorf("summary %+v, want %+v", audit.Summary, wantSummary)
+	}
+
+	// the bounds of the cube and of the kinds of regular drops are widened to what was observed in single runs,
+	// dropping the exception seen; the cluster has too few samples to be tightened
+	suggestions := make([]string, 0)
+	for _, suggestion := range audit.Suggestions {
+		suggestions = append(suggestions, fmt.Sprintf("%s %+v: %s", suggestion.Subject, *suggestion.Suggested, suggestion.Reason))
+	}
+	wantSuggestions := []string{
+		"30012 {Upper:4 Lower:0 Exceptions:[]}: 3 of 5 single-run reports violate the bounds; widen them if those drops are genuine",
+		"REGULAR {Upper:2 Lower:0 Exceptions:[]}: 1 of 5 single-run reports violate the bounds; widen them if those drops are genuine",
+	}
+	if !reflect.DeepEqual(suggestions, wantSuggestions) {
+		t.Errorf("suggestions %q, want %q", suggestions, wantSuggestions)
+	}
+}
+
+func TestAuditDropReportBoundsTighten(t *testing.T) {
+	tests := []struct {
+		name       string
+		minSamples int
+		want       []string
+	}{
+		{"enough samples", 3, []string{"30012 {Upper:3 Lower:1 Exceptions:[2]} of 3 samples", "REGULAR {Upper:2 Lower:1 Exceptions:[]} of 3 samples"}},
+		{"too few samples", 4, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the exception within the observed quantities is kept
+			snapshot := auditSnapshot(&models.Bounds{Upper: 5, Exceptions: []int{2, 5}}, &models.Bounds{Upper: 1}, &models.Bounds{Upper: 2})
+			reports, patterns := auditReports(1, "1:1", "1:3", "1:3|2:1")
+
+			audit, err := AuditDropReportBounds(snapshot, "CN", reports, patterns, BoundsAuditOptions{MinSamples: tt.minSamples})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(audit.Violations) != 0 {
+				t.Errorf("got %d violations, want none", len(audit.Violations))
+			}
+			suggestions := make([]string, 0)
+			for _, suggestion := range audit.Suggestions {
+				suggestions = append(suggestions, fmt.Sprintf("%s %+v of %d samples", suggestion.Subject, *suggestion.Suggested, suggestion.Samples))
+			}
+			if !reflect.DeepEqual(suggestions, tt.want) {
+				t.Errorf("suggestions %q, want %q", suggestions, tt.want)
+			}
+		})
+	}
+}
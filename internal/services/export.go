@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/cache"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+)
+
+// Export fetches every admin-managed entity past the caches, as a point-in-time copy of the admin data.
+// Notices are fetched by noticeService, which the game data service does not otherwise need, and only on
+// a best-effort basis.
+func (s *GameDataService) Export(ctx context.Context, noticeService *NoticeService) (*types.Snapshot, error) {
+	snapshot, err := s.LiveState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range []string{cache.TagAny(cache.TagKindItem), cache.TagAny(cache.TagKindNotice)} {
+		if err := cache.InvalidateTag(tag); err != nil {
+			log.Warn().Err(err).Str("tag", tag).Msg("failed to invalidate caches of the exported data")
+		}
+	}
+
+	items, err := s.ItemService.GetItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	activities, err := s.ActivityService.GetActivities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// notices are listed by an endpoint the admin api is not known to serve, so they are left out of the
+	// export rather than failing it
+	notices, err := noticeService.GetNotices(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to list notices; they are not exported")
+	}
+
+	// the slices are shared with the caches, so they are copied for callers to sort
+	snapshot.Items = append([]*models.Item(nil), items...)
+	snapshot.Activities = append([]*models.Activity(nil), activities...)
+	snapshot.Notices = append([]*models.Notice(nil), notices...)
+	return snapshot, nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,23 +16,35 @@ import (
 
 	"github.com/penguin-statistics/soracli/internal/consts"
 	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/cache"
 	"github.com/penguin-statistics/soracli/internal/models/gamedata"
 	"github.com/penguin-statistics/soracli/internal/pkg/client"
 	"github.com/penguin-statistics/soracli/internal/pkg/gdutils"
 )
 
-var ErrCannotGetFromRemote = errors.New("cannot get from remote")
+var (
+	ErrCannotGetFromRemote = errors.New("cannot get from remote")
+	ErrItemNotFound        = errors.New("item not found")
+)
 
 type GameDataService struct {
-	ItemService *ItemService
+	ItemService      *ItemService
+	StageService     *StageService
+	TimeRangeService *TimeRangeService
+	ZoneService      *ZoneService
+	ActivityService  *ActivityService
 
 	http     *http.Client
 	pgclient *client.Penguin
 }
 
-func NewGameDataService(itemService *ItemService, client *client.Penguin) *GameDataService {
+func NewGameDataService(itemService *ItemService, stageService *StageService, timeRangeService *TimeRangeService, zoneService *ZoneService, activityService *ActivityService, client *client.Penguin) *GameDataService {
 	return &GameDataService{
-		ItemService: itemService,
+		ItemService:      itemService,
+		StageService:     stageService,
+		TimeRangeService: timeRangeService,
+		ZoneService:      zoneService,
+		ActivityService:  activityService,
 		http: &http.Client{
 			Timeout: time.Second * 10,
 		},
@@ -49,6 +62,24 @@ var dropTypeOrderMapping = map[string]int{
 
 func (s *GameDataService) RenderNewEvent(ctx context.Context, sourceUrl string, info *gamedata.NewEventBasicInfo) (*gamedata.RenderedObjects, error) {
 	log.Info().Interface("info", info).Msg("rendering new event")
+	importStages, err := s.fetchLatestStages(ctx, sourceUrl, []string{info.ArkZoneId})
+	if err != nil {
+		return nil, err
+	}
+
+	findings, err := s.preflight(ctx, info, importStages)
+	if err != nil {
+		return nil, err
+	}
+	for _, finding := range findings {
+		if finding.Severity == gamedata.PreflightSeverityError {
+			return nil, &gamedata.PreflightError{Findings: findings}
+		}
+	}
+	for _, finding := range findings {
+		log.Warn().Str("subject", finding.Subject).Str("suggestion", finding.Suggestion).Msg(finding.Message)
+	}
+
 	zone, err := s.renderNewZone(info)
 	if err != nil {
 		return nil, err
@@ -65,11 +96,6 @@ func (s *GameDataService) RenderNewEvent(ctx context.Context, sourceUrl string,
 		return nil, err
 	}
 
-	importStages, err := s.fetchLatestStages(ctx, sourceUrl, []string{info.ArkZoneId})
-	if err != nil {
-		return nil, err
-	}
-
 	stages := make([]*models.Stage, 0)
 	dropInfosMap := make(map[string][]*models.DropInfo)
 	for _, gamedataStage := range importStages {
@@ -82,19 +108,142 @@ func (s *GameDataService) RenderNewEvent(ctx context.Context, sourceUrl string,
 		dropInfosMap[stage.ArkStageID] = dropInfosForOneStage
 	}
 
-	return &gamedata.RenderedObjects{
+	rendered := &gamedata.RenderedObjects{
 		Zone:         zone,
 		Stages:       stages,
 		DropInfosMap: dropInfosMap,
 		TimeRange:    timeRange,
 		Activity:     activity,
-	}, nil
+	}
+
+	timeRangeFindings, err := s.CheckRenderedTimeRange(ctx, rendered)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to check the rendered time range against existing time ranges; skipping")
+	}
+	for _, finding := range timeRangeFindings {
+		log.Warn().Str("subject", finding.Subject).Str("suggestion", finding.Suggestion).Msg(finding.Message)
+	}
+
+	return rendered, nil
 }
 
-func (s *GameDataService) UpdateNewEvent(ctx context.Context, renderedObjects *gamedata.RenderedObjects) error {
+// CheckRenderedTimeRange runs CheckTimeRanges over live data with the rendered time range and drop infos
+// added, reporting only the findings involving the rendered time range.
+func (s *GameDataService) CheckRenderedTimeRange(ctx context.Context, rendered *gamedata.RenderedObjects) ([]*gamedata.PreflightFinding, error) {
+	findings, err := s.CheckRenderedTimeRanges(ctx, []*gamedata.RenderedObjects{rendered})
+	if err != nil {
+		return nil, err
+	}
+	return findings[0], nil
+}
+
+// CheckRenderedTimeRanges is CheckRenderedTimeRange over bundles saved together: the time ranges and drop
+// infos of every bundle are added to live data at once, so that the bundles are also checked against each
+// other. The findings of each bundle are returned at its index.
+func (s *GameDataService) CheckRenderedTimeRanges(ctx context.Context, bundles []*gamedata.RenderedObjects) ([][]*gamedata.PreflightFinding, error) {
+	findings := make([][]*gamedata.PreflightFinding, len(bundles))
+	servers := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, rendered := range bundles {
+		if rendered.TimeRange != nil && !seen[rendered.TimeRange.Server] {
+			seen[rendered.TimeRange.Server] = true
+			servers = append(servers, rendered.TimeRange.Server)
+		}
+	}
+
+	for _, server := range servers {
+		snapshot, err := s.TimeRangeService.GetSnapshot(ctx, server)
+		if err != nil {
+			return nil, err
+		}
+
+		// the rendered objects have no IDs yet; they take negative ones so as not to collide with live ones
+		rangeIDs := make(map[int]int)
+		nextStageID := -1
+		stageIDs := make(map[string]int, len(snapshot.Stages))
+		for _, stage := range snapshot.Stages {
+			stageIDs[stage.ArkStageID] = stage.StageID
+		}
+		for i, rendered := range bundles {
+			if rendered.TimeRange == nil || rendered.TimeRange.Server != server {
+				continue
+			}
+			timeRange := *rendered.TimeRange
+			timeRange.RangeID = -1 - i
+			rangeIDs[i] = timeRange.RangeID
+			snapshot.TimeRanges = append(snapshot.TimeRanges, &timeRange)
+
+			for _, stage := range rendered.Stages {
+				stageID, ok := stageIDs[stage.ArkStageID]
+				if !ok {
+					renderedStage := *stage
+					renderedStage.StageID = nextStageID
+					nextStageID--
+					snapshot.Stages = append(snapshot.Stages, &renderedStage)
+					stageIDs[stage.ArkStageID] = renderedStage.StageID
+					stageID = renderedStage.StageID
+				}
+				for _, dropInfo := range rendered.DropInfosMap[stage.ArkStageID] {
+					renderedDropInfo := *dropInfo
+					renderedDropInfo.StageID = stageID
+					renderedDropInfo.RangeID = timeRange.RangeID
+					snapshot.DropInfos = append(snapshot.DropInfos, &renderedDropInfo)
+				}
+			}
+		}
+
+		for i, rangeID := range rangeIDs {
+			findings[i] = CheckTimeRanges(snapshot, server, rangeID)
+		}
+	}
+	return findings, nil
+}
+
+// UpdateNewEvent saves the rendered objects, and returns the IDs of the entities created or updated, so
+// that the bundle can be undone. The IDs are taken from the response of the admin api, or looked up
+// afterwards if it has none. Telling them is only a best effort: if the entities cannot be listed, the
+// bundle is saved all the same, and the returned bundle is nil.
+func (s *GameDataService) UpdateNewEvent(ctx context.Context, renderedObjects *gamedata.RenderedObjects) (*gamedata.AppliedBundle, error) {
 	log.Trace().Interface("renderedObjects", renderedObjects).Msg("updating new event")
 
-	return s.pgclient.PostJSON("/save", renderedObjects)
+	before, err := s.entitiesBeforeSave(ctx, renderedObjects)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to list the entities before saving; the bundle will not be undoable")
+	}
+
+	var resp json.RawMessage
+	if err := s.pgclient.PostJSONWithResponse("/save", renderedObjects, &resp); err != nil {
+		return nil, err
+	}
+
+	tags := []string{
+		cache.TagZone(renderedObjects.Zone.ArkZoneID),
+		cache.TagAny(cache.TagKindTimeRange),
+		cache.TagAny(cache.TagKindDropInfo),
+		cache.TagAny(cache.TagKindActivity),
+	}
+	for _, stage := range renderedObjects.Stages {
+		tags = append(tags, cache.TagStage(stage.ArkStageID))
+	}
+	for _, tag := range tags {
+		if err := cache.InvalidateTag(tag); err != nil {
+			log.Warn().Err(err).Str("tag", tag).Msg("failed to invalidate caches of the new event")
+		}
+	}
+
+	if before == nil {
+		return nil, nil
+	}
+	var saved gamedata.RenderedObjects
+	if err := json.Unmarshal(resp, &saved); err != nil {
+		log.Debug().Err(err).Msg("the response of /save has no saved objects; looking up their IDs instead")
+	}
+	applied, err := s.resolveAppliedBundle(ctx, renderedObjects, &saved, before)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to tell the entities the bundle saved; it will not be undoable")
+		return nil, nil
+	}
+	return applied, nil
 }
 
 func (s *GameDataService) renderNewZone(info *gamedata.NewEventBasicInfo) (*models.Zone, error) {
@@ -204,14 +353,12 @@ func (s *GameDataService) renderNewActivity(info *gamedata.NewEventBasicInfo) (*
 	}, nil
 }
 
-func (s *GameDataService) fetchLatestStages(ctx context.Context, sourceUrl string, arkZoneIds []string) ([]*gamedata.Stage, error) {
+func (s *GameDataService) fetch(ctx context.Context, sourceUrl string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceUrl, http.NoBody)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Debug().Str("url", sourceUrl).Msg("fetching latest stages")
-
 	res, err := s.http.Do(req)
 	if err != nil {
 		return nil, err
@@ -221,7 +368,13 @@ func (s *GameDataService) fetchLatestStages(ctx context.Context, sourceUrl strin
 	}
 	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
+	return io.ReadAll(res.Body)
+}
+
+func (s *GameDataService) fetchLatestStages(ctx context.Context, sourceUrl string, arkZoneIds []string) ([]*gamedata.Stage, error) {
+	log.Debug().Str("url", sourceUrl).Msg("fetching latest stages")
+
+	body, err := s.fetch(ctx, sourceUrl)
 	if err != nil {
 		return nil, err
 	}
@@ -238,11 +391,8 @@ func (s *GameDataService) fetchLatestStages(ctx context.Context, sourceUrl strin
 	}
 
 	importStages := make([]*gamedata.Stage, 0)
+	importZoneIds := make(map[string]bool)
 	for _, stage := range stageMap {
-		if len(arkZoneIds) > 0 && !linq.From(arkZoneIds).Contains(stage.ZoneID) {
-			continue
-		}
-
 		switch {
 		case gdutils.IsCampaignStage(stage):
 		case gdutils.IsGuideStage(stage):
@@ -253,9 +403,14 @@ func (s *GameDataService) fetchLatestStages(ctx context.Context, sourceUrl strin
 		case gdutils.IsNormalModeExStage(stage):
 		case gdutils.IsEasyDiffGroupStage(stage):
 		default:
-			importStages = append(importStages, stage)
+			importZoneIds[stage.ZoneID] = true
+			if len(arkZoneIds) == 0 || linq.From(arkZoneIds).Contains(stage.ZoneID) {
+				importStages = append(importStages, stage)
+			}
 		}
 	}
+	s.recordStageTableZones(sourceUrl, importZoneIds)
+
 	linq.From(importStages).
 		DistinctByT(func(stage *gamedata.Stage) string { return stage.StageID }).
 		SortT(func(a, b *gamedata.Stage) bool { return gdutils.CompareStageCode(a.Code, b.Code) }).
@@ -265,6 +420,46 @@ func (s *GameDataService) fetchLatestStages(ctx context.Context, sourceUrl strin
 	return importStages, nil
 }
 
+// recordStageTableZones caches the IDs of the zones with stages to import of the stage table at sourceUrl,
+// for shell completion to offer without fetching it.
+func (s *GameDataService) recordStageTableZones(sourceUrl string, zoneIds map[string]bool) {
+	if len(zoneIds) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(zoneIds))
+	for id := range zoneIds {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if err := cache.StageTableZoneIDs.Set(sourceUrl, ids, 30*24*time.Hour); err != nil {
+		log.Warn().Err(err).Str("url", sourceUrl).Msg("failed to cache the zones of the stage table")
+	}
+}
+
+// FetchZoneStages fetches the stage table at sourceUrl and groups the stages render imports by their zones,
+// sorted by zone ID.
+func (s *GameDataService) FetchZoneStages(ctx context.Context, sourceUrl string) ([]*gamedata.ZoneStages, error) {
+	stages, err := s.fetchLatestStages(ctx, sourceUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	zones := make([]*gamedata.ZoneStages, 0)
+	linq.From(stages).
+		GroupByT(
+			func(stage *gamedata.Stage) string { return stage.ZoneID },
+			func(stage *gamedata.Stage) *gamedata.Stage { return stage },
+		).
+		SelectT(func(group linq.Group) *gamedata.ZoneStages {
+			zone := &gamedata.ZoneStages{ArkZoneID: group.Key.(string)}
+			linq.From(group.Group).ToSlice(&zone.Stages)
+			return zone
+		}).
+		SortT(func(a, b *gamedata.ZoneStages) bool { return a.ArkZoneID < b.ArkZoneID }).
+		ToSlice(&zones)
+	return zones, nil
+}
+
 func (s *GameDataService) genStageAndDropInfosFromGameData(ctx context.Context, server string, gamedataStage *gamedata.Stage, zoneId int, timeRange *models.TimeRange, isMainZone bool) (*models.Stage, []*models.DropInfo, error) {
 	codeMap := make(map[string]string)
 	for _, lang := range consts.Languages {
@@ -309,6 +504,9 @@ func (s *GameDataService) genStageAndDropInfosFromGameData(ctx context.Context,
 	if err != nil {
 		return nil, nil, err
 	}
+	if gamedataStage.StageDropInfo == nil {
+		return nil, nil, errors.Errorf("stage %s has no stageDropInfo", gamedataStage.StageID)
+	}
 	var activityToken string
 	for _, reward := range gamedataStage.StageDropInfo.DisplayDetailRewards {
 		if reward.Type == consts.ItemTypeActivity && activityToken == "" {
@@ -340,7 +538,10 @@ func (s *GameDataService) genStageAndDropInfosFromGameData(ctx context.Context,
 		items := make([]*models.Item, 0)
 		for _, reward := range rewards {
 			if reward.Type == consts.ItemTypeMaterial || reward.Type == consts.ItemTypeCardExp {
-				item := itemsMap[reward.Id]
+				item, ok := itemsMap[reward.Id]
+				if !ok {
+					return nil, nil, errors.Wrapf(ErrItemNotFound, "item %s dropped by stage %s; add it with `soracli item add %s`", reward.Id, gamedataStage.StageID, reward.Id)
+				}
 				items = append(items, item)
 				var bounds *models.Bounds
 				if isMainZone == true {
@@ -373,7 +574,10 @@ func (s *GameDataService) genStageAndDropInfosFromGameData(ctx context.Context,
 
 	// add dropinfo for furniture
 	if gamedataStage.ApCost != 0 {
-		item := itemsMap[consts.FurnitureArkItemID]
+		item, ok := itemsMap[consts.FurnitureArkItemID]
+		if !ok {
+			return nil, nil, errors.Wrapf(ErrItemNotFound, "furniture item %s", consts.FurnitureArkItemID)
+		}
 		dropInfos = append(dropInfos, &models.DropInfo{
 			Server:      server,
 			ItemID:      null.IntFrom(int64(item.ItemID)),
@@ -448,3 +652,272 @@ func (s *GameDataService) decideDropTypeBounds(dropType string, items []*models.
 	}
 	return nil
 }
+
+// FetchItemTables fetches item_table.json of every server. sourceUrlTemplate is the URL of
+// item_table.json with the region replaced by consts.GameDataRegionPlaceholder.
+// Servers whose item table cannot be fetched are logged and left out of the result.
+func (s *GameDataService) FetchItemTables(ctx context.Context, sourceUrlTemplate string) (map[string]*gamedata.ItemTable, error) {
+	itemTables := make(map[string]*gamedata.ItemTable)
+	for _, server := range consts.Servers {
+		sourceUrl := strings.ReplaceAll(sourceUrlTemplate, consts.GameDataRegionPlaceholder, consts.ServerGameDataRegions[server])
+		log.Debug().Str("url", sourceUrl).Str("server", server).Msg("fetching item table")
+
+		body, err := s.fetch(ctx, sourceUrl)
+		if err != nil {
+			log.Warn().Err(err).Str("server", server).Msg("failed to fetch item table; skipping server")
+			continue
+		}
+
+		var itemTable gamedata.ItemTable
+		if err := json.Unmarshal(body, &itemTable); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse item table of server %s", server)
+		}
+		itemTables[server] = &itemTable
+	}
+	if len(itemTables) == 0 {
+		return nil, ErrCannotGetFromRemote
+	}
+	return itemTables, nil
+}
+
+// RenderNewItem renders a new item from the item tables of every server. The sort ID and
+// group are suggestions derived from the items already known to Penguin.
+func (s *GameDataService) RenderNewItem(ctx context.Context, itemTables map[string]*gamedata.ItemTable, arkItemId string) (*models.Item, error) {
+	items, err := s.ItemService.GetItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.ArkItemID == arkItemId {
+			return nil, errors.Errorf("item %s already exists as penguinItemId %d", arkItemId, item.ItemID)
+		}
+	}
+
+	return renderNewItem(items, itemTables, arkItemId)
+}
+
+func renderNewItem(items []*models.Item, itemTables map[string]*gamedata.ItemTable, arkItemId string) (*models.Item, error) {
+	var base *gamedata.Item
+	nameMap := make(map[string]string)
+	existence := make(models.Existence)
+	for _, server := range consts.Servers {
+		existence[server] = &models.ServerExistence{Exist: false}
+		itemTable, ok := itemTables[server]
+		if !ok {
+			continue
+		}
+		gamedataItem, ok := itemTable.Items[arkItemId]
+		if !ok {
+			continue
+		}
+		existence[server].Exist = true
+		nameMap[consts.ServerLanguages[server]] = gamedataItem.Name
+		if base == nil {
+			base = gamedataItem
+		}
+	}
+	if base == nil {
+		return nil, errors.Wrapf(ErrItemNotFound, "item %s is not in the item table of any server", arkItemId)
+	}
+	// fall back to the name in the primary language for servers without the item yet
+	for _, lang := range consts.Languages {
+		if _, ok := nameMap[lang]; !ok {
+			nameMap[lang] = base.Name
+		}
+	}
+
+	name, err := json.Marshal(nameMap)
+	if err != nil {
+		return nil, err
+	}
+	existenceJSON, err := existence.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Item{
+		ArkItemID: arkItemId,
+		Name:      name,
+		Existence: existenceJSON,
+		Type:      base.ItemType,
+		SortID:    suggestItemSortID(items, itemTables, base),
+		Rarity:    int(base.Rarity),
+		Group:     suggestItemGroup(items, arkItemId),
+	}, nil
+}
+
+// suggestItemSortID places the new item right after the known item that precedes it
+// in the game's own sort order.
+func suggestItemSortID(items []*models.Item, itemTables map[string]*gamedata.ItemTable, base *gamedata.Item) int {
+	var preceding *models.Item
+	precedingGameSortID := -1
+	for _, item := range items {
+		for _, server := range consts.Servers {
+			itemTable, ok := itemTables[server]
+			if !ok {
+				continue
+			}
+			gamedataItem, ok := itemTable.Items[item.ArkItemID]
+			if !ok {
+				continue
+			}
+			if gamedataItem.SortID <= base.SortID && gamedataItem.SortID > precedingGameSortID {
+				preceding = item
+				precedingGameSortID = gamedataItem.SortID
+			}
+			break
+		}
+	}
+	if preceding != nil {
+		return preceding.SortID + 1
+	}
+
+	max := 0
+	for _, item := range items {
+		if item.SortID > max {
+			max = item.SortID
+		}
+	}
+	return max + 1
+}
+
+// suggestItemGroup returns the group of a known item of the same family. Items of one family
+// differ only in the last digit of their ark item IDs, e.g. 30011 to 30014 are all orirocks.
+func suggestItemGroup(items []*models.Item, arkItemId string) null.String {
+	if len(arkItemId) < 2 {
+		return null.String{}
+	}
+	family := arkItemId[:len(arkItemId)-1]
+	for _, item := range items {
+		if item.Group.Valid && len(item.ArkItemID) == len(arkItemId) && strings.HasPrefix(item.ArkItemID, family) {
+			return item.Group
+		}
+	}
+	return null.String{}
+}
+
+// DiffItems compares the item tables of every server with the items known to Penguin, limited
+// to items of itemTypes, and renders the payloads that would bring Penguin up to date.
+func (s *GameDataService) DiffItems(ctx context.Context, itemTables map[string]*gamedata.ItemTable, itemTypes []string) (*gamedata.ItemSyncReport, error) {
+	items, err := s.ItemService.GetItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	itemsMap := make(map[string]*models.Item)
+	for _, item := range items {
+		itemsMap[item.ArkItemID] = item
+	}
+
+	report := &gamedata.ItemSyncReport{
+		Payload: &gamedata.ItemSyncPayload{
+			Create: make([]*models.Item, 0),
+			Update: make([]*models.Item, 0),
+		},
+	}
+
+	missing := make(map[string]*gamedata.MissingItem)
+	updates := make(map[string]*models.Item)
+	for _, server := range consts.Servers {
+		itemTable, ok := itemTables[server]
+		if !ok {
+			continue
+		}
+		lang := consts.ServerLanguages[server]
+
+		for arkItemId, gamedataItem := range itemTable.Items {
+			if !linq.From(itemTypes).Contains(gamedataItem.ItemType) {
+				continue
+			}
+
+			item, ok := itemsMap[arkItemId]
+			if !ok {
+				if _, ok := missing[arkItemId]; !ok {
+					missing[arkItemId] = &gamedata.MissingItem{ArkItemID: arkItemId, Name: gamedataItem.Name}
+				}
+				missing[arkItemId].Servers = append(missing[arkItemId].Servers, server)
+				continue
+			}
+
+			nameMap := make(map[string]string)
+			if err := json.Unmarshal(item.Name, &nameMap); err != nil {
+				return nil, errors.Wrapf(err, "invalid name of item %s", arkItemId)
+			}
+			existence, err := models.ParseExistence(item.Existence)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid existence of item %s", arkItemId)
+			}
+
+			nameDiffers := nameMap[lang] != gamedataItem.Name
+			existenceDiffers := existence[server] == nil || !existence[server].Exist
+			if !nameDiffers && !existenceDiffers {
+				continue
+			}
+
+			update, ok := updates[arkItemId]
+			if !ok {
+				copied := *item
+				update = &copied
+				updates[arkItemId] = update
+			}
+			if nameDiffers {
+				report.NameMismatches = append(report.NameMismatches, &gamedata.ItemNameMismatch{
+					ItemID:       item.ItemID,
+					ArkItemID:    arkItemId,
+					Lang:         lang,
+					PenguinName:  nameMap[lang],
+					GameDataName: gamedataItem.Name,
+				})
+				if err := patchJSONObject(&update.Name, lang, gamedataItem.Name); err != nil {
+					return nil, err
+				}
+			}
+			if existenceDiffers {
+				report.ExistenceMismatches = append(report.ExistenceMismatches, &gamedata.ItemExistenceMismatch{
+					ItemID:    item.ItemID,
+					ArkItemID: arkItemId,
+					Server:    server,
+				})
+				if err := patchJSONObject(&update.Existence, server, &models.ServerExistence{Exist: true}); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for arkItemId, missingItem := range missing {
+		report.Missing = append(report.Missing, missingItem)
+		item, err := renderNewItem(items, itemTables, arkItemId)
+		if err != nil {
+			return nil, err
+		}
+		report.Payload.Create = append(report.Payload.Create, item)
+	}
+	for _, update := range updates {
+		report.Payload.Update = append(report.Payload.Update, update)
+	}
+
+	linq.From(report.Missing).SortT(func(a, b *gamedata.MissingItem) bool { return a.ArkItemID < b.ArkItemID }).ToSlice(&report.Missing)
+	linq.From(report.NameMismatches).SortT(func(a, b *gamedata.ItemNameMismatch) bool { return a.ItemID < b.ItemID }).ToSlice(&report.NameMismatches)
+	linq.From(report.ExistenceMismatches).SortT(func(a, b *gamedata.ItemExistenceMismatch) bool { return a.ItemID < b.ItemID }).ToSlice(&report.ExistenceMismatches)
+	linq.From(report.Payload.Create).SortT(func(a, b *models.Item) bool { return a.SortID < b.SortID }).ToSlice(&report.Payload.Create)
+	linq.From(report.Payload.Update).SortT(func(a, b *models.Item) bool { return a.ItemID < b.ItemID }).ToSlice(&report.Payload.Update)
+
+	return report, nil
+}
+
+// patchJSONObject sets key of the JSON object in raw to value.
+func patchJSONObject(raw *json.RawMessage, key string, value any) error {
+	m := make(map[string]any)
+	if len(*raw) > 0 && string(*raw) != "null" {
+		if err := json.Unmarshal(*raw, &m); err != nil {
+			return err
+		}
+	}
+	m[key] = value
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	*raw = b
+	return nil
+}
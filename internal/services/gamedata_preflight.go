@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+)
+
+// knownRewardTypes are the reward types of stage_table.json that rendering knows about,
+// either to render drop infos from or to deliberately skip.
+var knownRewardTypes = map[string]bool{
+	consts.ItemTypeCardExp:    true,
+	consts.ItemTypeMaterial:   true,
+	consts.ItemTypeChip:       true,
+	consts.ItemTypeFurniture:  true,
+	consts.ItemTypeArkPlanner: true,
+	consts.ItemTypeActivity:   true,
+	consts.ItemTypeTemp:       true,
+	consts.ItemTypeLggShd:     true,
+	consts.ItemTypeGold:       true,
+	consts.ItemTypeDiamond:    true,
+	consts.ItemTypeDiamondShd: true,
+	consts.ItemTypeChar:       true,
+	consts.ItemTypeExpPlayer:  true,
+}
+
+var knownDropTypes = map[string]bool{
+	consts.StageTableDropTypeNormal:     true,
+	consts.StageTableDropTypeSpecial:    true,
+	consts.StageTableDropTypeAdditional: true,
+	consts.StageTableDropTypeOnce:       true,
+	consts.StageTableDropTypeComplete:   true,
+}
+
+// preflight checks everything rendering relies on before any object is built, so that
+// problems in the source data are reported together instead of failing halfway.
+func (s *GameDataService) preflight(ctx context.Context, info *gamedata.NewEventBasicInfo, importStages []*gamedata.Stage) ([]*gamedata.PreflightFinding, error) {
+	findings := make([]*gamedata.PreflightFinding, 0)
+
+	if _, ok := consts.LocMap[info.Server]; !ok {
+		findings = append(findings, &gamedata.PreflightFinding{
+			Severity:   gamedata.PreflightSeverityError,
+			Subject:    "server " + info.Server,
+			Message:    "no timezone entry in consts.LocMap",
+			Suggestion: fmt.Sprintf("check --server is one of %v, or add a location for %s to consts.LocMap", consts.Servers, info.Server),
+		})
+	}
+
+	if len(importStages) == 0 {
+		findings = append(findings, &gamedata.PreflightFinding{
+			Severity:   gamedata.PreflightSeverityError,
+			Subject:    "zone " + info.ArkZoneId,
+			Message:    "no stages to import from the source table",
+			Suggestion: "check --ark-zone-id and --sourceUrl; retro zones need retro_table.json",
+		})
+	}
+
+	itemsMap, err := s.ItemService.GetItemsMapByArkId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reportedItems := make(map[string]bool)
+	reportedRewardTypes := make(map[string]bool)
+	reportedDropTypes := make(map[string]bool)
+	needsFurniture := false
+	for _, stage := range importStages {
+		if stage.ApCost != 0 {
+			needsFurniture = true
+		}
+		if stage.StageDropInfo == nil {
+			findings = append(findings, &gamedata.PreflightFinding{
+				Severity:   gamedata.PreflightSeverityError,
+				Subject:    "stage " + stage.StageID,
+				Message:    "stage has no stageDropInfo",
+				Suggestion: "check that --sourceUrl points to a complete, up-to-date stage table",
+			})
+			continue
+		}
+
+		for _, reward := range stage.StageDropInfo.DisplayDetailRewards {
+			if !knownRewardTypes[reward.Type] && !reportedRewardTypes[reward.Type] {
+				reportedRewardTypes[reward.Type] = true
+				findings = append(findings, &gamedata.PreflightFinding{
+					Severity:   gamedata.PreflightSeverityWarning,
+					Subject:    "reward type " + reward.Type,
+					Message:    fmt.Sprintf("unknown reward type (first seen on %s, item %s); it will not be rendered", stage.StageID, reward.Id),
+					Suggestion: "add it to consts and handle it in genStageAndDropInfosFromGameData if it should be tracked",
+				})
+			}
+			if !knownDropTypes[reward.DropType] && !reportedDropTypes[reward.DropType] {
+				reportedDropTypes[reward.DropType] = true
+				findings = append(findings, &gamedata.PreflightFinding{
+					Severity:   gamedata.PreflightSeverityWarning,
+					Subject:    "drop type " + reward.DropType,
+					Message:    fmt.Sprintf("unknown drop type (first seen on %s, item %s); it will not be rendered", stage.StageID, reward.Id),
+					Suggestion: "add it to consts.StageTableDropType* and gdutils.RewardTypeMap if it should be rendered",
+				})
+			}
+
+			if reward.Type != consts.ItemTypeMaterial && reward.Type != consts.ItemTypeCardExp {
+				continue
+			}
+			if _, ok := itemsMap[reward.Id]; !ok && !reportedItems[reward.Id] {
+				reportedItems[reward.Id] = true
+				findings = append(findings, &gamedata.PreflightFinding{
+					Severity:   gamedata.PreflightSeverityError,
+					Subject:    "item " + reward.Id,
+					Message:    fmt.Sprintf("unknown item dropped by %s", stage.StageID),
+					Suggestion: "soracli item add " + reward.Id,
+				})
+			}
+		}
+	}
+
+	if _, ok := itemsMap[consts.FurnitureArkItemID]; needsFurniture && !ok {
+		findings = append(findings, &gamedata.PreflightFinding{
+			Severity:   gamedata.PreflightSeverityError,
+			Subject:    "item " + consts.FurnitureArkItemID,
+			Message:    "furniture item is missing from the item seed",
+			Suggestion: "check the item seed of the admin api contains item " + consts.FurnitureArkItemID,
+		})
+	}
+
+	return findings, nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+)
+
+func TestPreflight(t *testing.T) {
+	reward := func(id, dropType, rewardType string) *gamedata.DisplayDetailReward {
+		return &gamedata.DisplayDetailReward{Id: id, DropType: dropType, Type: rewardType}
+	}
+	stage := func(id string, apCost int, rewards ...*gamedata.DisplayDetailReward) *gamedata.Stage {
+		return &gamedata.Stage{StageID: id, ApCost: apCost, StageDropInfo: &gamedata.StageDropInfo{DisplayDetailRewards: rewards}}
+	}
+	known := &models.Item{ItemID: 1, ArkItemID: "30012"}
+	furniture := &models.Item{ItemID: 2, ArkItemID: consts.FurnitureArkItemID}
+
+	// want holds the severity and subject of each finding
+	tests := []struct {
+		name   string
+		server string
+		items  []*models.Item
+		stages []*gamedata.Stage
+		want   []string
+	}{
+		{
+			name:   "known data",
+			server: "CN",
+			items:  []*models.Item{known, furniture},
+			stages: []*gamedata.Stage{stage("act1side_01", 9, reward("30012", consts.StageTableDropTypeNormal, consts.ItemTypeMaterial))},
+		},
+		{
+			name:   "unknown server and no stages",
+			server: "TW",
+			items:  []*models.Item{known, furniture},
+			want:   []string{"ERROR server TW", "ERROR zone act1side_zone1"},
+		},
+		{
+			// each unknown reward type, drop type and item is reported once, at the first stage of it
+			name:   "unknown reward type, drop type and item",
+			server: "CN",
+			items:  []*models.Item{known, furniture},
+			stages: []*gamedata.Stage{
+				stage("act1side_01", 9,
+					reward("30012", consts.StageTableDropTypeNormal, "NEW_REWARD_TYPE"),
+					reward("30012", "NEW_DROP_TYPE", consts.ItemTypeMaterial),
+					reward("31093", consts.StageTableDropTypeNormal, consts.ItemTypeMaterial),
+				),
+				stage("act1side_02", 9,
+					reward("30012", consts.StageTableDropTypeNormal, "NEW_REWARD_TYPE"),
+					reward("31093", consts.StageTableDropTypeNormal, consts.ItemTypeMaterial),
+				),
+			},
+			want: []string{"WARNING reward type NEW_REWARD_TYPE", "WARNING drop type NEW_DROP_TYPE", "ERROR item 31093"},
+		},
+		{
+			// unknown items of other reward types are not rendered, so they are not required
+			name:   "unknown furniture piece",
+			server: "CN",
+			items:  []*models.Item{known, furniture},
+			stages: []*gamedata.Stage{stage("act1side_01", 9, reward("furni_1", consts.StageTableDropTypeSpecial, consts.ItemTypeFurniture))},
+		},
+		{
+			name:   "missing stage drop info",
+			server: "CN",
+			items:  []*models.Item{known, furniture},
+			stages: []*gamedata.Stage{{StageID: "act1side_01", ApCost: 9}},
+			want:   []string{"ERROR stage act1side_01"},
+		},
+		{
+			name:   "missing furniture item",
+			server: "CN",
+			items:  []*models.Item{known},
+			stages: []*gamedata.Stage{stage("act1side_01", 9), stage("act1side_st01", 0)},
+			want:   []string{"ERROR item " + consts.FurnitureArkItemID},
+		},
+		{
+			// stages without sanity cost drop no furniture
+			name:   "no furniture needed",
+			server: "CN",
+			items:  []*models.Item{known},
+			stages: []*gamedata.Stage{stage("act1side_st01", 0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestGameDataService(t, &fakeBackend{items: tt.items})
+
+			findings, err := s.preflight(context.Background(), &gamedata.NewEventBasicInfo{ArkZoneId: "act1side_zone1", Server: tt.server}, tt.stages)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0)
+			for _, finding := range findings {
+				got = append(got, finding.Severity+" "+finding.Subject)
+			}
+			want := tt.want
+			if want == nil {
+				want = []string{}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("findings %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -2,8 +2,11 @@ package services
 
 import (
 	"context"
+	"strconv"
 	"time"
 
+	"github.com/rs/zerolog/log"
+
 	"github.com/penguin-statistics/soracli/internal/models"
 	"github.com/penguin-statistics/soracli/internal/models/cache"
 	"github.com/penguin-statistics/soracli/internal/models/types"
@@ -53,3 +56,27 @@ func (s *ItemService) GetItemsMapByArkId(ctx context.Context) (map[string]*model
 	}
 	return itemsMapByArkId, nil
 }
+
+func (s *ItemService) CreateItem(ctx context.Context, item *models.Item) (*models.Item, error) {
+	var created models.Item
+	if err := s.http.PostJSONWithResponse("/items", item, &created); err != nil {
+		return nil, err
+	}
+	s.invalidate(item.ArkItemID)
+	return &created, nil
+}
+
+func (s *ItemService) UpdateItem(ctx context.Context, item *models.Item) (*models.Item, error) {
+	var updated models.Item
+	if err := s.http.PostJSONWithResponse("/items/"+strconv.Itoa(item.ItemID), item, &updated); err != nil {
+		return nil, err
+	}
+	s.invalidate(item.ArkItemID)
+	return &updated, nil
+}
+
+func (s *ItemService) invalidate(arkItemId string) {
+	if err := cache.InvalidateTag(cache.TagItem(arkItemId)); err != nil {
+		log.Warn().Err(err).Str("arkItemId", arkItemId).Msg("failed to invalidate item caches")
+	}
+}
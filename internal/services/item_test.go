@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+)
+
+// testItemTables has the orirocks on every server, and the orirock cluster, introduced on CN, on CN only.
+func testItemTables() map[string]*gamedata.ItemTable {
+	itemTables := make(map[string]*gamedata.ItemTable)
+	for _, server := range consts.Servers {
+		itemTables[server] = &gamedata.ItemTable{Items: map[string]*gamedata.Item{
+			"30011": {ItemID: "30011", Name: "Orirock " + server, SortID: 10, ItemType: consts.ItemTypeMaterial, Rarity: 0},
+			"30012": {ItemID: "30012", Name: "Orirock Cube " + server, SortID: 20, ItemType: consts.ItemTypeMaterial, Rarity: 1},
+			"4001":  {ItemID: "4001", Name: "LMD " + server, SortID: 1, ItemType: consts.ItemTypeGold},
+		}}
+	}
+	itemTables["CN"].Items["30013"] = &gamedata.Item{ItemID: "30013", Name: "Orirock Cluster", SortID: 30, ItemType: consts.ItemTypeMaterial, Rarity: 2}
+	return itemTables
+}
+
+func testItem(itemID int, arkItemID string, sortID int, group string, names map[string]string, servers ...string) *models.Item {
+	name, _ := json.Marshal(names)
+	existence := make(models.Existence)
+	for _, server := range consts.Servers {
+		existence[server] = &models.ServerExistence{Exist: false}
+	}
+	for _, server := range servers {
+		existence[server].Exist = true
+	}
+	existenceJSON, _ := existence.Marshal()
+	return &models.Item{
+		ItemID:    itemID,
+		ArkItemID: arkItemID,
+		Name:      name,
+		Existence: existenceJSON,
+		Type:      consts.ItemTypeMaterial,
+		SortID:    sortID,
+		Group:     null.NewString(group, group != ""),
+	}
+}
+
+func TestSuggestItemSortID(t *testing.T) {
+	items := []*models.Item{
+		testItem(1, "30011", 100, "", nil),
+		testItem(2, "30012", 200, "", nil),
+		// not in any item table
+		testItem(3, "99999", 900, "", nil),
+	}
+	tests := []struct {
+		name       string
+		gameSortID int
+		want       int
+	}{
+		{"after the last preceding item", 30, 201},
+		{"between two items", 15, 101},
+		{"before every item", 5, 901},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := suggestItemSortID(items, testItemTables(), &gamedata.Item{SortID: tt.gameSortID})
+			if got != tt.want {
+				t.Errorf("suggestItemSortID() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestItemGroup(t *testing.T) {
+	items := []*models.Item{
+		testItem(1, "30011", 100, "", nil),
+		testItem(2, "30012", 200, "orirock", nil),
+		testItem(3, "300121", 300, "other", nil),
+	}
+	tests := []struct {
+		arkItemID string
+		want      null.String
+	}{
+		{"30013", null.StringFrom("orirock")},
+		{"30021", null.String{}},
+		// longer IDs of the same prefix are another family
+		{"300122", null.StringFrom("other")},
+		{"3", null.String{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arkItemID, func(t *testing.T) {
+			if got := suggestItemGroup(items, tt.arkItemID); got != tt.want {
+				t.Errorf("suggestItemGroup(%s) = %v, want %v", tt.arkItemID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffItems(t *testing.T) {
+	rockNames, cubeNames := make(map[string]string), make(map[string]string)
+	for _, server := range consts.Servers {
+		rockNames[consts.ServerLanguages[server]] = "Orirock " + server
+		cubeNames[consts.ServerLanguages[server]] = "Orirock Cube " + server
+	}
+	// the US name is outdated
+	cubeNames["en"] = "Orirock Cube (old)"
+
+	backend := &fakeBackend{items: []*models.Item{
+		testItem(1, "30011", 100, "orirock", rockNames, consts.Servers...),
+		// not yet shown on KR
+		testItem(2, "30012", 200, "orirock", cubeNames, "CN", "US", "JP"),
+	}}
+	s := newTestGameDataService(t, backend)
+
+	report, err := s.DiffItems(context.Background(), testItemTables(), []string{consts.ItemTypeMaterial})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantMissing := []*gamedata.MissingItem{{ArkItemID: "30013", Name: "Orirock Cluster", Servers: []string{"CN"}}}
+	if !reflect.DeepEqual(report.Missing, wantMissing) {
+		t.Errorf("missing %+v, want %+v", report.Missing, wantMissing)
+	}
+	wantNames := []*gamedata.ItemNameMismatch{{ItemID: 2, ArkItemID: "30012", Lang: "en", PenguinName: "Orirock Cube (old)", GameDataName: "Orirock Cube US"}}
+	if !reflect.DeepEqual(report.NameMismatches, wantNames) {
+		t.Errorf("name mismatches %+v, want %+v", report.NameMismatches, wantNames)
+	}
+	wantExistences := []*gamedata.ItemExistenceMismatch{{ItemID: 2, ArkItemID: "30012", Server: "KR"}}
+	if !reflect.DeepEqual(report.ExistenceMismatches, wantExistences) {
+		t.Errorf("existence mismatches %+v, want %+v", report.ExistenceMismatches, wantExistences)
+	}
+
+	if len(report.Payload.Create) != 1 {
+		t.Fatalf("payload creates %d items, want 1", len(report.Payload.Create))
+	}
+	created := report.Payload.Create[0]
+	if created.ArkItemID != "30013" || created.SortID != 201 || created.Group != null.StringFrom("orirock") || created.Rarity != 2 {
+		t.Errorf("payload creates %+v, want 30013 after the cube in its group", created)
+	}
+	if len(report.Payload.Update) != 1 {
+		t.Fatalf("payload updates %d items, want 1", len(report.Payload.Update))
+	}
+	updated := report.Payload.Update[0]
+	updatedNames := make(map[string]string)
+	if err := json.Unmarshal(updated.Name, &updatedNames); err != nil {
+		t.Fatal(err)
+	}
+	existence, err := models.ParseExistence(updated.Existence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ItemID != 2 || updatedNames["en"] != "Orirock Cube US" || !existence["KR"].Exist {
+		t.Errorf("payload updates item %d to names %v and existence %s, want the US name and KR shown", updated.ItemID, updatedNames, updated.Existence)
+	}
+}
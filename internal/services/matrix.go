@@ -0,0 +1,196 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+	"github.com/penguin-statistics/soracli/internal/pkg/gdutils"
+)
+
+// DropMatrixOptions configures ComputeDropMatrix.
+type DropMatrixOptions struct {
+	// Confidence is the confidence level of the intervals, e.g. 0.95.
+	Confidence float64
+	// IncludeUnreliable also takes the reports of non-zero Reliability into account.
+	IncludeUnreliable bool
+}
+
+// reportedRuns is the quantity of each item dropped in a report of Times runs.
+type reportedRuns struct {
+	times      int
+	quantities map[int]int
+}
+
+type stageRangeKey struct {
+	stageID int
+	rangeID int
+}
+
+// ComputeDropMatrix computes the drop rate of each item on each stage of server within each time range,
+// from the drop reports of server and the drop patterns they refer to. A report is counted in the time range
+// of its stage that contains its creation time; if several do, the one starting the latest. Items in the drop
+// infos of the stage and time range are included even if never reported to drop.
+//
+// The confidence interval of a rate is the normal approximation around the mean quantity per run, with the
+// per-run variance estimated from the spread of the reports. For items never dropped, the lower bound is zero
+// and the upper bound is -ln(1 - confidence) / times, e.g. the rule of three at 95%.
+func ComputeDropMatrix(snapshot *types.Snapshot, server string, reports []*models.DropReport, patterns []*models.DropPattern, opts DropMatrixOptions) ([]*types.DropMatrixElement, *types.DropMatrixSummary, error) {
+	patternQuantities, err := patternQuantitiesOf(patterns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timeRangesMap := timeRangesMapOf(snapshot, server)
+	stageTimeRanges := stageTimeRangesOf(snapshot, server, timeRangesMap)
+
+	summary := &types.DropMatrixSummary{}
+	runs := make(map[stageRangeKey][]reportedRuns)
+	for _, report := range reports {
+		if report.Server != server {
+			continue
+		}
+		summary.Reports++
+		if report.Reliability != 0 && !opts.IncludeUnreliable {
+			summary.Unreliable++
+			continue
+		}
+		quantities, ok := patternQuantities[report.PatternID]
+		if !ok {
+			summary.NoPattern++
+			continue
+		}
+		timeRange := reportTimeRange(report, stageTimeRanges[report.StageID])
+		if timeRange == nil {
+			summary.NoTimeRange++
+			continue
+		}
+		key := stageRangeKey{stageID: report.StageID, rangeID: timeRange.RangeID}
+		runs[key] = append(runs[key], reportedRuns{times: report.Times, quantities: quantities})
+	}
+
+	// items expected to drop, as configured in the drop infos
+	expectedItems := make(map[stageRangeKey]map[int]bool)
+	for _, dropInfo := range snapshot.DropInfos {
+		if dropInfo.Server != server || !dropInfo.ItemID.Valid {
+			continue
+		}
+		key := stageRangeKey{stageID: dropInfo.StageID, rangeID: dropInfo.RangeID}
+		if expectedItems[key] == nil {
+			expectedItems[key] = make(map[int]bool)
+		}
+		expectedItems[key][int(dropInfo.ItemID.Int64)] = true
+	}
+
+	arkStageIDs := make(map[int]string, len(snapshot.Stages))
+	for _, stage := range snapshot.Stages {
+		arkStageIDs[stage.StageID] = stage.ArkStageID
+	}
+	arkItemIDs := make(map[int]string, len(snapshot.Items))
+	for _, item := range snapshot.Items {
+		arkItemIDs[item.ItemID] = item.ArkItemID
+	}
+	z := math.Sqrt2 * math.Erfinv(opts.Confidence)
+
+	elements := make([]*types.DropMatrixElement, 0)
+	for key, reported := range runs {
+		items := make(map[int]bool, len(expectedItems[key]))
+		for itemID := range expectedItems[key] {
+			items[itemID] = true
+		}
+		times := 0
+		for _, r := range reported {
+			times += r.times
+			for itemID := range r.quantities {
+				items[itemID] = true
+			}
+		}
+		if times == 0 {
+			continue
+		}
+
+		for itemID := range items {
+			element := &types.DropMatrixElement{
+				Server:     server,
+				StageID:    key.stageID,
+				ItemID:     itemID,
+				ArkStageID: gdutils.ArkIDOf(arkStageIDs, key.stageID),
+				ArkItemID:  gdutils.ArkIDOf(arkItemIDs, itemID),
+				TimeRange:  timeRangesMap[key.rangeID],
+				Times:      times,
+			}
+			for _, r := range reported {
+				element.Quantity += r.quantities[itemID]
+			}
+			element.Rate = float64(element.Quantity) / float64(times)
+			element.Lower, element.Upper = dropRateInterval(element.Rate, itemID, reported, times, z, opts.Confidence)
+			elements = append(elements, element)
+		}
+	}
+
+	sort.Slice(elements, func(i, j int) bool {
+		a, b := elements[i], elements[j]
+		if a.StageID != b.StageID {
+			return a.StageID < b.StageID
+		}
+		if !a.TimeRange.StartTime.Equal(*b.TimeRange.StartTime) {
+			return a.TimeRange.StartTime.Before(*b.TimeRange.StartTime)
+		}
+		return a.ItemID < b.ItemID
+	})
+	return elements, summary, nil
+}
+
+// patternQuantitiesOf returns the item quantities of each pattern, keyed by pattern ID.
+func patternQuantitiesOf(patterns []*models.DropPattern) (map[int]map[int]int, error) {
+	patternQuantities := make(map[int]map[int]int, len(patterns))
+	for _, pattern := range patterns {
+		quantities, err := pattern.Quantities()
+		if err != nil {
+			return nil, err
+		}
+		patternQuantities[pattern.PatternID] = quantities
+	}
+	return patternQuantities, nil
+}
+
+// reportTimeRange returns the latest starting time range of ranges that contains the creation time of report.
+func reportTimeRange(report *models.DropReport, ranges []*models.TimeRange) *models.TimeRange {
+	if report.CreatedAt == nil {
+		return nil
+	}
+	var found *models.TimeRange
+	for _, timeRange := range ranges {
+		if !report.CreatedAt.Before(*timeRange.StartTime) && report.CreatedAt.Before(*timeRange.EndTime) {
+			found = timeRange
+		}
+	}
+	return found
+}
+
+// dropRateInterval returns the bounds of the confidence interval of the drop rate of itemID over times runs.
+// Without drops, the upper bound follows the rule of three; otherwise the interval is the normal approximation,
+// with the variance estimated from the spread of the reports, or taken as rate if there is only one.
+func dropRateInterval(rate float64, itemID int, reported []reportedRuns, times int, z, confidence float64) (float64, float64) {
+	if rate == 0 {
+		return 0, -math.Log(1-confidence) / float64(times)
+	}
+
+	// a report of t runs dropping q has variance t·σ², so (q - t·rate)² / t estimates σ²
+	sum, used := 0.0, 0
+	for _, r := range reported {
+		if r.times == 0 {
+			continue
+		}
+		d := float64(r.quantities[itemID]) - float64(r.times)*rate
+		sum += d * d / float64(r.times)
+		used++
+	}
+	variance := rate
+	if used > 1 {
+		variance = sum / float64(used-1)
+	}
+	margin := z * math.Sqrt(variance/float64(times))
+	return math.Max(0, rate-margin), rate + margin
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDropRateInterval(t *testing.T) {
+	const itemID = 1
+	report := func(times, quantity int) reportedRuns {
+		return reportedRuns{times: times, quantities: map[int]int{itemID: quantity}}
+	}
+
+	tests := []struct {
+		name      string
+		rate      float64
+		reported  []reportedRuns
+		times     int
+		wantLower float64
+		wantUpper float64
+	}{
+		{
+			// -ln(0.05) / 100, about 3 / 100
+			name:      "rule of three",
+			rate:      0,
+			reported:  []reportedRuns{report(60, 0), report(40, 0)},
+			times:     100,
+			wantLower: 0,
+			wantUpper: 0.029957,
+		},
+		{
+			// squared deviations of 1, 1 and 0 over 10 runs each: variance (0.1 + 0.1 + 0) / 2 = 0.1,
+			// margin 1.96·√(0.1 / 30)
+			name:      "normal approximation",
+			rate:      0.4,
+			reported:  []reportedRuns{report(10, 5), report(10, 3), report(10, 4)},
+			times:     30,
+			wantLower: 0.286839,
+			wantUpper: 0.513161,
+		},
+		{
+			name:      "reports without runs are not counted",
+			rate:      0.4,
+			reported:  []reportedRuns{report(10, 5), report(0, 0), report(10, 3), report(0, 0), report(10, 4)},
+			times:     30,
+			wantLower: 0.286839,
+			wantUpper: 0.513161,
+		},
+		{
+			// variance taken as the rate: margin 1.96·√(0.4 / 10)
+			name:      "single report",
+			rate:      0.4,
+			reported:  []reportedRuns{report(0, 0), report(10, 4)},
+			times:     10,
+			wantLower: 0.008,
+			wantUpper: 0.792,
+		},
+		{
+			name:      "lower bound clamped",
+			rate:      0.01,
+			reported:  []reportedRuns{report(1, 0)},
+			times:     1,
+			wantLower: 0,
+			wantUpper: 0.206,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lower, upper := dropRateInterval(tt.rate, itemID, tt.reported, tt.times, 1.96, 0.95)
+			if math.Abs(lower-tt.wantLower) > 1e-6 || math.Abs(upper-tt.wantUpper) > 1e-6 {
+				t.Errorf("interval [%f, %f], want [%f, %f]", lower, upper, tt.wantLower, tt.wantUpper)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/cache"
+	"github.com/penguin-statistics/soracli/internal/pkg/client"
+)
+
+var ErrNoticeNotFound = errors.New("notice not found")
+
+type NoticeService struct {
+	http *client.Penguin
+}
+
+func NewNoticeService(http *client.Penguin) *NoticeService {
+	return &NoticeService{
+		http: http,
+	}
+}
+
+func (s *NoticeService) GetNotices(ctx context.Context) ([]*models.Notice, error) {
+	var notices []*models.Notice
+	err := cache.Notices.MutexGetSet(&notices, func() ([]*models.Notice, error) {
+		var resp []*models.Notice
+		if err := s.http.GetJSON("/cli/notices", &resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}, 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	return notices, nil
+}
+
+func (s *NoticeService) GetNoticeByID(ctx context.Context, id int) (*models.Notice, error) {
+	notices, err := s.GetNotices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, notice := range notices {
+		if notice.NoticeID == id {
+			return notice, nil
+		}
+	}
+	return nil, ErrNoticeNotFound
+}
+
+func (s *NoticeService) CreateNotice(ctx context.Context, notice *models.Notice) (*models.Notice, error) {
+	var created models.Notice
+	if err := s.http.PostJSONWithResponse("/notices", notice, &created); err != nil {
+		return nil, err
+	}
+	s.invalidate(created.NoticeID)
+	return &created, nil
+}
+
+func (s *NoticeService) UpdateNotice(ctx context.Context, notice *models.Notice) (*models.Notice, error) {
+	var updated models.Notice
+	if err := s.http.PostJSONWithResponse("/notices/"+strconv.Itoa(notice.NoticeID), notice, &updated); err != nil {
+		return nil, err
+	}
+	s.invalidate(notice.NoticeID)
+	return &updated, nil
+}
+
+func (s *NoticeService) invalidate(noticeId int) {
+	if err := cache.InvalidateTag(cache.TagNotice(noticeId)); err != nil {
+		log.Warn().Err(err).Int("noticeId", noticeId).Msg("failed to invalidate notice caches")
+	}
+}
@@ -0,0 +1,689 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+	"github.com/penguin-statistics/soracli/internal/pkg/audit"
+)
+
+// LiveState returns the live zones, stages, time ranges and drop infos. The caches are invalidated first,
+// so that plans are never computed against stale data.
+func (s *GameDataService) LiveState(ctx context.Context) (*types.Snapshot, error) {
+	s.invalidateGameData("stale")
+
+	zones, err := s.ZoneService.GetZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stages, err := s.StageService.GetStages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timeRanges, err := s.TimeRangeService.getAllTimeRanges()
+	if err != nil {
+		return nil, err
+	}
+	dropInfos, err := s.TimeRangeService.getDropInfos(func(*models.DropInfo) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	return &types.Snapshot{
+		Zones:      append([]*models.Zone(nil), zones...),
+		Stages:     append([]*models.Stage(nil), stages...),
+		TimeRanges: timeRanges,
+		DropInfos:  dropInfos,
+	}, nil
+}
+
+// LiveDigest returns a digest of the zones, stages, time ranges and drop infos of live, regardless of their order.
+func LiveDigest(live *types.Snapshot) (string, error) {
+	state := &types.Snapshot{
+		Zones:      append([]*models.Zone(nil), live.Zones...),
+		Stages:     append([]*models.Stage(nil), live.Stages...),
+		TimeRanges: append([]*models.TimeRange(nil), live.TimeRanges...),
+		DropInfos:  append([]*models.DropInfo(nil), live.DropInfos...),
+	}
+	sort.Slice(state.Zones, func(i, j int) bool { return state.Zones[i].ZoneID < state.Zones[j].ZoneID })
+	sort.Slice(state.Stages, func(i, j int) bool { return state.Stages[i].StageID < state.Stages[j].StageID })
+	sort.Slice(state.TimeRanges, func(i, j int) bool { return state.TimeRanges[i].RangeID < state.TimeRanges[j].RangeID })
+	sort.Slice(state.DropInfos, func(i, j int) bool { return state.DropInfos[i].DropID < state.DropInfos[j].DropID })
+
+	b, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return audit.HashPayload(b), nil
+}
+
+// reconciler matches the entities of desired bundles with the live ones.
+type reconciler struct {
+	live    *types.Snapshot
+	changes map[string][]*gamedata.PlanChange
+
+	// live IDs of the desired entities, keyed by their keys; zero if they are to be created
+	zoneIDs  map[string]int
+	stageIDs map[string]int
+	rangeIDs map[string]int
+	// keys of the live stages and time ranges matched with desired ones, keyed by their live IDs
+	stageKeys map[int]string
+	rangeKeys map[int]string
+}
+
+// PlanReconciliation plans the changes making the live zones, stages, time ranges and drop infos as in the
+// desired bundles. Entities of the bundles are matched with live ones by their IDs, if given, or by their
+// keys otherwise; see gamedata.PlanChange. Time ranges with new times are matched by their stages too; see
+// movedTimeRange. Unmatched ones are created, and matched ones updated if they differ.
+//
+// Deletes are limited to what the bundles manage: the stages of their zones missing from the bundles, and the
+// drop infos of their stages and time ranges missing from the bundles. Time ranges left without drop infos
+// by the deletes are deleted too. Zones are never deleted, and activities are not reconciled.
+func PlanReconciliation(desired []*gamedata.DesiredBundle, live *types.Snapshot) ([]*gamedata.PlanChange, error) {
+	r := &reconciler{
+		live:      live,
+		changes:   make(map[string][]*gamedata.PlanChange),
+		zoneIDs:   make(map[string]int),
+		stageIDs:  make(map[string]int),
+		rangeIDs:  make(map[string]int),
+		stageKeys: make(map[int]string),
+		rangeKeys: make(map[int]string),
+	}
+
+	if err := r.reconcileZones(desired); err != nil {
+		return nil, err
+	}
+	if err := r.reconcileTimeRanges(desired); err != nil {
+		return nil, err
+	}
+	if err := r.reconcileStages(desired); err != nil {
+		return nil, err
+	}
+	if err := r.reconcileDropInfos(desired); err != nil {
+		return nil, err
+	}
+	r.planDeletes()
+
+	changes := make([]*gamedata.PlanChange, 0)
+	for _, action := range []string{gamedata.PlanActionCreate, gamedata.PlanActionUpdate} {
+		for _, kind := range []string{gamedata.PlanKindZone, gamedata.PlanKindTimeRange, gamedata.PlanKindStage, gamedata.PlanKindDropInfo} {
+			changes = append(changes, r.sorted(action, kind)...)
+		}
+	}
+	for _, kind := range []string{gamedata.PlanKindDropInfo, gamedata.PlanKindStage, gamedata.PlanKindTimeRange} {
+		changes = append(changes, r.sorted(gamedata.PlanActionDelete, kind)...)
+	}
+	return changes, nil
+}
+
+func (r *reconciler) add(change *gamedata.PlanChange) {
+	r.changes[change.Action+" "+change.Kind] = append(r.changes[change.Action+" "+change.Kind], change)
+}
+
+func (r *reconciler) sorted(action, kind string) []*gamedata.PlanChange {
+	changes := r.changes[action+" "+kind]
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// upsert adds a create change if live is nil, or an update change if desired differs from it.
+func (r *reconciler) upsert(change *gamedata.PlanChange, live, desired any, liveID int) error {
+	if liveID == 0 {
+		change.Action = gamedata.PlanActionCreate
+		r.add(change)
+		return nil
+	}
+	fields, err := changedFields(live, desired)
+	if err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		change.Action = gamedata.PlanActionUpdate
+		change.ID = liveID
+		change.Fields = fields
+		r.add(change)
+	}
+	return nil
+}
+
+func (r *reconciler) reconcileZones(desired []*gamedata.DesiredBundle) error {
+	liveByID := make(map[int]*models.Zone, len(r.live.Zones))
+	liveByKey := make(map[string]*models.Zone, len(r.live.Zones))
+	for _, zone := range r.live.Zones {
+		liveByID[zone.ZoneID] = zone
+		liveByKey[zone.ArkZoneID] = zone
+	}
+
+	seen := make(map[string]*gamedata.DesiredBundle)
+	for _, bundle := range desired {
+		if bundle.Zone == nil {
+			continue
+		}
+		key := bundle.Zone.ArkZoneID
+		if other, ok := seen[key]; ok {
+			if err := sameEntity(bundle.Zone, other.Zone, "zone", key, bundle.File, other.File); err != nil {
+				return err
+			}
+			continue
+		}
+		seen[key] = bundle
+
+		live := liveByKey[key]
+		if id := bundle.Zone.ZoneID; id != 0 {
+			if live = liveByID[id]; live == nil {
+				return errors.Errorf("%s: zone %d not found; remove its id to create it", bundle.File, id)
+			}
+		}
+		zone := *bundle.Zone
+		zone.ZoneID = 0
+		if live != nil {
+			zone.ZoneID = live.ZoneID
+		}
+		r.zoneIDs[key] = zone.ZoneID
+		if err := r.upsert(&gamedata.PlanChange{Kind: gamedata.PlanKindZone, Key: key, Zone: &zone}, live, &zone, zone.ZoneID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) reconcileTimeRanges(desired []*gamedata.DesiredBundle) error {
+	liveByID := make(map[int]*models.TimeRange, len(r.live.TimeRanges))
+	liveByKey := make(map[string]*models.TimeRange, len(r.live.TimeRanges))
+	for _, timeRange := range r.live.TimeRanges {
+		liveByID[timeRange.RangeID] = timeRange
+		liveByKey[timeRangeKey(timeRange)] = timeRange
+	}
+
+	seen := make(map[string]*gamedata.DesiredBundle)
+	// bundles whose time ranges match no live one by ID or key; they are matched by their stages once the
+	// others have claimed their live ranges
+	var unmatched []*gamedata.DesiredBundle
+	for _, bundle := range desired {
+		if bundle.TimeRange == nil {
+			if len(bundle.DropInfosMap) > 0 {
+				return errors.Errorf("%s: drop infos need a time range", bundle.File)
+			}
+			continue
+		}
+		if bundle.TimeRange.StartTime == nil || bundle.TimeRange.EndTime == nil || bundle.TimeRange.Server == "" {
+			return errors.Errorf("%s: the time range needs a server, a start time and an end time", bundle.File)
+		}
+		key := timeRangeKey(bundle.TimeRange)
+		if other, ok := seen[key]; ok {
+			if err := sameEntity(utcTimeRange(bundle.TimeRange), utcTimeRange(other.TimeRange), "time range", key, bundle.File, other.File); err != nil {
+				return err
+			}
+			continue
+		}
+		seen[key] = bundle
+
+		live := liveByKey[key]
+		if id := bundle.TimeRange.RangeID; id != 0 {
+			if live = liveByID[id]; live == nil {
+				return errors.Errorf("%s: time range %d not found; remove its id to create it", bundle.File, id)
+			}
+		}
+		if live == nil {
+			unmatched = append(unmatched, bundle)
+			continue
+		}
+		if err := r.upsertTimeRange(bundle, live); err != nil {
+			return err
+		}
+	}
+
+	for _, bundle := range unmatched {
+		live, err := r.movedTimeRange(bundle)
+		if err != nil {
+			return err
+		}
+		if err := r.upsertTimeRange(bundle, live); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) upsertTimeRange(bundle *gamedata.DesiredBundle, live *models.TimeRange) error {
+	key := timeRangeKey(bundle.TimeRange)
+	timeRange := utcTimeRange(bundle.TimeRange)
+	timeRange.RangeID = 0
+	var liveRange *models.TimeRange
+	if live != nil {
+		timeRange.RangeID = live.RangeID
+		liveRange = utcTimeRange(live)
+		r.rangeKeys[live.RangeID] = key
+	}
+	r.rangeIDs[key] = timeRange.RangeID
+	return r.upsert(&gamedata.PlanChange{Kind: gamedata.PlanKindTimeRange, Key: key, TimeRange: timeRange}, liveRange, timeRange, timeRange.RangeID)
+}
+
+// movedTimeRange returns the live time range a desired one with new times stands for, or nil if it is new.
+// Rendered bundles carry no range IDs, so an event extended or brought forward is told apart from a new one
+// by its stages: the live range is the one of the same server holding drop infos of the stages of the bundle,
+// and starting or ending at the same time as desired. A rerun has new start and end times, and is created.
+func (r *reconciler) movedTimeRange(bundle *gamedata.DesiredBundle) (*models.TimeRange, error) {
+	stageIDs := make(map[int]bool, len(bundle.Stages))
+	for _, stage := range r.live.Stages {
+		for _, desiredStage := range bundle.Stages {
+			if stage.ArkStageID == desiredStage.ArkStageID {
+				stageIDs[stage.StageID] = true
+			}
+		}
+	}
+	holding := make(map[int]bool)
+	for _, dropInfo := range r.live.DropInfos {
+		if stageIDs[dropInfo.StageID] {
+			holding[dropInfo.RangeID] = true
+		}
+	}
+
+	desired := bundle.TimeRange
+	var candidates []*models.TimeRange
+	for _, timeRange := range r.live.TimeRanges {
+		if _, claimed := r.rangeKeys[timeRange.RangeID]; claimed || !holding[timeRange.RangeID] || timeRange.Server != desired.Server {
+			continue
+		}
+		if timesEqual(timeRange.StartTime, desired.StartTime) || timesEqual(timeRange.EndTime, desired.EndTime) {
+			candidates = append(candidates, timeRange)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, nil
+	case 1:
+		return candidates[0], nil
+	default:
+		ids := make([]int, 0, len(candidates))
+		for _, timeRange := range candidates {
+			ids = append(ids, timeRange.RangeID)
+		}
+		return nil, errors.Errorf("%s: the time range may stand for any of the live time ranges %v; set its id to the one to update", bundle.File, ids)
+	}
+}
+
+func (r *reconciler) reconcileStages(desired []*gamedata.DesiredBundle) error {
+	liveByID := make(map[int]*models.Stage, len(r.live.Stages))
+	liveByKey := make(map[string]*models.Stage, len(r.live.Stages))
+	for _, stage := range r.live.Stages {
+		liveByID[stage.StageID] = stage
+		liveByKey[stage.ArkStageID] = stage
+	}
+
+	seen := make(map[string]*gamedata.DesiredBundle)
+	for _, bundle := range desired {
+		if len(bundle.Stages) > 0 && bundle.Zone == nil {
+			return errors.Errorf("%s: stages need a zone", bundle.File)
+		}
+		for _, desiredStage := range bundle.Stages {
+			key := desiredStage.ArkStageID
+			if other, ok := seen[key]; ok {
+				var otherStage *models.Stage
+				for _, stage := range other.Stages {
+					if stage.ArkStageID == key {
+						otherStage = stage
+					}
+				}
+				if err := sameEntity(desiredStage, otherStage, "stage", key, bundle.File, other.File); err != nil {
+					return err
+				}
+				continue
+			}
+			seen[key] = bundle
+
+			live := liveByKey[key]
+			if id := desiredStage.StageID; id != 0 {
+				if live = liveByID[id]; live == nil {
+					return errors.Errorf("%s: stage %d not found; remove its id to create it", bundle.File, id)
+				}
+			}
+			stage := *desiredStage
+			stage.StageID = 0
+			stage.ZoneID = r.zoneIDs[bundle.Zone.ArkZoneID]
+			change := &gamedata.PlanChange{Kind: gamedata.PlanKindStage, Key: key, Stage: &stage}
+			if stage.ZoneID == 0 {
+				change.ZoneKey = bundle.Zone.ArkZoneID
+			}
+			if live != nil {
+				stage.StageID = live.StageID
+				r.stageKeys[live.StageID] = key
+			}
+			r.stageIDs[key] = stage.StageID
+			if err := r.upsert(change, live, &stage, stage.StageID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) reconcileDropInfos(desired []*gamedata.DesiredBundle) error {
+	liveByID := make(map[int]*models.DropInfo, len(r.live.DropInfos))
+	liveByKey := make(map[string]*models.DropInfo, len(r.live.DropInfos))
+	for _, dropInfo := range r.live.DropInfos {
+		liveByID[dropInfo.DropID] = dropInfo
+		if key, ok := r.liveDropInfoKey(dropInfo); ok {
+			liveByKey[key] = dropInfo
+		}
+	}
+
+	seen := make(map[string]string)
+	for _, bundle := range desired {
+		stageKeys := make(map[string]bool, len(bundle.Stages))
+		for _, stage := range bundle.Stages {
+			stageKeys[stage.ArkStageID] = true
+		}
+		for _, stageKey := range sortedStringKeys(bundle.DropInfosMap) {
+			if !stageKeys[stageKey] {
+				return errors.Errorf("%s: drop infos of stage %s, which is not in the bundle", bundle.File, stageKey)
+			}
+			rangeKey := timeRangeKey(bundle.TimeRange)
+			for _, desiredDropInfo := range bundle.DropInfosMap[stageKey] {
+				dropInfo := *desiredDropInfo
+				if dropInfo.Server == "" {
+					dropInfo.Server = bundle.TimeRange.Server
+				}
+				if dropInfo.Server != bundle.TimeRange.Server {
+					return errors.Errorf("%s: drop info of stage %s is of server %s, not %s as its time range", bundle.File, stageKey, dropInfo.Server, bundle.TimeRange.Server)
+				}
+				key := dropInfoKey(stageKey, rangeKey, dropInfo.DropType, dropInfo.ItemID.Int64, dropInfo.ItemID.Valid)
+				if file, ok := seen[key]; ok {
+					return errors.Errorf("%s: drop info %s is also in %s", bundle.File, key, file)
+				}
+				seen[key] = bundle.File
+
+				live := liveByKey[key]
+				if id := desiredDropInfo.DropID; id != 0 {
+					if live = liveByID[id]; live == nil {
+						return errors.Errorf("%s: drop info %d not found; remove its id to create it", bundle.File, id)
+					}
+				}
+				dropInfo.DropID = 0
+				dropInfo.StageID = r.stageIDs[stageKey]
+				dropInfo.RangeID = r.rangeIDs[rangeKey]
+				change := &gamedata.PlanChange{Kind: gamedata.PlanKindDropInfo, Key: key, DropInfo: &dropInfo}
+				if dropInfo.StageID == 0 {
+					change.StageKey = stageKey
+				}
+				if dropInfo.RangeID == 0 {
+					change.RangeKey = rangeKey
+				}
+				if live != nil {
+					dropInfo.DropID = live.DropID
+					delete(liveByKey, key)
+					if liveKey, ok := r.liveDropInfoKey(live); ok {
+						delete(liveByKey, liveKey)
+					}
+				}
+				if err := r.upsert(change, live, &dropInfo, dropInfo.DropID); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// the remaining live drop infos of the desired stages and time ranges are no longer desired
+	for key, live := range liveByKey {
+		r.add(&gamedata.PlanChange{Action: gamedata.PlanActionDelete, Kind: gamedata.PlanKindDropInfo, Key: key, ID: live.DropID})
+	}
+	return nil
+}
+
+// liveDropInfoKey returns the key of a live drop info, if both its stage and time range are desired.
+func (r *reconciler) liveDropInfoKey(dropInfo *models.DropInfo) (string, bool) {
+	stageKey, ok := r.stageKeys[dropInfo.StageID]
+	if !ok {
+		return "", false
+	}
+	rangeKey, ok := r.rangeKeys[dropInfo.RangeID]
+	if !ok {
+		return "", false
+	}
+	return dropInfoKey(stageKey, rangeKey, dropInfo.DropType, dropInfo.ItemID.Int64, dropInfo.ItemID.Valid), true
+}
+
+// planDeletes deletes the live stages of the desired zones missing from the bundles, along with all their
+// drop infos, and the time ranges left without drop infos.
+func (r *reconciler) planDeletes() {
+	desiredZoneIDs := make(map[int]bool)
+	for _, id := range r.zoneIDs {
+		if id != 0 {
+			desiredZoneIDs[id] = true
+		}
+	}
+	deletedStages := make(map[int]bool)
+	for _, stage := range r.live.Stages {
+		if _, ok := r.stageKeys[stage.StageID]; ok || !desiredZoneIDs[stage.ZoneID] {
+			continue
+		}
+		deletedStages[stage.StageID] = true
+		r.add(&gamedata.PlanChange{Action: gamedata.PlanActionDelete, Kind: gamedata.PlanKindStage, Key: stage.ArkStageID, ID: stage.StageID})
+	}
+
+	deletedDropInfos := make(map[int]bool)
+	for _, change := range r.changes[gamedata.PlanActionDelete+" "+gamedata.PlanKindDropInfo] {
+		deletedDropInfos[change.ID] = true
+	}
+	arkStageIDs := make(map[int]string, len(r.live.Stages))
+	for _, stage := range r.live.Stages {
+		arkStageIDs[stage.StageID] = stage.ArkStageID
+	}
+	timeRanges := make(map[int]*models.TimeRange, len(r.live.TimeRanges))
+	for _, timeRange := range r.live.TimeRanges {
+		timeRanges[timeRange.RangeID] = timeRange
+	}
+	for _, dropInfo := range r.live.DropInfos {
+		if !deletedStages[dropInfo.StageID] || deletedDropInfos[dropInfo.DropID] {
+			continue
+		}
+		deletedDropInfos[dropInfo.DropID] = true
+		rangeKey := "#" + strconv.Itoa(dropInfo.RangeID)
+		if timeRange, ok := timeRanges[dropInfo.RangeID]; ok {
+			rangeKey = timeRangeKey(timeRange)
+		}
+		key := dropInfoKey(arkStageIDs[dropInfo.StageID], rangeKey, dropInfo.DropType, dropInfo.ItemID.Int64, dropInfo.ItemID.Valid)
+		r.add(&gamedata.PlanChange{Action: gamedata.PlanActionDelete, Kind: gamedata.PlanKindDropInfo, Key: key, ID: dropInfo.DropID})
+	}
+
+	kept := make(map[int]bool)
+	emptied := make(map[int]bool)
+	for _, dropInfo := range r.live.DropInfos {
+		if deletedDropInfos[dropInfo.DropID] {
+			emptied[dropInfo.RangeID] = true
+		} else {
+			kept[dropInfo.RangeID] = true
+		}
+	}
+	for rangeID := range emptied {
+		_, desired := r.rangeKeys[rangeID]
+		timeRange, ok := timeRanges[rangeID]
+		if kept[rangeID] || desired || !ok {
+			continue
+		}
+		r.add(&gamedata.PlanChange{Action: gamedata.PlanActionDelete, Kind: gamedata.PlanKindTimeRange, Key: timeRangeKey(timeRange), ID: rangeID})
+	}
+}
+
+// ApplyPlan runs the changes of a plan in order, stopping at the first failure. The IDs of the entities
+// created are filled in the changes referencing them.
+func (s *GameDataService) ApplyPlan(ctx context.Context, plan *gamedata.Plan) error {
+	defer s.invalidateGameData("reconciled")
+
+	created := make(map[string]int)
+	resolve := func(kind, key string, id *int) error {
+		if key == "" {
+			return nil
+		}
+		createdID, ok := created[kind+" "+key]
+		if !ok {
+			return errors.Errorf("%s %s is not created by the plan", kind, key)
+		}
+		*id = createdID
+		return nil
+	}
+
+	for _, change := range plan.Changes {
+		var err error
+		switch {
+		case change.Action == gamedata.PlanActionDelete:
+			endpoint, ok := deleteEndpoints[change.Kind]
+			if !ok {
+				err = errors.Errorf("unknown kind %q", change.Kind)
+				break
+			}
+			err = s.pgclient.PostJSON(endpoint, &types.DeleteEntitiesRequest{IDs: []int{change.ID}})
+
+		case change.Kind == gamedata.PlanKindZone:
+			zone := *change.Zone
+			err = s.postEntity(change, "/zones", &zone, &zone.ZoneID)
+
+		case change.Kind == gamedata.PlanKindTimeRange:
+			timeRange := *change.TimeRange
+			err = s.postEntity(change, "/timeranges", &timeRange, &timeRange.RangeID)
+
+		case change.Kind == gamedata.PlanKindStage:
+			stage := *change.Stage
+			if err = resolve(gamedata.PlanKindZone, change.ZoneKey, &stage.ZoneID); err == nil {
+				err = s.postEntity(change, "/stages", &stage, &stage.StageID)
+			}
+
+		case change.Kind == gamedata.PlanKindDropInfo:
+			dropInfo := *change.DropInfo
+			if err = resolve(gamedata.PlanKindStage, change.StageKey, &dropInfo.StageID); err == nil {
+				err = resolve(gamedata.PlanKindTimeRange, change.RangeKey, &dropInfo.RangeID)
+			}
+			if err == nil {
+				err = s.postEntity(change, "/dropinfos", &dropInfo, &dropInfo.DropID)
+			}
+
+		default:
+			err = errors.Errorf("unknown kind %q", change.Kind)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to %s %s %s", change.Action, change.Kind, change.Key)
+		}
+		if change.Action == gamedata.PlanActionCreate {
+			created[change.Kind+" "+change.Key] = change.ID
+		}
+		log.Info().Str("action", change.Action).Str("kind", change.Kind).Str("key", change.Key).Int("id", change.ID).Msg("applied change")
+	}
+	return nil
+}
+
+var deleteEndpoints = map[string]string{
+	gamedata.PlanKindZone:      "/zones/delete",
+	gamedata.PlanKindStage:     "/stages/delete",
+	gamedata.PlanKindTimeRange: "/timeranges/delete",
+	gamedata.PlanKindDropInfo:  "/dropinfos/delete",
+}
+
+// postEntity creates entity at endpoint, or updates it at endpoint/ID, and records the ID of a created
+// entity in the change.
+func (s *GameDataService) postEntity(change *gamedata.PlanChange, endpoint string, entity any, id *int) error {
+	if change.Action == gamedata.PlanActionUpdate {
+		return s.pgclient.PostJSON(endpoint+"/"+strconv.Itoa(change.ID), entity)
+	}
+	if err := s.pgclient.PostJSONWithResponse(endpoint, entity, entity); err != nil {
+		return err
+	}
+	if *id == 0 {
+		return errors.Errorf("the admin api responded no id for the created %s", change.Kind)
+	}
+	change.ID = *id
+	return nil
+}
+
+// timeRangeKey returns the key of a time range, its server and times in UTC, e.g. `CN 2022-05-01T08:00:00Z/2022-05-15T03:59:59Z`.
+// A time missing from a live range is keyed as `-`.
+func timeRangeKey(timeRange *models.TimeRange) string {
+	timeRange = utcTimeRange(timeRange)
+	return fmt.Sprintf("%s %s/%s", timeRange.Server, keyTime(timeRange.StartTime), keyTime(timeRange.EndTime))
+}
+
+func keyTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func dropInfoKey(stageKey, rangeKey, dropType string, itemID int64, hasItem bool) string {
+	key := fmt.Sprintf("%s @ %s %s", stageKey, rangeKey, dropType)
+	if hasItem {
+		key += " item " + strconv.FormatInt(itemID, 10)
+	}
+	return key
+}
+
+// utcTimeRange copies timeRange with its times in UTC, so that times of the same instant compare equal.
+func utcTimeRange(timeRange *models.TimeRange) *models.TimeRange {
+	copied := *timeRange
+	if copied.StartTime != nil {
+		t := copied.StartTime.UTC()
+		copied.StartTime = &t
+	}
+	if copied.EndTime != nil {
+		t := copied.EndTime.UTC()
+		copied.EndTime = &t
+	}
+	return &copied
+}
+
+// changedFields returns the JSON fields of desired differing from the ones of live.
+func changedFields(live, desired any) ([]string, error) {
+	liveFields, err := jsonFields(live)
+	if err != nil {
+		return nil, err
+	}
+	desiredFields, err := jsonFields(desired)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]string, 0)
+	for _, field := range sortedStringKeys(desiredFields) {
+		if !reflect.DeepEqual(liveFields[field], desiredFields[field]) {
+			fields = append(fields, field)
+		}
+	}
+	for _, field := range sortedStringKeys(liveFields) {
+		if _, ok := desiredFields[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+func jsonFields(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// sameEntity checks that an entity desired in two bundles is desired the same in both.
+func sameEntity(a, b any, kind, key, fileA, fileB string) error {
+	fields, err := changedFields(a, b)
+	if err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		return errors.Errorf("%s %s differs between %s and %s in %v", kind, key, fileB, fileA, fields)
+	}
+	return nil
+}
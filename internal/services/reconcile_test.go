@@ -0,0 +1,110 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+)
+
+func TestPlanReconciliationMovedTimeRange(t *testing.T) {
+	date := func(day, hour int) *time.Time {
+		t := time.Date(2022, 5, day, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+	// the event ran from May 1 to 15 and is rerun from May 20; both ranges hold drop infos of stage 1.
+	// A range of another zone has no end time.
+	live := func() *types.Snapshot {
+		return &types.Snapshot{
+			Zones: []*models.Zone{{ZoneID: 1, ArkZoneID: "act1side_zone1"}, {ZoneID: 2, ArkZoneID: "main_01"}},
+			Stages: []*models.Stage{
+				{StageID: 1, ArkStageID: "act1side_01", ZoneID: 1},
+				{StageID: 2, ArkStageID: "main_01-07", ZoneID: 2},
+			},
+			TimeRanges: []*models.TimeRange{
+				{RangeID: 1, Server: "CN", StartTime: date(1, 8), EndTime: date(15, 4)},
+				{RangeID: 2, Server: "CN", StartTime: date(20, 8), EndTime: date(27, 4)},
+				{RangeID: 3, Server: "CN", StartTime: date(1, 8)},
+			},
+			DropInfos: []*models.DropInfo{
+				{DropID: 1, Server: "CN", StageID: 1, RangeID: 1, DropType: "NORMAL_DROP"},
+				{DropID: 2, Server: "CN", StageID: 1, RangeID: 2, DropType: "NORMAL_DROP"},
+				{DropID: 3, Server: "CN", StageID: 2, RangeID: 3, DropType: "NORMAL_DROP"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		start, end *time.Time
+		want       []string
+		wantErr    string
+	}{
+		{
+			name:  "unchanged",
+			start: date(20, 8), end: date(27, 4),
+			want: []string{},
+		},
+		{
+			name:  "extended",
+			start: date(20, 8), end: date(30, 4),
+			want: []string{"update timeRange #2 [endTime]"},
+		},
+		{
+			name:  "brought forward",
+			start: date(19, 8), end: date(27, 4),
+			want: []string{"update timeRange #2 [startTime]"},
+		},
+		{
+			name:  "new rerun",
+			start: date(25, 8), end: date(29, 4),
+			want: []string{
+				"create timeRange #0 []",
+				"create dropInfo #0 []",
+			},
+		},
+		{
+			name:  "start of one and end of another",
+			start: date(1, 8), end: date(27, 4),
+			wantErr: "any of the live time ranges [1 2]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desired := []*gamedata.DesiredBundle{{
+				File: "act1side.json",
+				RenderedObjects: &gamedata.RenderedObjects{
+					Zone:   &models.Zone{ArkZoneID: "act1side_zone1"},
+					Stages: []*models.Stage{{ArkStageID: "act1side_01", ZoneID: 1}},
+					DropInfosMap: map[string][]*models.DropInfo{
+						"act1side_01": {{Server: "CN", DropType: "NORMAL_DROP"}},
+					},
+					TimeRange: &models.TimeRange{Server: "CN", StartTime: tt.start, EndTime: tt.end},
+				},
+			}}
+
+			changes, err := PlanReconciliation(desired, live())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(changes))
+			for _, change := range changes {
+				got = append(got, fmt.Sprintf("%s %s #%d %v", change.Action, change.Kind, change.ID, change.Fields))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+)
+
+// ApplyRenderRules overrides the bounds of the rendered drop infos and leaves out the stages excluded by rules.
+// Unknown items and drop types are errors, as they are likely typos, while rules matching nothing rendered
+// are only warned about, as the rules of a manifest are shared by its entries.
+func (s *GameDataService) ApplyRenderRules(ctx context.Context, rendered *gamedata.RenderedObjects, rules *gamedata.RenderRules) error {
+	if rules == nil {
+		return nil
+	}
+
+	for _, arkStageID := range rules.ExcludeStages {
+		if _, ok := rendered.DropInfosMap[arkStageID]; !ok {
+			log.Warn().Str("zone", rendered.Zone.ArkZoneID).Str("stage", arkStageID).Msg("excluded stage is not rendered")
+		}
+		delete(rendered.DropInfosMap, arkStageID)
+	}
+	stages := make([]*models.Stage, 0, len(rendered.Stages))
+	for _, stage := range rendered.Stages {
+		if _, ok := rendered.DropInfosMap[stage.ArkStageID]; ok {
+			stages = append(stages, stage)
+		}
+	}
+	rendered.Stages = stages
+
+	itemIDs := make(map[string]int, len(rules.ItemBounds))
+	if len(rules.ItemBounds) > 0 {
+		itemsMap, err := s.ItemService.GetItemsMapByArkId(ctx)
+		if err != nil {
+			return err
+		}
+		for arkItemID := range rules.ItemBounds {
+			item, ok := itemsMap[arkItemID]
+			if !ok {
+				return errors.Wrapf(ErrItemNotFound, "item %s of the bounds rules", arkItemID)
+			}
+			itemIDs[arkItemID] = item.ItemID
+		}
+	}
+	for dropType := range rules.DropTypeBounds {
+		if _, ok := dropTypeOrderMapping[dropType]; !ok {
+			return errors.Errorf("unknown drop type %s in the bounds rules", dropType)
+		}
+	}
+
+	overridden := make(map[string]bool)
+	for _, dropInfos := range rendered.DropInfosMap {
+		for _, dropInfo := range dropInfos {
+			if dropInfo.ItemID.Valid {
+				for arkItemID, itemID := range itemIDs {
+					if int(dropInfo.ItemID.Int64) == itemID {
+						dropInfo.Bounds = copyBounds(rules.ItemBounds[arkItemID])
+						overridden[arkItemID] = true
+					}
+				}
+			} else if bounds, ok := rules.DropTypeBounds[dropInfo.DropType]; ok {
+				dropInfo.Bounds = copyBounds(bounds)
+				overridden[dropInfo.DropType] = true
+			}
+		}
+	}
+	for _, subject := range append(sortedStringKeys(rules.ItemBounds), sortedStringKeys(rules.DropTypeBounds)...) {
+		if !overridden[subject] {
+			log.Warn().Str("zone", rendered.Zone.ArkZoneID).Str("subject", subject).Msg("bounds rule matches no rendered drop info")
+		}
+	}
+	return nil
+}
+
+func copyBounds(bounds *models.Bounds) *models.Bounds {
+	if bounds == nil {
+		return nil
+	}
+	copied := *bounds
+	copied.Exceptions = append([]int(nil), bounds.Exceptions...)
+	return &copied
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/models/shims"
+)
+
+// shimLanguage is the language the v2 shims use for their untranslated fields, e.g. `code` and `zoneName`.
+const shimLanguage = "zh"
+
+// RenderShimBundle converts rendered into the v2 shim shapes, resolving items against the item seed.
+// Drop infos and stages follow the server of the rendered time range.
+func (s *GameDataService) RenderShimBundle(ctx context.Context, rendered *gamedata.RenderedObjects) (*gamedata.ShimBundle, error) {
+	items, err := s.ItemService.GetItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	itemsMapById := make(map[int]*models.Item, len(items))
+	for _, item := range items {
+		itemsMapById[item.ItemID] = item
+	}
+
+	bundle := &gamedata.ShimBundle{
+		Zones:  make([]*shims.Zone, 0, 1),
+		Stages: make([]*shims.Stage, 0, len(rendered.Stages)),
+		Items:  make([]*shims.Item, 0),
+		Period: make([]*shims.Activity, 0, 1),
+	}
+
+	if rendered.Zone != nil {
+		zone, err := shimZone(rendered.Zone, rendered.Stages)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Zones = append(bundle.Zones, zone)
+	}
+
+	referencedItems := make(map[int]*models.Item)
+	for _, stage := range rendered.Stages {
+		shimStage, err := shimStage(stage, rendered.Zone, rendered.DropInfosMap[stage.ArkStageID], itemsMapById, referencedItems)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Stages = append(bundle.Stages, shimStage)
+	}
+
+	for _, item := range referencedItems {
+		shimItem, err := shimItem(item)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Items = append(bundle.Items, shimItem)
+	}
+	sort.Slice(bundle.Items, func(i, j int) bool {
+		return bundle.Items[i].SortID < bundle.Items[j].SortID
+	})
+
+	if rendered.Activity != nil {
+		bundle.Period = append(bundle.Period, shimActivity(rendered.Activity))
+	}
+
+	return bundle, nil
+}
+
+func shimZone(zone *models.Zone, stages []*models.Stage) (*shims.Zone, error) {
+	name, err := I18nValue(zone.Name, shimLanguage)
+	if err != nil {
+		return nil, errors.Wrapf(err, "name of zone %s", zone.ArkZoneID)
+	}
+
+	stageIds := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		stageIds = append(stageIds, stage.ArkStageID)
+	}
+
+	return &shims.Zone{
+		ZoneID:       zone.ZoneID,
+		ArkZoneID:    zone.ArkZoneID,
+		Index:        zone.Index,
+		Category:     zone.Category,
+		Type:         zone.Type,
+		ZoneName:     name,
+		ZoneNameI18n: zone.Name,
+		Existence:    zone.Existence,
+		Background:   zone.Background,
+		StageIds:     stageIds,
+	}, nil
+}
+
+// shimStage converts stage and its drop infos, adding the items the drop infos refer to to referencedItems.
+// As in the v2 api, recognition only drop infos are listed in RecognitionOnly instead of DropInfos, and
+// drop types are converted back to their api form.
+func shimStage(stage *models.Stage, zone *models.Zone, dropInfos []*models.DropInfo, itemsMapById map[int]*models.Item, referencedItems map[int]*models.Item) (*shims.Stage, error) {
+	code, err := I18nValue(stage.Code, shimLanguage)
+	if err != nil {
+		return nil, errors.Wrapf(err, "code of stage %s", stage.ArkStageID)
+	}
+
+	shimStage := &shims.Stage{
+		StageID:      stage.StageID,
+		ArkStageID:   stage.ArkStageID,
+		ZoneID:       stage.ZoneID,
+		StageType:    stage.StageType,
+		Code:         code,
+		CodeI18n:     stage.Code,
+		Sanity:       stage.Sanity,
+		Existence:    stage.Existence,
+		MinClearTime: stage.MinClearTime,
+	}
+	if zone != nil {
+		shimStage.ArkZoneID = zone.ArkZoneID
+	}
+
+	recognitionOnly := make([]string, 0)
+	for _, dropInfo := range dropInfos {
+		if dropInfo.DropType == consts.DropTypeRecognitionOnly {
+			var extras struct {
+				ArkItemID string `json:"arkItemId"`
+			}
+			if len(dropInfo.Extras) > 0 {
+				if err := json.Unmarshal(dropInfo.Extras, &extras); err != nil {
+					return nil, errors.Wrapf(err, "extras of recognition only drop info of stage %s", stage.ArkStageID)
+				}
+			}
+			if extras.ArkItemID != "" {
+				recognitionOnly = append(recognitionOnly, extras.ArkItemID)
+			}
+			continue
+		}
+
+		bounds, err := json.Marshal(dropInfo.Bounds)
+		if err != nil {
+			return nil, err
+		}
+		shimDropInfo := &shims.DropInfo{
+			DropID:     dropInfo.DropID,
+			Server:     dropInfo.Server,
+			StageID:    stage.StageID,
+			ArkStageID: stage.ArkStageID,
+			DropType:   consts.DropTypeReversedMap[dropInfo.DropType],
+			RangeID:    dropInfo.RangeID,
+			Bounds:     bounds,
+			Extras:     dropInfo.Extras,
+		}
+		if dropInfo.ItemID.Valid {
+			item, ok := itemsMapById[int(dropInfo.ItemID.Int64)]
+			if !ok {
+				return nil, errors.Wrapf(ErrItemNotFound, "item %d dropped by stage %s", dropInfo.ItemID.Int64, stage.ArkStageID)
+			}
+			referencedItems[item.ItemID] = item
+			shimDropInfo.ItemID = item.ItemID
+			shimDropInfo.ArkItemID = item.ArkItemID
+		}
+		shimStage.DropInfos = append(shimStage.DropInfos, shimDropInfo)
+	}
+	if len(recognitionOnly) > 0 {
+		sort.Strings(recognitionOnly)
+		shimStage.RecognitionOnly = recognitionOnly
+	}
+
+	return shimStage, nil
+}
+
+func shimItem(item *models.Item) (*shims.Item, error) {
+	name, err := I18nValue(item.Name, shimLanguage)
+	if err != nil {
+		return nil, errors.Wrapf(err, "name of item %s", item.ArkItemID)
+	}
+
+	shimItem := &shims.Item{
+		ItemID:    item.ItemID,
+		ArkItemID: item.ArkItemID,
+		Name:      name,
+		NameI18n:  item.Name,
+		Existence: item.Existence,
+		ItemType:  item.Type,
+		SortID:    item.SortID,
+		Rarity:    item.Rarity,
+		Group:     item.Group,
+		Sprite:    item.Sprite,
+		Keywords:  item.Keywords,
+	}
+
+	// sprite is in a form of Y:X, while spriteCoord is [X, Y]
+	if item.Sprite.Valid {
+		if y, x, ok := strings.Cut(item.Sprite.String, ":"); ok {
+			xCoord, xErr := strconv.Atoi(x)
+			yCoord, yErr := strconv.Atoi(y)
+			if xErr == nil && yErr == nil {
+				shimItem.SpriteCoord = &[]int{xCoord, yCoord}
+			}
+		}
+	}
+
+	if len(item.Keywords) > 0 {
+		var keywords struct {
+			Alias json.RawMessage `json:"alias"`
+			Pron  json.RawMessage `json:"pron"`
+		}
+		if err := json.Unmarshal(item.Keywords, &keywords); err != nil {
+			return nil, errors.Wrapf(err, "keywords of item %s", item.ArkItemID)
+		}
+		shimItem.AliasMap = keywords.Alias
+		shimItem.PronMap = keywords.Pron
+	}
+
+	return shimItem, nil
+}
+
+// shimActivity converts activity; as in the v2 api, activities ending at consts.FakeEndTimeMilli have no end.
+func shimActivity(activity *models.Activity) *shims.Activity {
+	shimActivity := &shims.Activity{
+		ActivityID: activity.ActivityID,
+		LabelI18n:  activity.Name,
+		Existence:  activity.Existence,
+	}
+	if activity.StartTime != nil {
+		shimActivity.Start = activity.StartTime.UnixMilli()
+	}
+	if activity.EndTime != nil && !activity.EndTime.Equal(time.UnixMilli(consts.FakeEndTimeMilli)) {
+		shimActivity.End = null.IntFrom(activity.EndTime.UnixMilli())
+	}
+	return shimActivity
+}
+
+// I18nValue returns the value of lang in an i18n map such as the name of a zone.
+func I18nValue(raw json.RawMessage, lang string) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return "", err
+	}
+	return m[lang], nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/penguin-statistics/soracli/internal/consts"
+	"github.com/penguin-statistics/soracli/internal/models"
+)
+
+func TestRenderShimBundle(t *testing.T) {
+	cube := testItem(1, "30012", 200, "orirock", map[string]string{"zh": "固源岩组", "en": "Orirock Cube"}, "CN")
+	cube.Sprite = null.StringFrom("3:5")
+	cube.Keywords = json.RawMessage(`{"alias": {"zh": ["gyyz"]}, "pron": {"zh": ["guyuanyanzu"]}}`)
+	rock := testItem(2, "30011", 100, "orirock", map[string]string{"zh": "源岩"}, "CN")
+	// not dropped by the stage, so not in the bundle
+	unused := testItem(3, "30013", 300, "", nil, "CN")
+	s := newTestGameDataService(t, &fakeBackend{items: []*models.Item{cube, rock, unused}})
+
+	start := time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+	end := time.UnixMilli(consts.FakeEndTimeMilli)
+	rendered := testBundle("act1side_zone1", start, start.AddDate(0, 0, 14))
+	rendered.Zone.Name = json.RawMessage(`{"zh": "活动", "en": "Side Story"}`)
+	rendered.Stages[0].Code = json.RawMessage(`{"zh": "SS-1", "en": "SS-1"}`)
+	rendered.DropInfosMap["act1side_zone1_01"] = []*models.DropInfo{
+		{Server: "CN", DropType: consts.DropTypeRegular},
+		{Server: "CN", DropType: consts.DropTypeRegular, ItemID: null.IntFrom(1), Bounds: &models.Bounds{Upper: 3}},
+		{Server: "CN", DropType: consts.DropTypeExtra, ItemID: null.IntFrom(2), Bounds: &models.Bounds{Upper: 1}},
+		{Server: "CN", DropType: consts.DropTypeRecognitionOnly, Extras: json.RawMessage(`{"arkItemId": "ap_supply_lt_010"}`)},
+		{Server: "CN", DropType: consts.DropTypeRecognitionOnly, Extras: json.RawMessage(`{"arkItemId": "2001"}`)},
+	}
+	rendered.Activity = &models.Activity{StartTime: &start, EndTime: &end, Name: json.RawMessage(`{"zh": "活动"}`)}
+
+	bundle, err := s.RenderShimBundle(context.Background(), rendered)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bundle.Zones) != 1 || bundle.Zones[0].ZoneName != "活动" || !reflect.DeepEqual(bundle.Zones[0].StageIds, []string{"act1side_zone1_01"}) {
+		t.Errorf("zones %+v, want the zone named in zh with its stage", bundle.Zones)
+	}
+
+	if len(bundle.Stages) != 1 {
+		t.Fatalf("got %d stages, want 1", len(bundle.Stages))
+	}
+	stage := bundle.Stages[0]
+	if stage.Code != "SS-1" || stage.ArkZoneID != "act1side_zone1" {
+		t.Errorf("stage code %q of zone %q, want SS-1 of act1side_zone1", stage.Code, stage.ArkZoneID)
+	}
+	// recognition only drop infos are listed by their ark item IDs, sorted
+	if !reflect.DeepEqual(stage.RecognitionOnly, []string{"2001", "ap_supply_lt_010"}) {
+		t.Errorf("recognition only %v, want [2001 ap_supply_lt_010]", stage.RecognitionOnly)
+	}
+	dropInfos := make([]string, 0)
+	for _, dropInfo := range stage.DropInfos {
+		dropInfos = append(dropInfos, dropInfo.DropType+" "+dropInfo.ArkItemID+" "+string(dropInfo.Bounds))
+	}
+	// drop types are converted back to their api form
+	wantDropInfos := []string{"NORMAL_DROP  null", `NORMAL_DROP 30012 {"upper":3,"lower":0}`, `EXTRA_DROP 30011 {"upper":1,"lower":0}`}
+	if !reflect.DeepEqual(dropInfos, wantDropInfos) {
+		t.Errorf("drop infos %q, want %q", dropInfos, wantDropInfos)
+	}
+
+	// only the dropped items, sorted by their sort IDs
+	if len(bundle.Items) != 2 || bundle.Items[0].ArkItemID != "30011" || bundle.Items[1].ArkItemID != "30012" {
+		t.Fatalf("items %+v, want 30011 and 30012", bundle.Items)
+	}
+	item := bundle.Items[1]
+	if item.Name != "固源岩组" || item.SpriteCoord == nil || !reflect.DeepEqual(*item.SpriteCoord, []int{5, 3}) {
+		t.Errorf("item named %q at sprite coord %v, want the zh name at [5 3]", item.Name, item.SpriteCoord)
+	}
+	if string(item.AliasMap) != `{"zh":["gyyz"]}` || string(item.PronMap) != `{"zh":["guyuanyanzu"]}` {
+		t.Errorf("item alias %s and pron %s, want them taken from the keywords", item.AliasMap, item.PronMap)
+	}
+	if bundle.Items[0].SpriteCoord != nil {
+		t.Errorf("item 30011 has sprite coord %v without a sprite", *bundle.Items[0].SpriteCoord)
+	}
+
+	// an activity ending at the fake end time has no end
+	if len(bundle.Period) != 1 || bundle.Period[0].Start != start.UnixMilli() || bundle.Period[0].End.Valid {
+		t.Errorf("period %+v, want one starting at %d without an end", bundle.Period, start.UnixMilli())
+	}
+}
+
+func TestRenderShimBundleUnknownItem(t *testing.T) {
+	s := newTestGameDataService(t, &fakeBackend{})
+
+	start := time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+	rendered := testBundle("act1side_zone1", start, start.AddDate(0, 0, 14))
+	rendered.DropInfosMap["act1side_zone1_01"][0].ItemID = null.IntFrom(1)
+
+	if _, err := s.RenderShimBundle(context.Background(), rendered); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("RenderShimBundle() error = %v, want ErrItemNotFound", err)
+	}
+}
+
+func TestShimActivityEnd(t *testing.T) {
+	start := time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 14)
+	if activity := shimActivity(&models.Activity{StartTime: &start, EndTime: &end}); activity.End != null.IntFrom(end.UnixMilli()) {
+		t.Errorf("end %v, want %d", activity.End, end.UnixMilli())
+	}
+	if activity := shimActivity(&models.Activity{StartTime: &start}); activity.End.Valid {
+		t.Errorf("end %v of an activity without an end time, want none", activity.End)
+	}
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/cache"
+	"github.com/penguin-statistics/soracli/internal/pkg/client"
+)
+
+type StageService struct {
+	http *client.Penguin
+}
+
+func NewStageService(http *client.Penguin) *StageService {
+	return &StageService{
+		http: http,
+	}
+}
+
+func (s *StageService) GetStages(ctx context.Context) ([]*models.Stage, error) {
+	var stages []*models.Stage
+	err := cache.Stages.MutexGetSet(&stages, func() ([]*models.Stage, error) {
+		var resp []*models.Stage
+		if err := s.http.GetJSON("/cli/stages", &resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}, time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	return stages, nil
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/cache"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+	"github.com/penguin-statistics/soracli/internal/pkg/client"
+)
+
+var ErrTimeRangeNotFound = errors.New("time range not found")
+
+type TimeRangeService struct {
+	ItemService  *ItemService
+	StageService *StageService
+
+	http *client.Penguin
+}
+
+func NewTimeRangeService(itemService *ItemService, stageService *StageService, http *client.Penguin) *TimeRangeService {
+	return &TimeRangeService{
+		ItemService:  itemService,
+		StageService: stageService,
+		http:         http,
+	}
+}
+
+// GetTimeRanges returns the time ranges of server, ordered by their start times.
+func (s *TimeRangeService) GetTimeRanges(ctx context.Context, server string) ([]*models.TimeRange, error) {
+	var timeRanges []*models.TimeRange
+	_, err := cache.TimeRanges.MutexGetSet(server, &timeRanges, func() (*[]*models.TimeRange, error) {
+		all, err := s.getAllTimeRanges()
+		if err != nil {
+			return nil, err
+		}
+		timeRanges := make([]*models.TimeRange, 0)
+		for _, timeRange := range all {
+			if timeRange.Server == server {
+				timeRanges = append(timeRanges, timeRange)
+			}
+		}
+		// a range without a start time comes first
+		sort.SliceStable(timeRanges, func(i, j int) bool {
+			a, b := timeRanges[i].StartTime, timeRanges[j].StartTime
+			return b != nil && (a == nil || a.Before(*b))
+		})
+		return &timeRanges, nil
+	}, 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	return timeRanges, nil
+}
+
+func (s *TimeRangeService) GetTimeRangeByID(ctx context.Context, id int) (*models.TimeRange, error) {
+	var timeRange models.TimeRange
+	_, err := cache.TimeRangeByID.MutexGetSet(strconv.Itoa(id), &timeRange, func() (*models.TimeRange, error) {
+		all, err := s.getAllTimeRanges()
+		if err != nil {
+			return nil, err
+		}
+		for _, timeRange := range all {
+			if timeRange.RangeID == id {
+				return timeRange, nil
+			}
+		}
+		return nil, errors.Wrapf(ErrTimeRangeNotFound, "time range %d", id)
+	}, 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	return &timeRange, nil
+}
+
+func (s *TimeRangeService) getAllTimeRanges() ([]*models.TimeRange, error) {
+	var resp []*models.TimeRange
+	if err := s.http.GetJSON("/cli/timeranges", &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetDropInfosByRangeID returns the drop infos of a time range. Drop infos are not cached, as they
+// are only looked up right before changing or checking time ranges.
+func (s *TimeRangeService) GetDropInfosByRangeID(ctx context.Context, rangeId int) ([]*models.DropInfo, error) {
+	return s.getDropInfos(func(dropInfo *models.DropInfo) bool {
+		return dropInfo.RangeID == rangeId
+	})
+}
+
+func (s *TimeRangeService) getDropInfos(filter func(dropInfo *models.DropInfo) bool) ([]*models.DropInfo, error) {
+	var resp []*models.DropInfo
+	if err := s.http.GetJSON("/cli/dropinfos", &resp); err != nil {
+		return nil, err
+	}
+	dropInfos := make([]*models.DropInfo, 0)
+	for _, dropInfo := range resp {
+		if filter(dropInfo) {
+			dropInfos = append(dropInfos, dropInfo)
+		}
+	}
+	return dropInfos, nil
+}
+
+// GetSnapshot returns the live items and stages, along with the time ranges and drop infos of
+// server, to run ComputeMaxAccumulableTimeRanges and CheckTimeRanges over.
+func (s *TimeRangeService) GetSnapshot(ctx context.Context, server string) (*types.Snapshot, error) {
+	items, err := s.ItemService.GetItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stages, err := s.StageService.GetStages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timeRanges, err := s.GetTimeRanges(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	dropInfos, err := s.getDropInfos(func(dropInfo *models.DropInfo) bool {
+		return dropInfo.Server == server
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the slices are shared with the caches, so they are copied for callers to append to
+	return &types.Snapshot{
+		Items:      append([]*models.Item(nil), items...),
+		Stages:     append([]*models.Stage(nil), stages...),
+		TimeRanges: append([]*models.TimeRange(nil), timeRanges...),
+		DropInfos:  dropInfos,
+	}, nil
+}
+
+// GetMaxAccumulableTimeRanges computes the max accumulable time ranges of server over live data;
+// see ComputeMaxAccumulableTimeRanges.
+func (s *TimeRangeService) GetMaxAccumulableTimeRanges(ctx context.Context, server string) (map[int]map[int][]*models.TimeRange, error) {
+	var maxAccumulableTimeRanges map[int]map[int][]*models.TimeRange
+	_, err := cache.MaxAccumulableTimeRanges.MutexGetSet(server, &maxAccumulableTimeRanges, func() (*map[int]map[int][]*models.TimeRange, error) {
+		snapshot, err := s.GetSnapshot(ctx, server)
+		if err != nil {
+			return nil, err
+		}
+		computed := ComputeMaxAccumulableTimeRanges(snapshot, server)
+		return &computed, nil
+	}, 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	return maxAccumulableTimeRanges, nil
+}
+
+func (s *TimeRangeService) CreateTimeRange(ctx context.Context, timeRange *models.TimeRange) (*models.TimeRange, error) {
+	var created models.TimeRange
+	if err := s.http.PostJSONWithResponse("/timeranges", timeRange, &created); err != nil {
+		return nil, err
+	}
+	s.invalidate(created.RangeID)
+	return &created, nil
+}
+
+// SplitTimeRange ends a time range at req.SplitTime, and creates a new time range from then on
+// with req.DropInfos. The new range and its drop infos are created before the original range is
+// ended, so that a failure halfway leaves the original range as it was.
+func (s *TimeRangeService) SplitTimeRange(ctx context.Context, req *types.SplitTimeRangeRequest) (*types.SplitTimeRangeResponse, error) {
+	original, err := s.getTimeRange(req.RangeID)
+	if err != nil {
+		return nil, err
+	}
+	if original.StartTime == nil || original.EndTime == nil {
+		return nil, errors.Errorf("time range %d has no start or end time", original.RangeID)
+	}
+	splitTime := time.UnixMilli(req.SplitTime)
+	if !splitTime.After(*original.StartTime) || !splitTime.Before(*original.EndTime) {
+		return nil, errors.Errorf("split time %s is not within time range %d", splitTime.Format(time.RFC3339), original.RangeID)
+	}
+
+	timeRange, err := s.CreateTimeRange(ctx, &models.TimeRange{
+		Name:      req.Name,
+		StartTime: &splitTime,
+		EndTime:   original.EndTime,
+		Comment:   req.Comment,
+		Server:    original.Server,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer s.invalidate(timeRange.RangeID)
+	resp := &types.SplitTimeRangeResponse{TimeRange: timeRange}
+	for _, dropInfo := range req.DropInfos {
+		dup := *dropInfo
+		dup.DropID = 0
+		dup.RangeID = timeRange.RangeID
+		var created models.DropInfo
+		if err := s.http.PostJSONWithResponse("/dropinfos", &dup, &created); err != nil {
+			return nil, errors.Wrapf(err, "created time range %d with %d of its %d drop infos, but time range %d still overlaps it",
+				timeRange.RangeID, len(resp.DropInfos), len(req.DropInfos), original.RangeID)
+		}
+		resp.DropInfos = append(resp.DropInfos, &created)
+	}
+
+	ended := *original
+	ended.EndTime = &splitTime
+	if err := s.updateTimeRange(&ended); err != nil {
+		return nil, errors.Wrapf(err, "created time range %d with its drop infos, but time range %d still overlaps it", timeRange.RangeID, original.RangeID)
+	}
+	resp.Original = &ended
+	return resp, nil
+}
+
+// MergeTimeRanges merges contiguous time ranges of a server into the earliest of them: it is extended to
+// the end of the latest, the drop infos of the later ranges are moved onto it, or deleted if it has a drop
+// info of the same stage, item and drop type already, and the later ranges are deleted.
+func (s *TimeRangeService) MergeTimeRanges(ctx context.Context, req *types.MergeTimeRangesRequest) (*types.MergeTimeRangesResponse, error) {
+	if len(req.RangeIDs) < 2 {
+		return nil, errors.New("at least two time ranges are required")
+	}
+	ranges := make([]*models.TimeRange, 0, len(req.RangeIDs))
+	for _, id := range req.RangeIDs {
+		timeRange, err := s.getTimeRange(id)
+		if err != nil {
+			return nil, err
+		}
+		if timeRange.StartTime == nil || timeRange.EndTime == nil {
+			return nil, errors.Errorf("time range %d has no start or end time", id)
+		}
+		ranges = append(ranges, timeRange)
+	}
+	dropInfos, err := s.getDropInfos(func(dropInfo *models.DropInfo) bool {
+		for _, id := range req.RangeIDs {
+			if dropInfo.RangeID == id {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, moved, err := planMerge(ranges, dropInfos)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		for _, id := range req.RangeIDs {
+			s.invalidate(id)
+		}
+	}()
+	// the merged range is extended first, so that the drop infos moved onto it are within it even if a later step fails
+	if err := s.updateTimeRange(resp.TimeRange); err != nil {
+		return nil, err
+	}
+	for _, dropInfo := range moved {
+		if err := s.http.PostJSON("/dropinfos/"+strconv.Itoa(dropInfo.DropID), dropInfo); err != nil {
+			return nil, errors.Wrapf(err, "extended time range %d, but failed to move drop info %d onto it", resp.TimeRange.RangeID, dropInfo.DropID)
+		}
+	}
+	if len(resp.DeletedDropIDs) > 0 {
+		if err := s.http.PostJSON("/dropinfos/delete", &types.DeleteEntitiesRequest{IDs: resp.DeletedDropIDs}); err != nil {
+			return nil, errors.Wrapf(err, "extended time range %d and moved the drop infos onto it, but failed to delete the duplicated ones %v", resp.TimeRange.RangeID, resp.DeletedDropIDs)
+		}
+	}
+	if err := s.http.PostJSON("/timeranges/delete", &types.DeleteEntitiesRequest{IDs: resp.DeletedRangeIDs}); err != nil {
+		return nil, errors.Wrapf(err, "merged the drop infos into time range %d, but failed to delete time ranges %v", resp.TimeRange.RangeID, resp.DeletedRangeIDs)
+	}
+	return resp, nil
+}
+
+// planMerge plans merging ranges, returning the merged range and the IDs of the ranges and drop infos
+// deleted, along with the drop infos moved onto the merged range.
+func planMerge(ranges []*models.TimeRange, dropInfos []*models.DropInfo) (*types.MergeTimeRangesResponse, []*models.DropInfo, error) {
+	ranges = append([]*models.TimeRange(nil), ranges...)
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].StartTime.Before(*ranges[j].StartTime)
+	})
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i].Server != ranges[0].Server {
+			return nil, nil, errors.Errorf("time ranges %d and %d are of different servers", ranges[0].RangeID, ranges[i].RangeID)
+		}
+		if !ranges[i].StartTime.Equal(*ranges[i-1].EndTime) {
+			return nil, nil, errors.Errorf("time ranges %d and %d are not contiguous", ranges[i-1].RangeID, ranges[i].RangeID)
+		}
+	}
+
+	merged := *ranges[0]
+	merged.EndTime = ranges[len(ranges)-1].EndTime
+	resp := &types.MergeTimeRangesResponse{TimeRange: &merged}
+
+	type dropKey struct {
+		stageID  int
+		itemID   int64
+		dropType string
+	}
+	existing := make(map[dropKey]bool)
+	for _, dropInfo := range dropInfos {
+		if dropInfo.RangeID == merged.RangeID {
+			existing[dropKey{dropInfo.StageID, dropInfo.ItemID.Int64, dropInfo.DropType}] = true
+		}
+	}
+
+	moved := make([]*models.DropInfo, 0)
+	for _, timeRange := range ranges[1:] {
+		resp.DeletedRangeIDs = append(resp.DeletedRangeIDs, timeRange.RangeID)
+		for _, dropInfo := range dropInfos {
+			if dropInfo.RangeID != timeRange.RangeID {
+				continue
+			}
+			key := dropKey{dropInfo.StageID, dropInfo.ItemID.Int64, dropInfo.DropType}
+			if existing[key] {
+				resp.DeletedDropIDs = append(resp.DeletedDropIDs, dropInfo.DropID)
+				continue
+			}
+			existing[key] = true
+			movedDropInfo := *dropInfo
+			movedDropInfo.RangeID = merged.RangeID
+			moved = append(moved, &movedDropInfo)
+			resp.MovedDropIDs = append(resp.MovedDropIDs, dropInfo.DropID)
+		}
+	}
+	return resp, moved, nil
+}
+
+// getTimeRange looks up a time range uncached, as it is about to be changed.
+func (s *TimeRangeService) getTimeRange(id int) (*models.TimeRange, error) {
+	all, err := s.getAllTimeRanges()
+	if err != nil {
+		return nil, err
+	}
+	for _, timeRange := range all {
+		if timeRange.RangeID == id {
+			return timeRange, nil
+		}
+	}
+	return nil, errors.Wrapf(ErrTimeRangeNotFound, "time range %d", id)
+}
+
+func (s *TimeRangeService) updateTimeRange(timeRange *models.TimeRange) error {
+	if err := s.http.PostJSON("/timeranges/"+strconv.Itoa(timeRange.RangeID), timeRange); err != nil {
+		return err
+	}
+	s.invalidate(timeRange.RangeID)
+	return nil
+}
+
+// invalidate invalidates the caches of a time range, along with the caches derived from drop infos,
+// as changing time ranges moves drop infos between them.
+func (s *TimeRangeService) invalidate(rangeId int) {
+	for _, tag := range []string{cache.TagTimeRange(rangeId), cache.TagAny(cache.TagKindDropInfo)} {
+		if err := cache.InvalidateTag(tag); err != nil {
+			log.Warn().Err(err).Str("tag", tag).Msg("failed to invalidate time range caches")
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+	"github.com/penguin-statistics/soracli/internal/pkg/gdutils"
+)
+
+// timeRangeGapTolerance is the longest gap between two time ranges of a stage that is reported;
+// longer gaps are taken as the stage being closed on purpose, e.g. between an event and its rerun.
+const timeRangeGapTolerance = 24 * time.Hour
+
+// ComputeMaxAccumulableTimeRanges computes, for each stage and item of server, the time ranges whose
+// drop results may be accumulated together, keyed by stage ID and then item ID. Walking back from the
+// latest time range of the drop, ranges are collected as long as the drop infos are accumulable; the
+// latest time range is always included. Ranges are ordered by their start times.
+func ComputeMaxAccumulableTimeRanges(snapshot *types.Snapshot, server string) map[int]map[int][]*models.TimeRange {
+	timeRangesMap := timeRangesMapOf(snapshot, server)
+
+	results := make(map[int]map[int][]*models.TimeRange)
+	for key, dropInfos := range itemDropInfosOf(snapshot, server, timeRangesMap) {
+		ranges := make([]*models.TimeRange, 0, len(dropInfos))
+		for i := len(dropInfos) - 1; i >= 0; i-- {
+			dropInfo := dropInfos[i]
+			if i < len(dropInfos)-1 && !dropInfo.Accumulable {
+				break
+			}
+			ranges = append(ranges, timeRangesMap[dropInfo.RangeID])
+			if !dropInfo.Accumulable {
+				break
+			}
+		}
+		// collected backwards
+		for i, j := 0, len(ranges)-1; i < j; i, j = i+1, j-1 {
+			ranges[i], ranges[j] = ranges[j], ranges[i]
+		}
+
+		if results[key.stageID] == nil {
+			results[key.stageID] = make(map[int][]*models.TimeRange)
+		}
+		results[key.stageID][key.itemID] = ranges
+	}
+	return results
+}
+
+// CheckTimeRanges reports overlapping time ranges of a stage, short gaps between them, and drop infos
+// of an item whose Accumulable flag differs from the one of the neighbouring time range. If focusRangeID
+// is not zero, only findings involving that time range are reported.
+func CheckTimeRanges(snapshot *types.Snapshot, server string, focusRangeID int) []*gamedata.PreflightFinding {
+	timeRangesMap := timeRangesMapOf(snapshot, server)
+	arkStageIDs := make(map[int]string, len(snapshot.Stages))
+	for _, stage := range snapshot.Stages {
+		arkStageIDs[stage.StageID] = stage.ArkStageID
+	}
+	arkItemIDs := make(map[int]string, len(snapshot.Items))
+	for _, item := range snapshot.Items {
+		arkItemIDs[item.ItemID] = item.ArkItemID
+	}
+	involvesFocus := func(ranges ...*models.TimeRange) bool {
+		if focusRangeID == 0 {
+			return true
+		}
+		for _, timeRange := range ranges {
+			if timeRange.RangeID == focusRangeID {
+				return true
+			}
+		}
+		return false
+	}
+
+	findings := make([]*gamedata.PreflightFinding, 0)
+
+	stageTimeRanges := stageTimeRangesOf(snapshot, server, timeRangesMap)
+	for _, stageID := range sortedKeys(stageTimeRanges) {
+		ranges := stageTimeRanges[stageID]
+		subject := fmt.Sprintf("stage %s (%s)", gdutils.ArkIDOf(arkStageIDs, stageID), server)
+
+		latest := ranges[0]
+		for i := 1; i < len(ranges); i++ {
+			next := ranges[i]
+			for _, prev := range ranges[:i] {
+				if next.StartTime.Before(*prev.EndTime) && involvesFocus(prev, next) {
+					findings = append(findings, &gamedata.PreflightFinding{
+						Severity:   gamedata.PreflightSeverityWarning,
+						Subject:    subject,
+						Message:    fmt.Sprintf("%s overlaps %s", describeTimeRange(next), describeTimeRange(prev)),
+						Suggestion: "end the earlier time range before the later one starts, e.g. with `soracli timerange split`",
+					})
+				}
+			}
+
+			gap := next.StartTime.Sub(*latest.EndTime)
+			if gap > 0 && gap < timeRangeGapTolerance && involvesFocus(latest, next) {
+				findings = append(findings, &gamedata.PreflightFinding{
+					Severity:   gamedata.PreflightSeverityWarning,
+					Subject:    subject,
+					Message:    fmt.Sprintf("gap of %s between %s and %s", gap, describeTimeRange(latest), describeTimeRange(next)),
+					Suggestion: "start the later time range when the earlier one ends, unless the stage is closed in between",
+				})
+			}
+			if next.EndTime.After(*latest.EndTime) {
+				latest = next
+			}
+		}
+	}
+
+	itemDropInfos := itemDropInfosOf(snapshot, server, timeRangesMap)
+	keys := make([]stageItemKey, 0, len(itemDropInfos))
+	for key := range itemDropInfos {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].stageID != keys[j].stageID {
+			return keys[i].stageID < keys[j].stageID
+		}
+		return keys[i].itemID < keys[j].itemID
+	})
+	for _, key := range keys {
+		dropInfos := itemDropInfos[key]
+		for i := 1; i < len(dropInfos); i++ {
+			prev, next := dropInfos[i-1], dropInfos[i]
+			if prev.Accumulable == next.Accumulable {
+				continue
+			}
+			prevRange, nextRange := timeRangesMap[prev.RangeID], timeRangesMap[next.RangeID]
+			if nextRange.StartTime.Sub(*prevRange.EndTime) >= timeRangeGapTolerance || !involvesFocus(prevRange, nextRange) {
+				continue
+			}
+			findings = append(findings, &gamedata.PreflightFinding{
+				Severity: gamedata.PreflightSeverityWarning,
+				Subject:  fmt.Sprintf("stage %s (%s), item %s", gdutils.ArkIDOf(arkStageIDs, key.stageID), server, gdutils.ArkIDOf(arkItemIDs, key.itemID)),
+				Message: fmt.Sprintf("drop info is accumulable=%t on %s but accumulable=%t on the neighbouring %s",
+					next.Accumulable, describeTimeRange(nextRange), prev.Accumulable, describeTimeRange(prevRange)),
+				Suggestion: "check whether the drop changed between the time ranges; if not, make the flags agree",
+			})
+		}
+	}
+
+	return findings
+}
+
+type stageItemKey struct {
+	stageID int
+	itemID  int
+}
+
+func timeRangesMapOf(snapshot *types.Snapshot, server string) map[int]*models.TimeRange {
+	timeRangesMap := make(map[int]*models.TimeRange)
+	for _, timeRange := range snapshot.TimeRanges {
+		if timeRange.Server == server && timeRange.StartTime != nil && timeRange.EndTime != nil {
+			timeRangesMap[timeRange.RangeID] = timeRange
+		}
+	}
+	return timeRangesMap
+}
+
+// itemDropInfosOf groups the item drop infos of server by stage and item, ordered by the start
+// times of their time ranges.
+func itemDropInfosOf(snapshot *types.Snapshot, server string, timeRangesMap map[int]*models.TimeRange) map[stageItemKey][]*models.DropInfo {
+	grouped := make(map[stageItemKey][]*models.DropInfo)
+	for _, dropInfo := range snapshot.DropInfos {
+		if dropInfo.Server != server || !dropInfo.ItemID.Valid {
+			continue
+		}
+		if _, ok := timeRangesMap[dropInfo.RangeID]; !ok {
+			continue
+		}
+		key := stageItemKey{stageID: dropInfo.StageID, itemID: int(dropInfo.ItemID.Int64)}
+		grouped[key] = append(grouped[key], dropInfo)
+	}
+	for _, dropInfos := range grouped {
+		sort.SliceStable(dropInfos, func(i, j int) bool {
+			return timeRangesMap[dropInfos[i].RangeID].StartTime.Before(*timeRangesMap[dropInfos[j].RangeID].StartTime)
+		})
+	}
+	return grouped
+}
+
+// stageTimeRangesOf returns the distinct time ranges each stage of server has drop infos in,
+// ordered by their start times.
+func stageTimeRangesOf(snapshot *types.Snapshot, server string, timeRangesMap map[int]*models.TimeRange) map[int][]*models.TimeRange {
+	type stageRangeKey struct {
+		stageID int
+		rangeID int
+	}
+	seen := make(map[stageRangeKey]bool)
+	stageRanges := make(map[int][]*models.TimeRange)
+	for _, dropInfo := range snapshot.DropInfos {
+		timeRange, ok := timeRangesMap[dropInfo.RangeID]
+		if dropInfo.Server != server || !ok {
+			continue
+		}
+		key := stageRangeKey{stageID: dropInfo.StageID, rangeID: dropInfo.RangeID}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		stageRanges[dropInfo.StageID] = append(stageRanges[dropInfo.StageID], timeRange)
+	}
+	for _, ranges := range stageRanges {
+		sort.SliceStable(ranges, func(i, j int) bool {
+			return ranges[i].StartTime.Before(*ranges[j].StartTime)
+		})
+	}
+	return stageRanges
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func describeTimeRange(timeRange *models.TimeRange) string {
+	if timeRange.RangeID <= 0 {
+		return fmt.Sprintf("the new time range %s", timeRange.String())
+	}
+	return fmt.Sprintf("time range %d %s", timeRange.RangeID, timeRange.String())
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+)
+
+// checkSnapshot builds a snapshot of stage 1 (main_01-07) and item 1 (30012) on CN, with a time range of
+// each of ranges, given as [start, end) in days of May 2022, and a drop info of the item in each, of the
+// accumulability of accumulable at the same index.
+func checkSnapshot(ranges [][2]float64, accumulable []bool) *types.Snapshot {
+	snapshot := &types.Snapshot{
+		Stages: []*models.Stage{{StageID: 1, ArkStageID: "main_01-07"}},
+		Items:  []*models.Item{{ItemID: 1, ArkItemID: "30012"}},
+	}
+	for i, r := range ranges {
+		day := func(d float64) *time.Time {
+			t := time.Date(2022, 4, 30, 0, 0, 0, 0, time.UTC).Add(time.Duration(d * float64(24*time.Hour)))
+			return &t
+		}
+		rangeID := i + 1
+		snapshot.TimeRanges = append(snapshot.TimeRanges, &models.TimeRange{RangeID: rangeID, Server: "CN", StartTime: day(r[0]), EndTime: day(r[1])})
+		snapshot.DropInfos = append(snapshot.DropInfos, &models.DropInfo{
+			DropID:      rangeID,
+			Server:      "CN",
+			StageID:     1,
+			ItemID:      null.IntFrom(1),
+			DropType:    "NORMAL_DROP",
+			RangeID:     rangeID,
+			Accumulable: accumulable[i],
+		})
+	}
+	return snapshot
+}
+
+func TestComputeMaxAccumulableTimeRanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		ranges      [][2]float64
+		accumulable []bool
+		want        []int
+	}{
+		{"all accumulable", [][2]float64{{1, 2}, {2, 3}, {3, 4}}, []bool{true, true, true}, []int{1, 2, 3}},
+		{"the latest alone is always included", [][2]float64{{1, 2}, {2, 3}}, []bool{true, false}, []int{2}},
+		{"stops at a non-accumulable range", [][2]float64{{1, 2}, {2, 3}, {3, 4}}, []bool{true, false, true}, []int{3}},
+		{"ordered by start times", [][2]float64{{3, 4}, {1, 2}, {2, 3}}, []bool{true, true, true}, []int{2, 3, 1}},
+		{"a single range", [][2]float64{{1, 2}}, []bool{false}, []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComputeMaxAccumulableTimeRanges(checkSnapshot(tt.ranges, tt.accumulable), "CN")
+			rangeIDs := make([]int, 0)
+			for _, timeRange := range got[1][1] {
+				rangeIDs = append(rangeIDs, timeRange.RangeID)
+			}
+			if !reflect.DeepEqual(rangeIDs, tt.want) {
+				t.Errorf("max accumulable time ranges %v, want %v", rangeIDs, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeMaxAccumulableTimeRangesOtherServers(t *testing.T) {
+	snapshot := checkSnapshot([][2]float64{{1, 2}, {2, 3}}, []bool{true, true})
+	snapshot.TimeRanges[1].Server, snapshot.DropInfos[1].Server = "US", "US"
+	// drop infos of drop types without an item are not accumulated
+	snapshot.DropInfos = append(snapshot.DropInfos, &models.DropInfo{DropID: 3, Server: "CN", StageID: 1, DropType: "NORMAL_DROP", RangeID: 1})
+
+	got := ComputeMaxAccumulableTimeRanges(snapshot, "CN")
+	if len(got) != 1 || len(got[1]) != 1 || len(got[1][1]) != 1 || got[1][1][0].RangeID != 1 {
+		t.Errorf("max accumulable time ranges %v, want only time range 1 of the item on CN", got)
+	}
+}
+
+func TestCheckTimeRanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		ranges      [][2]float64
+		accumulable []bool
+		focus       int
+		// want holds the subject and a part of the message of each finding
+		want []string
+	}{
+		{
+			name:        "contiguous",
+			ranges:      [][2]float64{{1, 2}, {2, 3}},
+			accumulable: []bool{true, true},
+		},
+		{
+			name:        "overlap",
+			ranges:      [][2]float64{{1, 3}, {2, 4}},
+			accumulable: []bool{true, true},
+			want:        []string{"stage main_01-07 (CN): time range 2 1651449600000-1651622400000 overlaps time range 1"},
+		},
+		{
+			name:        "short gap",
+			ranges:      [][2]float64{{1, 2}, {2.5, 3}},
+			accumulable: []bool{true, true},
+			want:        []string{"stage main_01-07 (CN): gap of 12h0m0s"},
+		},
+		{
+			name:        "a long gap is the stage closed on purpose",
+			ranges:      [][2]float64{{1, 2}, {3, 4}},
+			accumulable: []bool{true, false},
+		},
+		{
+			name:        "accumulability changing between neighbouring ranges",
+			ranges:      [][2]float64{{1, 2}, {2, 3}},
+			accumulable: []bool{true, false},
+			want:        []string{"stage main_01-07 (CN), item 30012: drop info is accumulable=false on time range 2"},
+		},
+		{
+			// the gap is measured from the latest end of the earlier ranges
+			name:        "gap after a range contained in an earlier one",
+			ranges:      [][2]float64{{1, 5}, {2, 3}, {5.5, 6}},
+			accumulable: []bool{true, true, true},
+			want: []string{
+				"stage main_01-07 (CN): time range 2 1651449600000-1651536000000 overlaps time range 1",
+				"stage main_01-07 (CN): gap of 12h0m0s between time range 1",
+			},
+		},
+		{
+			name:        "only the findings of the focused range",
+			ranges:      [][2]float64{{1, 3}, {2, 4}, {4.5, 5}},
+			accumulable: []bool{true, true, true},
+			focus:       3,
+			want:        []string{"stage main_01-07 (CN): gap of 12h0m0s between time range 2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := CheckTimeRanges(checkSnapshot(tt.ranges, tt.accumulable), "CN", tt.focus)
+			if len(findings) != len(tt.want) {
+				t.Fatalf("got %d findings %v, want %d", len(findings), findings, len(tt.want))
+			}
+			for i, finding := range findings {
+				got := finding.Subject + ": " + finding.Message
+				if !strings.HasPrefix(got, tt.want[i]) {
+					t.Errorf("finding %d is %q, want it to start with %q", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/cache"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+)
+
+const (
+	undoKindDropInfo  = "dropInfo"
+	undoKindStage     = "stage"
+	undoKindTimeRange = "timeRange"
+	undoKindZone      = "zone"
+	undoKindActivity  = "activity"
+)
+
+// savedEntities are the entities a bundle may touch, as they were before saving it.
+type savedEntities struct {
+	zones       map[string]*models.Zone
+	stages      map[string]*models.Stage
+	rangeIDs    map[int]bool
+	activityIDs map[int]bool
+}
+
+// entitiesBeforeSave takes the IDs of the entities right before saving rendered. The caches are dropped
+// first, as an entity created after they were filled would otherwise be taken as created by the bundle,
+// and deleted when it is undone.
+func (s *GameDataService) entitiesBeforeSave(ctx context.Context, rendered *gamedata.RenderedObjects) (*savedEntities, error) {
+	s.invalidateGameData("pre-save")
+
+	zones, err := s.ZoneService.GetZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stages, err := s.StageService.GetStages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	activities, err := s.ActivityService.GetActivities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	before := &savedEntities{
+		zones:       make(map[string]*models.Zone),
+		stages:      make(map[string]*models.Stage),
+		rangeIDs:    make(map[int]bool),
+		activityIDs: make(map[int]bool),
+	}
+	for _, zone := range zones {
+		before.zones[zone.ArkZoneID] = zone
+	}
+	for _, stage := range stages {
+		before.stages[stage.ArkStageID] = stage
+	}
+	for _, activity := range activities {
+		before.activityIDs[activity.ActivityID] = true
+	}
+	if rendered.TimeRange != nil {
+		timeRanges, err := s.TimeRangeService.getAllTimeRanges()
+		if err != nil {
+			return nil, err
+		}
+		for _, timeRange := range timeRanges {
+			before.rangeIDs[timeRange.RangeID] = true
+		}
+	}
+	return before, nil
+}
+
+// resolveAppliedBundle takes the IDs of the saved entities from saved, as responded by the admin api,
+// or looks up the ones missing.
+func (s *GameDataService) resolveAppliedBundle(ctx context.Context, rendered, saved *gamedata.RenderedObjects, before *savedEntities) (*gamedata.AppliedBundle, error) {
+	applied := &gamedata.AppliedBundle{
+		Session:   s.pgclient.Session(),
+		BaseURL:   s.pgclient.BaseURL(),
+		AppliedAt: time.Now(),
+		Created:   &gamedata.AppliedIDs{},
+		Updated:   &gamedata.AppliedIDs{},
+		Prior: &gamedata.PriorExistences{
+			Zones:  make(map[int]json.RawMessage),
+			Stages: make(map[int]json.RawMessage),
+		},
+		Rendered: rendered,
+	}
+	if rendered.TimeRange != nil {
+		applied.Server = rendered.TimeRange.Server
+	}
+
+	if rendered.Zone != nil {
+		zoneID := 0
+		if saved.Zone != nil {
+			zoneID = saved.Zone.ZoneID
+		}
+		if zoneID == 0 {
+			zones, err := s.ZoneService.GetZones(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, zone := range zones {
+				if zone.ArkZoneID == rendered.Zone.ArkZoneID {
+					zoneID = zone.ZoneID
+				}
+			}
+		}
+		if zoneID == 0 {
+			return nil, errors.Errorf("cannot find the saved zone %s", rendered.Zone.ArkZoneID)
+		}
+		if prior, ok := before.zones[rendered.Zone.ArkZoneID]; ok {
+			applied.Updated.ZoneIDs = append(applied.Updated.ZoneIDs, zoneID)
+			applied.Prior.Zones[zoneID] = prior.Existence
+		} else {
+			applied.Created.ZoneIDs = append(applied.Created.ZoneIDs, zoneID)
+		}
+	}
+
+	savedStageIDs := make(map[string]int, len(saved.Stages))
+	for _, stage := range saved.Stages {
+		savedStageIDs[stage.ArkStageID] = stage.StageID
+	}
+	var stages []*models.Stage
+	for _, renderedStage := range rendered.Stages {
+		stageID := savedStageIDs[renderedStage.ArkStageID]
+		if stageID == 0 {
+			if stages == nil {
+				var err error
+				if stages, err = s.StageService.GetStages(ctx); err != nil {
+					return nil, err
+				}
+			}
+			for _, stage := range stages {
+				if stage.ArkStageID == renderedStage.ArkStageID {
+					stageID = stage.StageID
+				}
+			}
+		}
+		if stageID == 0 {
+			return nil, errors.Errorf("cannot find the saved stage %s", renderedStage.ArkStageID)
+		}
+		if prior, ok := before.stages[renderedStage.ArkStageID]; ok {
+			applied.Updated.StageIDs = append(applied.Updated.StageIDs, stageID)
+			applied.Prior.Stages[stageID] = prior.Existence
+		} else {
+			applied.Created.StageIDs = append(applied.Created.StageIDs, stageID)
+		}
+	}
+
+	if rendered.TimeRange != nil {
+		rangeID := 0
+		if saved.TimeRange != nil {
+			rangeID = saved.TimeRange.RangeID
+		}
+		if rangeID == 0 {
+			var err error
+			if rangeID, err = s.newRangeID(rendered.TimeRange, before); err != nil {
+				return nil, err
+			}
+		}
+		applied.Created.RangeIDs = append(applied.Created.RangeIDs, rangeID)
+
+		// every drop info of the new time range is created by the bundle
+		dropInfos, err := s.TimeRangeService.GetDropInfosByRangeID(ctx, rangeID)
+		if err != nil {
+			return nil, err
+		}
+		for _, dropInfo := range dropInfos {
+			applied.Created.DropIDs = append(applied.Created.DropIDs, dropInfo.DropID)
+		}
+	}
+
+	if rendered.Activity != nil {
+		activityID := 0
+		if saved.Activity != nil {
+			activityID = saved.Activity.ActivityID
+		}
+		if activityID == 0 {
+			activities, err := s.ActivityService.GetActivities(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, activity := range activities {
+				if !before.activityIDs[activity.ActivityID] && activity.ActivityID > activityID &&
+					timesEqual(activity.StartTime, rendered.Activity.StartTime) {
+					activityID = activity.ActivityID
+				}
+			}
+		}
+		if activityID == 0 {
+			return nil, errors.New("cannot find the saved activity")
+		}
+		applied.Created.ActivityIDs = append(applied.Created.ActivityIDs, activityID)
+	}
+
+	return applied, nil
+}
+
+// newRangeID returns the ID of the time range saved for rendered: the one range of its server that did not
+// exist before. Only if other ranges were saved on the server meanwhile are they told apart by their times.
+func (s *GameDataService) newRangeID(rendered *models.TimeRange, before *savedEntities) (int, error) {
+	timeRanges, err := s.TimeRangeService.getAllTimeRanges()
+	if err != nil {
+		return 0, err
+	}
+	var created []*models.TimeRange
+	for _, timeRange := range timeRanges {
+		if timeRange.Server == rendered.Server && !before.rangeIDs[timeRange.RangeID] {
+			created = append(created, timeRange)
+		}
+	}
+	if len(created) > 1 {
+		matching := created[:0]
+		for _, timeRange := range created {
+			if timesEqual(timeRange.StartTime, rendered.StartTime) && timesEqual(timeRange.EndTime, rendered.EndTime) {
+				matching = append(matching, timeRange)
+			}
+		}
+		created = matching
+	}
+	switch len(created) {
+	case 0:
+		return 0, errors.Errorf("cannot find the saved time range %s", rendered.String())
+	case 1:
+		return created[0].RangeID, nil
+	default:
+		return 0, errors.Errorf("cannot tell the saved time range %s apart from %d others saved meanwhile", rendered.String(), len(created)-1)
+	}
+}
+
+// PlanUndo returns the steps undoing applied: entities created by the bundle are deleted, and the zone
+// and stages that existed before get their prior existences back. An updated entity whose prior existence
+// is not recorded is left as saved, rather than hidden. Entities are removed before the ones they depend
+// on: drop infos, stages, the time range, the zone, then the activity.
+func PlanUndo(applied *gamedata.AppliedBundle) []*gamedata.UndoStep {
+	steps := make([]*gamedata.UndoStep, 0)
+	prior := applied.Prior
+	if prior == nil {
+		prior = &gamedata.PriorExistences{}
+	}
+	deleteStep := func(kind, endpoint string, ids []int) {
+		if len(ids) > 0 {
+			steps = append(steps, &gamedata.UndoStep{Action: gamedata.UndoActionDelete, Kind: kind, IDs: ids, Endpoint: endpoint})
+		}
+	}
+	restoreStep := func(kind, endpoint string, ids []int, existences map[int]json.RawMessage) {
+		for _, id := range ids {
+			existence, ok := existences[id]
+			if !ok {
+				log.Warn().Str("kind", kind).Int("id", id).Msg("the existence before the bundle was saved is not recorded; leaving it as saved")
+				continue
+			}
+			steps = append(steps, &gamedata.UndoStep{Action: gamedata.UndoActionRestoreExistence, Kind: kind, IDs: []int{id}, Endpoint: endpoint + strconv.Itoa(id), Existence: existence})
+		}
+	}
+
+	deleteStep(undoKindDropInfo, "/dropinfos/delete", applied.Created.DropIDs)
+	deleteStep(undoKindStage, "/stages/delete", applied.Created.StageIDs)
+	restoreStep(undoKindStage, "/stages/", applied.Updated.StageIDs, prior.Stages)
+	deleteStep(undoKindTimeRange, "/timeranges/delete", applied.Created.RangeIDs)
+	deleteStep(undoKindZone, "/zones/delete", applied.Created.ZoneIDs)
+	restoreStep(undoKindZone, "/zones/", applied.Updated.ZoneIDs, prior.Zones)
+	deleteStep(undoKindActivity, "/activities/delete", applied.Created.ActivityIDs)
+	return steps
+}
+
+// Undo runs the steps undoing applied, as planned by PlanUndo, stopping at the first failure.
+func (s *GameDataService) Undo(ctx context.Context, applied *gamedata.AppliedBundle, steps []*gamedata.UndoStep) error {
+	defer s.invalidateGameData("undone")
+
+	for _, step := range steps {
+		var err error
+		switch step.Action {
+		case gamedata.UndoActionDelete:
+			err = s.pgclient.PostJSON(step.Endpoint, &types.DeleteEntitiesRequest{IDs: step.IDs})
+		case gamedata.UndoActionRestoreExistence:
+			err = s.restoreExistence(ctx, step)
+		default:
+			err = errors.Errorf("unknown undo action %q", step.Action)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to %s %s %v", step.Action, step.Kind, step.IDs)
+		}
+		log.Info().Str("action", step.Action).Str("kind", step.Kind).Ints("ids", step.IDs).Msg("undid part of the applied bundle")
+	}
+	return nil
+}
+
+func (s *GameDataService) restoreExistence(ctx context.Context, step *gamedata.UndoStep) error {
+	id := step.IDs[0]
+	switch step.Kind {
+	case undoKindStage:
+		stages, err := s.StageService.GetStages(ctx)
+		if err != nil {
+			return err
+		}
+		for _, stage := range stages {
+			if stage.StageID != id {
+				continue
+			}
+			updated := *stage
+			updated.Existence = step.Existence
+			return s.pgclient.PostJSON(step.Endpoint, &updated)
+		}
+	case undoKindZone:
+		zones, err := s.ZoneService.GetZones(ctx)
+		if err != nil {
+			return err
+		}
+		for _, zone := range zones {
+			if zone.ZoneID != id {
+				continue
+			}
+			updated := *zone
+			updated.Existence = step.Existence
+			return s.pgclient.PostJSON(step.Endpoint, &updated)
+		}
+	}
+	return errors.Errorf("%s %d not found", step.Kind, id)
+}
+
+// invalidateGameData invalidates the caches of every zone, stage, time range, drop info and activity, after
+// changes too many to invalidate one by one; reason tells what the game data is, e.g. "undone".
+func (s *GameDataService) invalidateGameData(reason string) {
+	tags := []string{
+		cache.TagAny(cache.TagKindZone),
+		cache.TagAny(cache.TagKindStage),
+		cache.TagAny(cache.TagKindTimeRange),
+		cache.TagAny(cache.TagKindDropInfo),
+		cache.TagAny(cache.TagKindActivity),
+	}
+	for _, tag := range tags {
+		if err := cache.InvalidateTag(tag); err != nil {
+			log.Warn().Err(err).Str("tag", tag).Msgf("failed to invalidate caches of the %s game data", reason)
+		}
+	}
+}
+
+func timesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/cache"
+	"github.com/penguin-statistics/soracli/internal/models/gamedata"
+	"github.com/penguin-statistics/soracli/internal/models/types"
+	"github.com/penguin-statistics/soracli/internal/pkg/client"
+)
+
+func init() {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+}
+
+// fakeBackend serves the listings the applied bundle is resolved from, and saves bundles
+// without responding with the saved IDs, so that they are looked up.
+type fakeBackend struct {
+	m sync.Mutex
+
+	// noListings answers the listings with 404, as a backend serving only /save would
+	noListings bool
+	saved      int
+
+	items      []*models.Item
+	zones      []*models.Zone
+	stages     []*models.Stage
+	timeRanges []*models.TimeRange
+	dropInfos  []*models.DropInfo
+	activities []*models.Activity
+}
+
+func (b *fakeBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.noListings && r.URL.Path != "/save" {
+		http.NotFound(w, r)
+		return
+	}
+	var v any
+	switch r.URL.Path {
+	case "/cli/gamedata/seed":
+		v = types.CliGameDataSeedResponse{Items: b.items}
+	case "/cli/zones":
+		v = b.zones
+	case "/cli/stages":
+		v = b.stages
+	case "/cli/timeranges":
+		v = b.timeRanges
+	case "/cli/dropinfos":
+		v = b.dropInfos
+	case "/cli/activities":
+		v = b.activities
+	case "/save":
+		var rendered gamedata.RenderedObjects
+		if err := json.NewDecoder(r.Body).Decode(&rendered); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b.save(&rendered)
+		v = struct{}{}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		panic(err)
+	}
+}
+
+func (b *fakeBackend) save(rendered *gamedata.RenderedObjects) {
+	b.saved++
+	zoneID := 0
+	for _, zone := range b.zones {
+		if zone.ArkZoneID == rendered.Zone.ArkZoneID {
+			zoneID = zone.ZoneID
+		}
+	}
+	if zoneID == 0 {
+		zoneID = 100 + len(b.zones)
+		zone := *rendered.Zone
+		zone.ZoneID = zoneID
+		b.zones = append(b.zones, &zone)
+	}
+
+	rangeID := 100 + len(b.timeRanges)
+	timeRange := *rendered.TimeRange
+	timeRange.RangeID = rangeID
+	b.timeRanges = append(b.timeRanges, &timeRange)
+
+	for _, stage := range rendered.Stages {
+		stageID := 0
+		for _, existing := range b.stages {
+			if existing.ArkStageID == stage.ArkStageID {
+				stageID = existing.StageID
+			}
+		}
+		if stageID == 0 {
+			stageID = 100 + len(b.stages)
+			saved := *stage
+			saved.StageID, saved.ZoneID = stageID, zoneID
+			b.stages = append(b.stages, &saved)
+		}
+		for _, dropInfo := range rendered.DropInfosMap[stage.ArkStageID] {
+			saved := *dropInfo
+			saved.DropID, saved.StageID, saved.RangeID = 100+len(b.dropInfos), stageID, rangeID
+			b.dropInfos = append(b.dropInfos, &saved)
+		}
+	}
+}
+
+func newTestGameDataService(t *testing.T, backend *fakeBackend) *GameDataService {
+	t.Helper()
+	cache.Initialize()
+	srv := httptest.NewServer(backend)
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() {
+		for _, flush := range cache.CacheSingularFlusherMap {
+			flush()
+		}
+		for _, flush := range cache.CacheSetMap {
+			flush()
+		}
+	})
+
+	pg := client.NewHTTP(srv.URL, "token")
+	itemService := NewItemService(pg)
+	stageService := NewStageService(pg)
+	return NewGameDataService(itemService, stageService, NewTimeRangeService(itemService, stageService, pg), NewZoneService(pg), NewActivityService(pg), pg)
+}
+
+func testBundle(arkZoneId string, start, end time.Time) *gamedata.RenderedObjects {
+	existence := json.RawMessage(fmt.Sprintf(`{"CN":{"exist":true,"openTime":%d,"closeTime":%d}}`, start.UnixMilli(), end.UnixMilli()))
+	return &gamedata.RenderedObjects{
+		Zone:   &models.Zone{ArkZoneID: arkZoneId, Existence: existence},
+		Stages: []*models.Stage{{ArkStageID: arkZoneId + "_01", Existence: existence}},
+		DropInfosMap: map[string][]*models.DropInfo{
+			arkZoneId + "_01": {{Server: "CN", DropType: "NORMAL_DROP"}},
+		},
+		TimeRange: &models.TimeRange{Server: "CN", StartTime: &start, EndTime: &end},
+	}
+}
+
+func TestUpdateNewEventPreExistingZone(t *testing.T) {
+	ctx := context.Background()
+	backend := &fakeBackend{}
+	s := newTestGameDataService(t, backend)
+
+	// the caches are filled before the zone and stage are saved by someone else
+	if _, err := s.ZoneService.GetZones(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.StageService.GetStages(ctx); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+	end := start.Add(14 * 24 * time.Hour)
+	existing := testBundle("act1side_zone1", start, end)
+	backend.save(existing)
+
+	rerun := start.AddDate(0, 3, 0)
+	applied, err := s.UpdateNewEvent(ctx, testBundle("act1side_zone1", rerun, rerun.Add(14*24*time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(applied.Created.ZoneIDs) != 0 || len(applied.Updated.ZoneIDs) != 1 || applied.Updated.ZoneIDs[0] != 100 {
+		t.Errorf("zones: created %v, updated %v; want zone 100 updated", applied.Created.ZoneIDs, applied.Updated.ZoneIDs)
+	}
+	if len(applied.Created.StageIDs) != 0 || len(applied.Updated.StageIDs) != 1 || applied.Updated.StageIDs[0] != 100 {
+		t.Errorf("stages: created %v, updated %v; want stage 100 updated", applied.Created.StageIDs, applied.Updated.StageIDs)
+	}
+	if got, want := string(applied.Prior.Zones[100]), string(existing.Zone.Existence); got != want {
+		t.Errorf("prior existence of zone 100 %s, want %s", got, want)
+	}
+	if got, want := string(applied.Prior.Stages[100]), string(existing.Stages[0].Existence); got != want {
+		t.Errorf("prior existence of stage 100 %s, want %s", got, want)
+	}
+	if len(applied.Created.RangeIDs) != 1 || applied.Created.RangeIDs[0] != 101 {
+		t.Errorf("created ranges %v, want [101]", applied.Created.RangeIDs)
+	}
+	if len(applied.Created.DropIDs) != 1 || applied.Created.DropIDs[0] != 101 {
+		t.Errorf("created drop infos %v, want [101]", applied.Created.DropIDs)
+	}
+}
+
+func TestUpdateNewEventNewZone(t *testing.T) {
+	ctx := context.Background()
+	backend := &fakeBackend{}
+	s := newTestGameDataService(t, backend)
+
+	start := time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+	applied, err := s.UpdateNewEvent(ctx, testBundle("act2side_zone1", start, start.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(applied.Created.ZoneIDs) != 1 || len(applied.Updated.ZoneIDs) != 0 {
+		t.Errorf("zones: created %v, updated %v; want one created", applied.Created.ZoneIDs, applied.Updated.ZoneIDs)
+	}
+	if len(applied.Created.StageIDs) != 1 || len(applied.Updated.StageIDs) != 0 {
+		t.Errorf("stages: created %v, updated %v; want one created", applied.Created.StageIDs, applied.Updated.StageIDs)
+	}
+	if len(applied.Created.RangeIDs) != 1 || applied.Created.RangeIDs[0] != 100 {
+		t.Errorf("created ranges %v, want [100]", applied.Created.RangeIDs)
+	}
+}
+
+func TestUpdateNewEventWithoutListings(t *testing.T) {
+	backend := &fakeBackend{noListings: true}
+	s := newTestGameDataService(t, backend)
+
+	start := time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+	applied, err := s.UpdateNewEvent(context.Background(), testBundle("act3side_zone1", start, start.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backend.saved != 1 {
+		t.Errorf("saved %d bundles, want 1", backend.saved)
+	}
+	if applied != nil {
+		t.Errorf("applied = %+v, want nil as the saved entities cannot be told", applied)
+	}
+}
+
+func TestPlanUndo(t *testing.T) {
+	prior := json.RawMessage(`{"CN":{"exist":true,"openTime":1651392000000,"closeTime":1652601600000}}`)
+	tests := []struct {
+		name    string
+		applied *gamedata.AppliedBundle
+		want    []string
+	}{
+		{
+			name: "created",
+			applied: &gamedata.AppliedBundle{
+				Created: &gamedata.AppliedIDs{ZoneIDs: []int{1}, StageIDs: []int{1, 2}, DropIDs: []int{1, 2, 3}, RangeIDs: []int{1}, ActivityIDs: []int{1}},
+				Updated: &gamedata.AppliedIDs{},
+			},
+			want: []string{
+				"delete dropInfo [1 2 3] /dropinfos/delete",
+				"delete stage [1 2] /stages/delete",
+				"delete timeRange [1] /timeranges/delete",
+				"delete zone [1] /zones/delete",
+				"delete activity [1] /activities/delete",
+			},
+		},
+		{
+			name: "existed before",
+			applied: &gamedata.AppliedBundle{
+				Created: &gamedata.AppliedIDs{StageIDs: []int{2}, DropIDs: []int{3}, RangeIDs: []int{2}},
+				Updated: &gamedata.AppliedIDs{ZoneIDs: []int{1}, StageIDs: []int{1}},
+				Prior: &gamedata.PriorExistences{
+					Zones:  map[int]json.RawMessage{1: prior},
+					Stages: map[int]json.RawMessage{1: prior},
+				},
+			},
+			want: []string{
+				"delete dropInfo [3] /dropinfos/delete",
+				"delete stage [2] /stages/delete",
+				"restore existence stage [1] /stages/1 " + string(prior),
+				"delete timeRange [2] /timeranges/delete",
+				"restore existence zone [1] /zones/1 " + string(prior),
+			},
+		},
+		{
+			// bundles recorded before prior existences were kept leave their updated entities as saved
+			name: "prior existence not recorded",
+			applied: &gamedata.AppliedBundle{
+				Created: &gamedata.AppliedIDs{DropIDs: []int{3}, RangeIDs: []int{2}},
+				Updated: &gamedata.AppliedIDs{ZoneIDs: []int{1}, StageIDs: []int{1}},
+			},
+			want: []string{
+				"delete dropInfo [3] /dropinfos/delete",
+				"delete timeRange [2] /timeranges/delete",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, step := range PlanUndo(tt.applied) {
+				line := fmt.Sprintf("%s %s %v %s", step.Action, step.Kind, step.IDs, step.Endpoint)
+				if step.Existence != nil {
+					line += " " + string(step.Existence)
+				}
+				got = append(got, line)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("steps = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
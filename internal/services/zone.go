@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/penguin-statistics/soracli/internal/models"
+	"github.com/penguin-statistics/soracli/internal/models/cache"
+	"github.com/penguin-statistics/soracli/internal/pkg/client"
+)
+
+type ZoneService struct {
+	http *client.Penguin
+}
+
+func NewZoneService(http *client.Penguin) *ZoneService {
+	return &ZoneService{
+		http: http,
+	}
+}
+
+func (s *ZoneService) GetZones(ctx context.Context) ([]*models.Zone, error) {
+	var zones []*models.Zone
+	err := cache.Zones.MutexGetSet(&zones, func() ([]*models.Zone, error) {
+		var resp []*models.Zone
+		if err := s.http.GetJSON("/cli/zones", &resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}, time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
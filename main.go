@@ -3,11 +3,14 @@ package main
 import (
 	"log"
 	"os"
+	"os/user"
 	"time"
 
 	"github.com/urfave/cli/v2"
 
 	"github.com/penguin-statistics/soracli/cmd"
+	"github.com/penguin-statistics/soracli/internal/completion"
+	"github.com/penguin-statistics/soracli/internal/consts"
 )
 
 func main() {
@@ -21,22 +24,19 @@ func main() {
 				Usage:   "renders a new game data",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:     "ark-zone-id",
-						Aliases:  []string{"zi"},
-						Usage:    "ark zone ID",
-						Required: true,
+						Name:    "ark-zone-id",
+						Aliases: []string{"zi"},
+						Usage:   "ark zone ID; prompted for in a terminal if not set",
 					},
 					&cli.StringFlag{
-						Name:     "zone-name",
-						Aliases:  []string{"zn"},
-						Usage:    "zone name",
-						Required: true,
+						Name:    "zone-name",
+						Aliases: []string{"zn"},
+						Usage:   "zone name; prompted for in a terminal if not set",
 					},
 					&cli.StringFlag{
-						Name:     "zone-category",
-						Aliases:  []string{"zc"},
-						Usage:    "zone category",
-						Required: true,
+						Name:    "zone-category",
+						Aliases: []string{"zc"},
+						Usage:   "zone category; prompted for in a terminal if not set",
 					},
 					&cli.StringFlag{
 						Name:    "zone-type",
@@ -44,35 +44,506 @@ func main() {
 						Usage:   "zone type",
 					},
 					&cli.StringFlag{
-						Name:     "server",
-						Aliases:  []string{"s"},
-						Usage:    "server",
-						Required: true,
-					},
-					&cli.TimestampFlag{
-						Name:     "start-time",
-						Aliases:  []string{"st"},
-						Usage:    "zone start time",
-						Required: true,
-						Layout:   time.RFC3339,
-					},
-					&cli.TimestampFlag{
-						Name:    "end-time",
-						Aliases: []string{"et"},
-						Usage:   "zone end time",
-						Layout:  time.RFC3339,
+						Name:    "server",
+						Aliases: []string{"s"},
+						Usage:   "server; prompted for in a terminal if not set",
 					},
+					serverTimeFlag("start-time", "st", "zone start time; required unless --start-date is given"),
+					serverDateFlag("start-date", "zone start date"),
+					serverTimeFlag("end-time", "et", "zone end time; never ending unless given"),
+					serverDateFlag("end-date", "zone end date"),
 					&cli.StringFlag{
 						Name:    "editor",
 						Aliases: []string{"e"},
-						Usage:   "editor",
-						Value:   os.Getenv("EDITOR"),
+						Usage:   "editor command, which may have arguments, e.g. 'code --wait'",
+						EnvVars: []string{"VISUAL", "EDITOR"},
+					},
+					&cli.StringFlag{
+						Name:  "preview",
+						Usage: "previews the edited bundle in another format before saving; supported: shim",
+					},
+					&cli.StringFlag{
+						Name:  "edit-format",
+						Usage: "format of the rendered file to edit: json, or yaml annotated with item names, stage codes and server local times",
+						Value: "json",
 					},
 				},
 				Action: func(c *cli.Context) error {
 					return cmd.Render(c)
 				},
 			},
+			{
+				Name:      "plan",
+				Usage:     "prints the creates, updates and deletes making the live zones, stages, time ranges and drop infos as in a directory of rendered bundles",
+				ArgsUsage: "<dir>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "out",
+						Aliases: []string{"o"},
+						Usage:   "file to save the plan to, for soracli apply to apply exactly it",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return cmd.Plan(c)
+				},
+			},
+			{
+				Name:      "apply",
+				Usage:     "applies the plan of a directory of rendered bundles or a plan saved by soracli plan; or, with --file, renders the new events of a manifest, reviews them together and saves them in order",
+				ArgsUsage: "[<dir>|<plan.json>]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "file",
+						Aliases: []string{"f"},
+						Usage:   "YAML or JSON manifest of the events to render, each with the fields of the render flags and optional sourceUrl, region and rules overrides; unless --sourceUrl is given, the stage table of the region of each server is used",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "only review the plan or the rendered manifest",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return cmd.Apply(c)
+				},
+			},
+			{
+				Name:  "export",
+				Usage: "exports the admin data as a versioned backup, or rendered game data to other formats",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "out",
+						Aliases: []string{"o"},
+						Usage:   "directory to export every zone, stage, time range, drop info, activity, item and notice to",
+					},
+					&cli.StringFlag{
+						Name:  "sqlite",
+						Usage: "SQLite database to export to, for ad-hoc SQL queries; replaced if it exists. needs soracli built with -tags sqlite",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return cmd.Export(c)
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:      "shim",
+						Usage:     "converts a rendered bundle into the JSON the v2 shim endpoints would return",
+						ArgsUsage: "<bundle.json>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "out",
+								Aliases: []string{"o"},
+								Usage:   "file to write to, instead of stdout",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.ExportShim(c)
+						},
+					},
+				},
+			},
+			{
+				Name:      "completion",
+				Usage:     "prints the shell completion script of soracli; e.g. source <(soracli completion bash)",
+				ArgsUsage: "<bash|zsh|fish>",
+				Action: func(c *cli.Context) error {
+					return cmd.Completion(c)
+				},
+			},
+			{
+				Name:  "gc",
+				Usage: "removes old log files and sessions, and expired or stale entries of the local caches",
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:  "session-max-age",
+						Usage: "removes the sessions of soracli undo last changed longer ago than this",
+						Value: 90 * 24 * time.Hour,
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "only list what would be removed",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return cmd.GC(c)
+				},
+			},
+			{
+				Name:  "mock-server",
+				Usage: "runs a local stand-in of the admin api, for practicing and integration testing",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "listen",
+						Aliases: []string{"l"},
+						Usage:   "address to listen on",
+						Value:   "127.0.0.1:9010",
+					},
+					&cli.StringFlag{
+						Name:  "snapshot",
+						Usage: "JSON snapshot file, or directory written by export --out, to seed the mock server state from",
+					},
+					&cli.StringFlag{
+						Name:  "state",
+						Usage: "JSON file to persist the mock server state to; takes precedence over --snapshot once it exists",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return cmd.MockServer(c)
+				},
+			},
+			{
+				Name:  "item",
+				Usage: "manages items",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "add",
+						Usage:     "adds a newly introduced item from item_table.json",
+						ArgsUsage: "<arkItemId>",
+						Flags: []cli.Flag{
+							itemTableUrlFlag(),
+							&cli.StringFlag{
+								Name:    "editor",
+								Aliases: []string{"e"},
+								Usage:   "editor command, which may have arguments, e.g. 'code --wait'",
+								EnvVars: []string{"VISUAL", "EDITOR"},
+							},
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.AddItem(c)
+						},
+					},
+					{
+						Name:  "sync",
+						Usage: "compares item_table.json of every server with the items on Penguin, and submits the reviewed fixes",
+						Flags: []cli.Flag{
+							itemTableUrlFlag(),
+							&cli.StringSliceFlag{
+								Name:  "item-type",
+								Usage: "item types of item_table.json to compare; may be repeated",
+								Value: cli.NewStringSlice(consts.ItemTypeMaterial, consts.ItemTypeCardExp),
+							},
+							&cli.BoolFlag{
+								Name:  "report-only",
+								Usage: "only print the report, without generating payloads",
+							},
+							&cli.StringFlag{
+								Name:    "editor",
+								Aliases: []string{"e"},
+								Usage:   "editor command, which may have arguments, e.g. 'code --wait'",
+								EnvVars: []string{"VISUAL", "EDITOR"},
+							},
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.SyncItems(c)
+						},
+					},
+				},
+			},
+			{
+				Name:  "local-cache",
+				Usage: "inspects and clears the on-disk cache of data fetched from the admin api",
+				Subcommands: []*cli.Command{
+					{
+						Name:    "ls",
+						Aliases: []string{"list"},
+						Usage:   "lists the entries of the local cache",
+						Action: func(c *cli.Context) error {
+							return cmd.ListLocalCache(c)
+						},
+					},
+					{
+						Name:      "clear",
+						Usage:     "clears the local cache, or only entries whose names start with any of the given prefixes",
+						ArgsUsage: "[prefix...]",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "stale",
+								Usage: "only clear expired entries and entries of older cache versions",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.ClearLocalCache(c)
+						},
+					},
+				},
+			},
+			{
+				Name:  "notice",
+				Usage: "manages notices shown on the site",
+				Subcommands: []*cli.Command{
+					{
+						Name:    "list",
+						Aliases: []string{"ls"},
+						Usage:   "lists all notices and their state on each server",
+						Action: func(c *cli.Context) error {
+							return cmd.ListNotices(c)
+						},
+					},
+					{
+						Name:      "preview",
+						Usage:     "previews how a notice renders in each language",
+						ArgsUsage: "<noticeId>",
+						Action: func(c *cli.Context) error {
+							return cmd.PreviewNotice(c)
+						},
+					},
+					{
+						Name:  "create",
+						Usage: "creates a notice, editing its content in the editor",
+						Flags: noticeEditFlags(),
+						Action: func(c *cli.Context) error {
+							return cmd.CreateNotice(c)
+						},
+					},
+					{
+						Name:      "update",
+						Usage:     "updates a notice, editing its content in the editor",
+						ArgsUsage: "<noticeId>",
+						Flags:     noticeEditFlags(),
+						Action: func(c *cli.Context) error {
+							return cmd.UpdateNotice(c)
+						},
+					},
+					{
+						Name:      "expire",
+						Usage:     "closes a notice on the given servers, or on all servers",
+						ArgsUsage: "<noticeId>",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:    "server",
+								Aliases: []string{"s"},
+								Usage:   "server to expire the notice on; may be repeated. defaults to all servers",
+							},
+							serverTimeFlag("at", "", "time to expire the notice at on each server; defaults to now"),
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.ExpireNotice(c)
+						},
+					},
+				},
+			},
+			{
+				Name:  "timerange",
+				Usage: "manages time ranges of drop infos",
+				Subcommands: []*cli.Command{
+					{
+						Name:    "list",
+						Aliases: []string{"ls"},
+						Usage:   "lists the time ranges of a server",
+						Flags: []cli.Flag{
+							timeRangeServerFlag(true),
+							&cli.StringFlag{
+								Name:  "overlapping",
+								Usage: "only list time ranges overlapping a time range in the form of <startMillis>-<endMillis>",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.ListTimeRanges(c)
+						},
+					},
+					{
+						Name:  "create",
+						Usage: "creates a time range",
+						Flags: []cli.Flag{
+							timeRangeServerFlag(true),
+							&cli.StringFlag{
+								Name:  "range",
+								Usage: "time range in the form of <startMillis>-<endMillis>, instead of --start-time and --end-time",
+							},
+							serverTimeFlag("start-time", "st", "time range start time"),
+							serverDateFlag("start-date", "time range start date"),
+							serverTimeFlag("end-time", "et", "time range end time; never ending unless given"),
+							serverDateFlag("end-date", "time range end date"),
+							timeRangeNameFlag(),
+							timeRangeCommentFlag(),
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.CreateTimeRange(c)
+						},
+					},
+					{
+						Name:      "split",
+						Usage:     "ends a time range at the given time, and duplicates its drop infos onto a new time range from then on",
+						ArgsUsage: "<rangeId>",
+						Flags: []cli.Flag{
+							timeRangeServerFlag(false),
+							serverTimeFlag("at", "", "time to split the time range at"),
+							serverDateFlag("at-date", "date to split the time range at"),
+							timeRangeNameFlag(),
+							timeRangeCommentFlag(),
+							&cli.StringFlag{
+								Name:    "editor",
+								Aliases: []string{"e"},
+								Usage:   "editor command, which may have arguments, e.g. 'code --wait'",
+								EnvVars: []string{"VISUAL", "EDITOR"},
+							},
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.SplitTimeRange(c)
+						},
+					},
+					{
+						Name:      "merge",
+						Usage:     "merges contiguous time ranges into the earliest of them",
+						ArgsUsage: "<rangeId> <rangeId>...",
+						Flags: []cli.Flag{
+							timeRangeServerFlag(false),
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.MergeTimeRanges(c)
+						},
+					},
+					{
+						Name:  "check",
+						Usage: "reports overlapping time ranges, short gaps between them and conflicting accumulable flags of drop infos",
+						Flags: []cli.Flag{
+							timeRangeServerFlag(true),
+							&cli.StringFlag{
+								Name:  "snapshot",
+								Usage: "JSON snapshot or export directory, as used by mock-server, to check instead of live data",
+							},
+							&cli.BoolFlag{
+								Name:  "accumulable",
+								Usage: "also list the max accumulable time ranges of every stage and item",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.CheckTimeRanges(c)
+						},
+					},
+				},
+			},
+			{
+				Name:  "analyze",
+				Usage: "analyzes exported drop data offline",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "matrix",
+						Usage: "computes drop rates of items on stages, with confidence intervals, for each server and time range",
+						Flags: append(analyzeInputFlags(),
+							&cli.StringFlag{
+								Name:    "format",
+								Aliases: []string{"f"},
+								Usage:   "output format: table, csv, or json in the shape of the v2 matrix shim",
+								Value:   "table",
+							},
+							&cli.Float64Flag{
+								Name:  "confidence",
+								Usage: "confidence level of the intervals",
+								Value: 0.95,
+							},
+							&cli.BoolFlag{
+								Name:  "include-unreliable",
+								Usage: "also count reports of non-zero reliability",
+							},
+						),
+						Action: func(c *cli.Context) error {
+							return cmd.AnalyzeMatrix(c)
+						},
+					},
+					{
+						Name:  "bounds-audit",
+						Usage: "replays drop reports against the bounds of the drop infos in force, listing violations and suggesting bounds",
+						Flags: append(analyzeInputFlags(),
+							&cli.IntFlag{
+								Name:  "min-samples",
+								Usage: "single-run reports needed to suggest tightening bounds wider than observed",
+								Value: 100,
+							},
+							&cli.IntFlag{
+								Name:  "limit",
+								Usage: "violations to list per server; 0 lists all",
+								Value: 100,
+							},
+						),
+						Action: func(c *cli.Context) error {
+							return cmd.AnalyzeBoundsAudit(c)
+						},
+					},
+				},
+			},
+			{
+				Name:      "undo",
+				Usage:     "removes the zone, stages, drop infos, time range and activity created by the bundles rendered in a session, and restores the existences of the zone and stages they updated",
+				ArgsUsage: "<session>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "only list the requests undoing the session",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return cmd.Undo(c)
+				},
+			},
+			{
+				Name:  "audit",
+				Usage: "browses the local audit log of the requests that changed data through the admin api",
+				Subcommands: []*cli.Command{
+					{
+						Name:    "list",
+						Aliases: []string{"ls"},
+						Usage:   "lists the most recent entries of the audit log",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "session",
+								Usage: "only list entries of the soracli run of this session",
+							},
+							&cli.StringFlag{
+								Name:  "by",
+								Usage: "only list entries of this operator",
+							},
+							&cli.StringFlag{
+								Name:  "endpoint",
+								Usage: "only list entries of endpoints starting with this, e.g. /save",
+							},
+							&cli.BoolFlag{
+								Name:  "failed",
+								Usage: "only list entries of failed requests",
+							},
+							&cli.IntFlag{
+								Name:  "limit",
+								Usage: "entries to list; 0 lists all",
+								Value: 20,
+							},
+						},
+						Action: func(c *cli.Context) error {
+							return cmd.ListAuditLog(c)
+						},
+					},
+					{
+						Name:      "show",
+						Usage:     "shows an entry of the audit log with its full payload and response",
+						ArgsUsage: "<requestId>",
+						Action: func(c *cli.Context) error {
+							return cmd.ShowAuditLogEntry(c)
+						},
+					},
+				},
+			},
+			{
+				Name:  "status",
+				Usage: "shows the zones, stages and activities open on each server, and a timeline of time ranges around then",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "server",
+						Aliases: []string{"s"},
+						Usage:   "server to show; defaults to all servers",
+					},
+					serverTimeFlag("at", "", "time to show the status at; defaults to now"),
+					&cli.IntFlag{
+						Name:  "days",
+						Usage: "days before and after --at the timeline covers",
+						Value: 30,
+					},
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "also list zones open without a close time",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return cmd.Status(c)
+				},
+			},
 		},
 		Flags: []cli.Flag{
 			&cli.StringFlag{
@@ -89,20 +560,174 @@ func main() {
 				Value:    "https://raw.githubusercontent.com/Kengxxiao/ArknightsGameData/master/zh_CN/gamedata/excel/stage_table.json",
 			},
 			&cli.StringFlag{
-				Name:     "token",
-				Usage:    "bearer token for authentication to the admin api; required",
-				Required: true,
+				Name:  "token",
+				Usage: "bearer token for authentication to the admin api; required",
+			},
+			&cli.StringFlag{
+				Name:    "profile",
+				Usage:   "name of the environment acted on, recorded in the audit log",
+				EnvVars: []string{"SORACLI_PROFILE"},
+				Value:   "default",
+			},
+			&cli.StringFlag{
+				Name:    "operator",
+				Usage:   "who is operating soracli, recorded in the audit log",
+				EnvVars: []string{"SORACLI_OPERATOR"},
+				Value:   defaultOperator(),
+			},
+			&cli.BoolFlag{
+				Name:    "unverified-endpoints",
+				Usage:   "post to the endpoints creating, updating and deleting zones, stages, time ranges, drop infos, items and notices, which the admin api is not known to serve; soracli mock-server serves them",
+				EnvVars: []string{"SORACLI_UNVERIFIED_ENDPOINTS"},
+			},
+			&cli.BoolFlag{
+				Name:  "no-local-cache",
+				Usage: "do not read from or write to the on-disk cache of data fetched from the admin api",
 			},
 			&cli.BoolFlag{
 				Name:    "verbose",
 				Aliases: []string{"v"},
 				Usage:   "verbose output",
 			},
+			&cli.StringFlag{
+				Name:    "data-dir",
+				Usage:   "directory to keep every file of soracli in, with caches and logs in its cache and logs subdirectories; defaults to the XDG base directories, or ~/.soracli if it exists",
+				EnvVars: []string{"SORACLI_DATA_DIR"},
+			},
+			&cli.StringFlag{
+				Name:  "log-format",
+				Usage: "format of the logs written to stderr and the log file: console or json",
+				Value: "console",
+			},
+			&cli.DurationFlag{
+				Name:  "log-max-age",
+				Usage: "removes log files older than this; 0 keeps log files of any age",
+				Value: 30 * 24 * time.Hour,
+			},
+			&cli.Int64Flag{
+				Name:  "log-max-size",
+				Usage: "removes the oldest log files while they total more than this many MiB; 0 keeps any size",
+				Value: 100,
+			},
 		},
 	}
 
+	completion.Install(app)
+
 	err := app.Run(os.Args)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+func noticeEditFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.Int64Flag{
+			Name:  "severity",
+			Usage: "notice severity",
+		},
+		&cli.StringSliceFlag{
+			Name:    "server",
+			Aliases: []string{"s"},
+			Usage:   "server to show the notice on, using --open-time and --close-time; may be repeated",
+		},
+		serverTimeFlag("open-time", "", "time to start showing the notice on each of the given servers"),
+		serverTimeFlag("close-time", "", "time to stop showing the notice on each of the given servers"),
+		&cli.StringFlag{
+			Name:    "editor",
+			Aliases: []string{"e"},
+			Usage:   "editor command, which may have arguments, e.g. 'code --wait'",
+			EnvVars: []string{"VISUAL", "EDITOR"},
+		},
+	}
+}
+
+func itemTableUrlFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "item-table-url",
+		Usage: "source url of item_table.json, with {region} in place of the game data region, e.g. zh_CN",
+		Value: "https://raw.githubusercontent.com/Kengxxiao/ArknightsGameData/master/{region}/gamedata/excel/item_table.json",
+	}
+}
+
+// defaultOperator is the name of the user running soracli.
+func defaultOperator() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func analyzeInputFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     "reports",
+			Usage:    "JSON lines file of drop reports",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "patterns",
+			Usage:    "JSON lines file of the drop patterns of the reports",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:    "server",
+			Aliases: []string{"s"},
+			Usage:   "server to analyze; defaults to all servers in the reports",
+		},
+		&cli.StringFlag{
+			Name:  "snapshot",
+			Usage: "JSON snapshot or export directory, as used by mock-server, to take stages, items, time ranges and drop infos from instead of live data",
+		},
+		&cli.StringFlag{
+			Name:    "out",
+			Aliases: []string{"o"},
+			Usage:   "file to write to instead of stdout",
+		},
+	}
+}
+
+func timeRangeServerFlag(required bool) cli.Flag {
+	usage := "server of the time ranges"
+	if !required {
+		usage += "; if given, the time ranges are checked to be of the server"
+	}
+	return &cli.StringFlag{
+		Name:     "server",
+		Aliases:  []string{"s"},
+		Usage:    usage,
+		Required: required,
+	}
+}
+
+func timeRangeNameFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "name",
+		Usage: "name of the new time range",
+	}
+}
+
+func timeRangeCommentFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "comment",
+		Usage: "comment of the new time range",
+	}
+}
+
+func serverTimeFlag(name, alias, usage string) cli.Flag {
+	flag := &cli.StringFlag{
+		Name:  name,
+		Usage: usage + ". RFC3339, or `2006-01-02 15:04 [@SERVER]` in the local time of --server or of @SERVER",
+	}
+	if alias != "" {
+		flag.Aliases = []string{alias}
+	}
+	return flag
+}
+
+func serverDateFlag(name, usage string) cli.Flag {
+	return &cli.StringFlag{
+		Name:  name,
+		Usage: usage + " as `2006-01-02`, meaning the start of that game day on the server",
+	}
+}